@@ -0,0 +1,230 @@
+package controllers
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/revel/cron"
+	"github.com/revel/revel"
+	"github.com/samsung-cnct/container-technical-on-boarding/app"
+	"github.com/samsung-cnct/container-technical-on-boarding/app/jobs"
+	"github.com/samsung-cnct/container-technical-on-boarding/app/jobs/onboarding"
+	"github.com/samsung-cnct/container-technical-on-boarding/app/models"
+	"golang.org/x/oauth2"
+)
+
+// API serves onboardings as JSON, for tools (e.g. HR's) rather than browsers. Its callers authenticate with a
+// provider token of their own (the "Authorization: token ..." or "Bearer ..." header), or with one of the API keys
+// (the "X-API-Key" header), acting as onboard.api.token's user. An onboarding is identified by its hire's username;
+// it is shown to the hire, to whoever started it, and to callers with an API key.
+type API struct {
+	*revel.Controller
+}
+
+type (
+	// onboardingRequest is the body of a request to start an onboarding.
+	onboardingRequest struct {
+		Hire   string `json:"hire"`
+		Role   string `json:"role,omitempty"`
+		Start  string `json:"start,omitempty"` // YYYY-MM-DD, today by default
+		Sync   bool   `json:"sync,omitempty"`
+		Resume bool   `json:"resume,omitempty"` // continue a failed onboarding from its checkpoint, in its role
+	}
+
+	// onboardingStatus describes an onboarding: its job, and the progress it recorded. An onboarding which failed
+	// before a restart is only known by its checkpoint.
+	onboardingStatus struct {
+		ID         string                 `json:"id"`
+		Status     string                 `json:"status"` // "running", "completed", "cancelled" or "failed"
+		Events     int                    `json:"events"`
+		LastEvent  *jobs.Event            `json:"last_event,omitempty"`
+		Checkpoint *onboarding.Checkpoint `json:"checkpoint,omitempty"`
+	}
+)
+
+// Create starts the onboarding of a hire, unless theirs is running already (409 Conflict, with its status).
+// The job runs with the caller's authorization; it is followed with Show and Events.
+func (c API) Create() revel.Result {
+	user, admin := c.apiUser()
+	if user == nil {
+		return c.apiError(http.StatusUnauthorized, "A provider token or an API key is required")
+	}
+
+	var request onboardingRequest
+	if err := json.Unmarshal(c.Params.JSON, &request); err != nil {
+		return c.apiError(http.StatusBadRequest, fmt.Sprintf("Invalid onboarding request: %v", err))
+	}
+	if len(request.Hire) == 0 {
+		return c.apiError(http.StatusUnprocessableEntity, "The hire is required")
+	}
+	hire, err := user.AuthEnv.ResolveUsername(request.Hire)
+	if err != nil {
+		revel.INFO.Printf("User '%s' could not onboard '%s': %v", user.Username, request.Hire, err)
+		return c.apiError(http.StatusUnprocessableEntity, fmt.Sprintf("Unknown user '%s'", request.Hire))
+	}
+
+	setup := app.CurrentSetup()
+	checkpoint := loadCheckpoint(setup, hire)
+	resume := request.Resume && (checkpoint != nil)
+	role := request.Role
+	if resume {
+		role = checkpoint.Role
+	}
+	if _, known := setup.Roles[role]; (len(setup.Roles) > 0) && !known {
+		return c.apiError(http.StatusUnprocessableEntity, fmt.Sprintf("Unknown role '%s'; expected one of %s", role, strings.Join(setup.RoleNames(), ", ")))
+	}
+
+	run, started := app.Runs.Start(onboardingKey(hire), workloadJob, user.Username, false, func(ctx context.Context, events chan<- jobs.Event) cron.Job {
+		return onboarding.GenerateProject{
+			ID:          user.ID,
+			Context:     ctx,
+			Setup:       setup,
+			AuthEnv:     user.AuthEnv,
+			New:         events,
+			Role:        role,
+			Sync:        request.Sync,
+			Resume:      resume,
+			StartDate:   request.Start,
+			Hire:        hire,
+			Checkpoints: app.Checkpoints,
+			Buddies:     app.Buddies,
+		}
+	})
+	if !started {
+		if run.Kind() != workloadJob {
+			return c.apiError(http.StatusConflict, fmt.Sprintf("The onboarding of '%s' is being torn down", hire))
+		}
+		if !mayFollow(user, admin, hire, run) {
+			return c.apiError(http.StatusConflict, fmt.Sprintf("The onboarding of '%s' is already in progress", hire))
+		}
+		c.Response.Status = http.StatusConflict
+		return c.RenderJSON(newOnboardingStatus(hire, run, loadCheckpoint(setup, hire)))
+	}
+
+	revel.INFO.Printf("User '%s' has started the onboarding of '%s' through the API", user.Username, hire)
+	c.Response.Status = http.StatusAccepted
+	c.Response.Out.Header().Set("Location", fmt.Sprintf("/api/v1/onboardings/%s", hire))
+	return c.RenderJSON(newOnboardingStatus(hire, run, checkpoint))
+}
+
+// Show renders the status of a hire's onboarding.
+func (c API) Show(id string) revel.Result {
+	user, admin := c.apiUser()
+	if user == nil {
+		return c.apiError(http.StatusUnauthorized, "A provider token or an API key is required")
+	}
+
+	run := onboardingRun(id)
+	if !mayFollow(user, admin, id, run) {
+		return c.apiError(http.StatusForbidden, fmt.Sprintf("Not allowed to follow the onboarding of '%s'", id))
+	}
+	checkpoint := loadCheckpoint(app.CurrentSetup(), id)
+	if (run == nil) && (checkpoint == nil) {
+		return c.apiError(http.StatusNotFound, fmt.Sprintf("No onboarding of '%s'", id))
+	}
+	return c.RenderJSON(newOnboardingStatus(id, run, checkpoint))
+}
+
+// Events renders the events of a hire's onboarding after the sequence number since, and whether its job has ended.
+// With wait, it waits for some when there are none yet (long-polling).
+func (c API) Events(id string, since int, wait bool) revel.Result {
+	user, admin := c.apiUser()
+	if user == nil {
+		return c.apiError(http.StatusUnauthorized, "A provider token or an API key is required")
+	}
+
+	run := onboardingRun(id)
+	if !mayFollow(user, admin, id, run) {
+		return c.apiError(http.StatusForbidden, fmt.Sprintf("Not allowed to follow the onboarding of '%s'", id))
+	}
+	if run == nil {
+		return c.apiError(http.StatusNotFound, fmt.Sprintf("No onboarding of '%s' has run since the server started", id))
+	}
+	if wait {
+		return c.RenderJSON(pollEvents(user, run, workloadJob, since))
+	}
+	events, done, _ := run.Since(since)
+	if events == nil {
+		events = []jobs.Event{}
+	}
+	return c.RenderJSON(polledEvents{Events: events, Done: done})
+}
+
+// apiUser authenticates the caller, with the provider, returning nil when it cannot, and indicates whether they used
+// an API key (which lets them follow any onboarding). The user only lasts for the request; unlike the browser's, it
+// is not stored.
+func (c API) apiUser() (*models.User, bool) {
+	var token string
+	admin := false
+	if key := c.Request.Header.Get("X-API-Key"); len(key) > 0 {
+		if !validAPIKey(key) {
+			revel.INFO.Printf("Invalid API key from %s", c.ClientIP)
+			return nil, false
+		}
+		token = app.Configs[app.OnboardAPITokenName]
+		admin = true
+	} else {
+		fields := strings.Fields(c.Request.Header.Get("Authorization"))
+		if (len(fields) != 2) || !(strings.EqualFold(fields[0], "token") || strings.EqualFold(fields[0], "bearer")) {
+			return nil, false
+		}
+		token = fields[1]
+	}
+
+	auth := app.Credentials.RestoreAuthEnvironment("", &oauth2.Token{AccessToken: token})
+	username := auth.Username()
+	if len(username) == 0 {
+		return nil, false
+	}
+	return &models.User{Username: username, AuthEnv: auth}, admin
+}
+
+// mayFollow indicates whether the caller may follow a hire's onboarding (whose run may be nil): their own, one they
+// started, or any when they used an API key.
+func mayFollow(user *models.User, admin bool, hire string, run *jobs.Run) bool {
+	return admin || strings.EqualFold(user.Username, hire) || ((run != nil) && startedBy(user, run))
+}
+
+// onboardingRun returns the last run of a hire's workload, or nil when there is none (or their teardown ran since).
+func onboardingRun(hire string) *jobs.Run {
+	run := app.Runs.Get(onboardingKey(hire))
+	if (run == nil) || (run.Kind() != workloadJob) {
+		return nil
+	}
+	return run
+}
+
+// apiError renders an error as JSON, with its HTTP status.
+func (c API) apiError(status int, message string) revel.Result {
+	c.Response.Status = status
+	return c.RenderJSON(map[string]string{"error": message})
+}
+
+// validAPIKey indicates whether a key is one of the configured API keys.
+func validAPIKey(key string) bool {
+	valid := false
+	for _, apiKey := range app.APIKeys {
+		if subtle.ConstantTimeCompare([]byte(key), []byte(apiKey)) == 1 {
+			valid = true
+		}
+	}
+	return valid
+}
+
+// newOnboardingStatus describes an onboarding from its job's run and its checkpoint, either of which may be nil.
+func newOnboardingStatus(hire string, run *jobs.Run, checkpoint *onboarding.Checkpoint) onboardingStatus {
+	status := onboardingStatus{ID: hire, Status: "failed", Checkpoint: checkpoint}
+	if run != nil {
+		events, _, _ := run.Since(0)
+		status.Status = run.Status()
+		status.Events = len(events)
+		if len(events) > 0 {
+			status.LastEvent = &events[len(events)-1]
+		}
+	}
+	return status
+}
@@ -1,9 +1,14 @@
 package controllers
 
 import (
+	"context"
 	"fmt"
+	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 
+	"github.com/revel/cron"
 	"github.com/revel/revel"
 	"github.com/samsung-cnct/container-technical-on-boarding/app"
 	"github.com/samsung-cnct/container-technical-on-boarding/app/jobs"
@@ -17,10 +22,12 @@ type App struct {
 	*revel.Controller
 }
 
-// Version endpoint to retrieve and serve app version.
+// Version endpoint to retrieve and serve app version, and the version of the onboarding scheme in use.
 // Can be used for an application readiness check.
 func (c App) Version() revel.Result {
-	return c.RenderJSON(app.SemanticVersion)
+	version := *app.SemanticVersion
+	version.Tasks = app.CurrentSetup().Version
+	return c.RenderJSON(version)
 }
 
 // Index of web app
@@ -28,24 +35,41 @@ func (c App) Index() revel.Result {
 	return c.Render()
 }
 
+// Pages a user may return to after authorizing, by the name given in Auth's next parameter.
+var nextPages = map[string]string{
+	"teardown": "/teardown",
+	"onboard":  "/onboard",
+	"sync":     "/workload?sync=true&dryrun=true", // previewed first, as syncing edits existing issues
+}
+
 // Auth initiates the oauth2 authorization request to github
-func (c App) Auth() revel.Result {
+func (c App) Auth(dryrun bool, next string) revel.Result {
 	user := c.currentUser()
 	if user == nil {
-		user = models.NewUser()
+		var err error
+		if user, err = app.Users.NewUser(); err != nil {
+			revel.ERROR.Printf("Could not create a user: %v", err)
+			return c.Redirect("/")
+		}
 		c.Session["uid"] = fmt.Sprintf("%d", user.ID)
 	}
+	c.Session["dryrun"] = strconv.FormatBool(dryrun)
+	c.Session["next"] = next
 
 	auth := app.Credentials.NewAuthEnvironment()
 	authURL := auth.AuthCodeURL()
 	user.AuthEnv = auth
+	if err := app.Users.SaveUser(user); err != nil {
+		revel.ERROR.Printf("Could not save user %d: %v", user.ID, err)
+		return c.Redirect("/")
+	}
 	return c.Redirect(authURL)
 }
 
 // AuthCallback handles the oauth2 authorization response and sets up a user
 func (c App) AuthCallback() revel.Result {
 	user := c.currentUser()
-	if user == nil {
+	if (user == nil) || (user.AuthEnv == nil) {
 		revel.ERROR.Println("Invalid OAuth Callback")
 		return c.Redirect("/")
 	}
@@ -64,35 +88,352 @@ func (c App) AuthCallback() revel.Result {
 		revel.ERROR.Printf("Could not get access token for user: %v", err)
 		return c.Redirect("/")
 	}
-	user.Username = auth.GithubUsername()
+	user.Username = auth.Username()
+	if err = app.Users.SaveUser(user); err != nil {
+		revel.ERROR.Printf("Could not save user '%s': %v", user.Username, err)
+		return c.Redirect("/")
+	}
 
-	revel.INFO.Printf("Successfully authenticated Github user: %s\n", user.Username)
+	revel.INFO.Printf("Successfully authenticated user: %s\n", user.Username)
+	if page, ok := nextPages[c.Session["next"]]; ok {
+		return c.Redirect(page)
+	}
+	if dryrun, _ := strconv.ParseBool(c.Session["dryrun"]); dryrun {
+		return c.Redirect("/workload?dryrun=true")
+	}
 	return c.Redirect("/workload")
 }
 
-// Workload handles the initial workload page rendering
-func (c App) Workload() revel.Result {
+// Onboard lets a manager generate the onboarding of a new hire, under the manager's own authorization. The hire's
+// username is validated with the provider before the planned changes are previewed.
+func (c App) Onboard(hire string, start string, role string) revel.Result {
 	user := c.currentUser()
-	if user == nil {
+	if (user == nil) || !user.Authenticated() {
+		return c.Redirect("/auth?next=onboard")
+	}
+
+	roleNames := app.CurrentSetup().RoleNames()
+	if len(hire) == 0 {
+		return c.Render(user, hire, start, role, roleNames)
+	}
+
+	login, err := user.AuthEnv.ResolveUsername(hire)
+	if err != nil {
+		revel.INFO.Printf("User '%s' could not onboard '%s': %v", user.Username, hire, err)
+		hireError := fmt.Sprintf("Unknown user '%s'", hire)
+		return c.Render(user, hire, start, role, roleNames, hireError)
+	}
+
+	query := url.Values{"dryrun": {"true"}, "hire": {login}, "start": {start}, "role": {role}}
+	return c.Redirect("/workload?%s", query.Encode())
+}
+
+// Workload handles the initial workload page rendering.
+// When the setup declares roles, the user is asked to choose one before the project is generated.
+// With sync, existing issues are edited where they have drifted from the task template.
+// When an earlier run failed, the user is offered to resume it (in its role), or to restart.
+// The start date (YYYY-MM-DD, today by default) is the hire's first day, from which due dates are computed.
+// The hire (by default, the current user) is whose onboarding it is; see Onboard.
+func (c App) Workload(dryrun bool, sync bool, resume bool, restart bool, role string, start string, hire string) revel.Result {
+	user := c.currentUser()
+	if (user == nil) || !user.Authenticated() {
+		revel.ERROR.Printf("User not setup correctly")
+		return c.Redirect("/")
+	}
+
+	setup := app.CurrentSetup()
+	checkpoint := c.checkpoint(setup, user, hire)
+	resume = resume && (checkpoint != nil)
+	if resume {
+		role = checkpoint.Role
+	}
+	offerResume := (checkpoint != nil) && !resume && !restart && !dryrun
+
+	roles := setup.Roles
+	roleNames := setup.RoleNames()
+	_, roleKnown := roles[role]
+	chooseRole := (len(roles) > 0) && !roleKnown
+	if chooseRole && (len(role) > 0) {
+		c.ViewArgs["roleError"] = fmt.Sprintf("Unknown role '%s'", role)
+	}
+
+	return c.Render(user, dryrun, sync, resume, restart, role, start, hire, roles, roleNames, chooseRole, offerResume, checkpoint)
+}
+
+// WorkloadPlan renders, as JSON, what the workload would change in the repository.
+func (c App) WorkloadPlan(sync bool, role string, start string, hire string) revel.Result {
+	user := c.currentUser()
+	if (user == nil) || !user.Authenticated() {
 		revel.ERROR.Printf("User not setup correctly")
 		return c.Redirect("/")
 	}
 
-	return c.Render(user)
+	// The plan stops (e.g. waiting out a rate limit) when the client disconnects.
+	job := onboarding.GenerateProject{
+		ID:        user.ID,
+		Context:   c.Request.Context(),
+		Setup:     app.CurrentSetup(),
+		AuthEnv:   user.AuthEnv,
+		Role:      role,
+		Sync:      sync,
+		StartDate: start,
+		Hire:      hire,
+		Buddies:   app.Buddies,
+	}
+	plan, err := job.Plan()
+	if err != nil {
+		revel.ERROR.Printf("Could not plan workload for user '%s': %v", user.Username, err)
+		c.Response.Status = http.StatusInternalServerError
+		return c.RenderJSON(map[string]string{"error": err.Error()})
+	}
+
+	return c.RenderJSON(plan)
 }
 
-// WorkloadSocket handles the websocket connection for workload events
-func (c App) WorkloadSocket(ws *websocket.Conn) revel.Result {
+// WorkloadSocket handles the websocket connection for workload events. The client may send "cancel" to stop the job,
+// if the user started it. The job runs independently of the connection, and a hire's job is never started twice
+// while it is running (whether here or through the API; the client follows the one in progress): a client
+// reconnecting (e.g. after a page reload) replays the events after the sequence number since, and with reattach,
+// follows the hire's last job even if it has completed meanwhile.
+func (c App) WorkloadSocket(ws *websocket.Conn, dryrun bool, sync bool, resume bool, role string, start string, hire string, since int, reattach bool) revel.Result {
 	if ws == nil {
 		revel.ERROR.Printf("Websocket not intialized")
 		return nil
 	}
 	user := c.currentUser()
-	if user == nil {
+	if (user == nil) || !user.Authenticated() {
+		revel.ERROR.Printf("User not setup correctly")
+		return c.Redirect("/")
+	}
+
+	run, started := startWorkload(user, dryrun, sync, resume, role, start, hire, reattach)
+	return c.streamJob(ws, user, run, workloadKind(dryrun), started, since)
+}
+
+// WorkloadStart starts the workload job, for clients following it without a websocket (see WorkloadEvents and
+// WorkloadPoll), and renders, as JSON, whether it was started; it isn't when a job is running under its key.
+func (c App) WorkloadStart(dryrun bool, sync bool, resume bool, role string, start string, hire string) revel.Result {
+	user := c.currentUser()
+	if (user == nil) || !user.Authenticated() {
+		revel.ERROR.Printf("User not setup correctly")
+		return c.Redirect("/")
+	}
+
+	_, started := startWorkload(user, dryrun, sync, resume, role, start, hire, false)
+	return c.RenderJSON(map[string]bool{"started": started})
+}
+
+// WorkloadEvents relays the events of the workload job as Server-Sent Events, for clients behind proxies which
+// don't let websockets through; see WorkloadSocket. It only follows the job, which WorkloadStart starts: when there
+// is none, it tells the client so. A reconnecting EventSource resumes after its Last-Event-ID.
+func (c App) WorkloadEvents(dryrun bool, hire string, since int) revel.Result {
+	user := c.currentUser()
+	if (user == nil) || !user.Authenticated() {
+		revel.ERROR.Printf("User not setup correctly")
+		return c.Redirect("/")
+	}
+
+	if id := c.Request.Header.Get("Last-Event-ID"); len(id) > 0 {
+		if sequence, err := strconv.Atoi(id); err == nil {
+			since = sequence
+		}
+	}
+	run := app.Runs.Get(workloadKey(user, dryrun, hire))
+	return eventStream{user: user, run: run, kind: workloadKind(dryrun), since: since}
+}
+
+// WorkloadPoll renders, as JSON, the events of the workload job after the sequence number since, waiting for some
+// when there are none yet; the last fallback of clients which can use neither websockets nor Server-Sent Events.
+// Like WorkloadEvents, it only follows the job.
+func (c App) WorkloadPoll(dryrun bool, hire string, since int) revel.Result {
+	user := c.currentUser()
+	if (user == nil) || !user.Authenticated() {
+		revel.ERROR.Printf("User not setup correctly")
+		return c.Redirect("/")
+	}
+
+	run := app.Runs.Get(workloadKey(user, dryrun, hire))
+	return c.RenderJSON(pollEvents(user, run, workloadKind(dryrun), since))
+}
+
+// WorkloadCancel cancels the workload job, for clients following it without a websocket, and renders, as JSON,
+// whether it was cancelled: not when it has ended already. Only the user who started the job may cancel it.
+func (c App) WorkloadCancel(dryrun bool, hire string) revel.Result {
+	user := c.currentUser()
+	if (user == nil) || !user.Authenticated() {
+		revel.ERROR.Printf("User not setup correctly")
+		return c.Redirect("/")
+	}
+
+	run := app.Runs.Get(workloadKey(user, dryrun, hire))
+	if (run == nil) || (run.Kind() != workloadKind(dryrun)) {
+		c.Response.Status = http.StatusNotFound
+		return c.RenderJSON(map[string]string{"error": "No workload job is known"})
+	}
+	if !startedBy(user, run) {
+		c.Response.Status = http.StatusForbidden
+		return c.RenderJSON(map[string]string{"error": "Only the user who started the job may cancel it"})
+	}
+	if run.Done() {
+		return c.RenderJSON(map[string]bool{"cancelled": false})
+	}
+
+	revel.INFO.Printf("The user '%s' has cancelled the job", user.Username)
+	run.Cancel()
+	return c.RenderJSON(map[string]bool{"cancelled": true})
+}
+
+// workloadKey identifies the workload job of the hire (by default, the user), which is the hire's onboarding whether
+// it is started here or through the API. Dry runs are told apart, per user, so that previewing doesn't attach to a
+// job changing the repository.
+func workloadKey(user *models.User, dryrun bool, hire string) string {
+	if dryrun {
+		return runKey(planJob, user)
+	}
+	if len(hire) == 0 {
+		hire = user.Username
+	}
+	return onboardingKey(hire)
+}
+
+// workloadKind is the kind of the workload job: a plan for dry runs.
+func workloadKind(dryrun bool) string {
+	if dryrun {
+		return planJob
+	}
+	return workloadJob
+}
+
+// startWorkload starts the workload job of the hire, unless a job is running under its key (or reattach is set); see
+// Registry.Start.
+func startWorkload(user *models.User, dryrun bool, sync bool, resume bool, role string, start string, hire string, reattach bool) (*jobs.Run, bool) {
+	return app.Runs.Start(workloadKey(user, dryrun, hire), workloadKind(dryrun), user.Username, reattach, func(ctx context.Context, events chan<- jobs.Event) cron.Job {
+		return onboarding.GenerateProject{
+			ID:          user.ID,
+			Context:     ctx,
+			Setup:       app.CurrentSetup(),
+			AuthEnv:     user.AuthEnv,
+			New:         events,
+			Role:        role,
+			DryRun:      dryrun,
+			Sync:        sync,
+			Resume:      resume,
+			StartDate:   start,
+			Hire:        hire,
+			Checkpoints: app.Checkpoints,
+			Buddies:     app.Buddies,
+		}
+	})
+}
+
+// Teardown handles the teardown page rendering. The onboarding of the named user (by default, the current user)
+// is only torn down once confirmed, and only by that user, the user who started it, or an admin; see mayTeardown.
+func (c App) Teardown(username string, purge bool, confirm bool) revel.Result {
+	user := c.currentUser()
+	if (user == nil) || !user.Authenticated() {
+		return c.Redirect("/auth?next=teardown")
+	}
+
+	if len(username) == 0 {
+		username = user.Username
+	}
+	if !mayTeardown(user, username) {
+		c.ViewArgs["teardownError"] = fmt.Sprintf("Not allowed to tear down the onboarding of '%s'", username)
+		confirm = false
+	}
+
+	return c.Render(user, username, purge, confirm)
+}
+
+// TeardownSocket handles the websocket connection for teardown events; see WorkloadSocket. The teardown shares the
+// hire's key with their workload, so that neither starts while the other is running; it is only started once
+// confirmed, and when the user may tear down the onboarding.
+func (c App) TeardownSocket(ws *websocket.Conn, username string, purge bool, confirm bool, since int, reattach bool) revel.Result {
+	if ws == nil {
+		revel.ERROR.Printf("Websocket not intialized")
+		return nil
+	}
+	user := c.currentUser()
+	if (user == nil) || !user.Authenticated() {
 		revel.ERROR.Printf("User not setup correctly")
 		return c.Redirect("/")
 	}
 
+	if len(username) == 0 {
+		username = user.Username
+	}
+	if !mayTeardown(user, username) {
+		revel.INFO.Printf("User '%s' may not tear down the onboarding of '%s'", user.Username, username)
+		event := jobs.NewError(user.ID, fmt.Sprintf("Not allowed to tear down the onboarding of '%s'", username), "")
+		websocket.JSON.Send(ws, &event)
+		return nil
+	}
+	if !confirm && !reattach {
+		event := jobs.NewError(user.ID, "The teardown was not confirmed", "")
+		websocket.JSON.Send(ws, &event)
+		return nil
+	}
+
+	run, started := app.Runs.Start(onboardingKey(username), teardownJob, user.Username, reattach, func(ctx context.Context, events chan<- jobs.Event) cron.Job {
+		return onboarding.TeardownProject{
+			ID:          user.ID,
+			Context:     ctx,
+			Setup:       app.CurrentSetup(),
+			AuthEnv:     user.AuthEnv,
+			New:         events,
+			Username:    username,
+			Purge:       purge,
+			Checkpoints: app.Checkpoints,
+			Buddies:     app.Buddies,
+		}
+	})
+	return c.streamJob(ws, user, run, teardownJob, started, since)
+}
+
+// mayTeardown indicates whether the user may tear down the onboarding of the named user: their own, one they
+// started (while its workload is the hire's last job), or any when they are an admin (see onboard.admins).
+func mayTeardown(user *models.User, username string) bool {
+	if strings.EqualFold(user.Username, username) || app.IsAdmin(user.Username) {
+		return true
+	}
+	run := app.Runs.Get(onboardingKey(username))
+	return (run != nil) && (run.Kind() == workloadJob) && startedBy(user, run)
+}
+
+// The kinds of jobs; a hire's workload and teardown share the hire's key (see onboardingKey), so that only one of
+// them runs at a time.
+const (
+	workloadJob = "workload"
+	planJob     = "plan"
+	teardownJob = "teardown"
+)
+
+// runKey identifies the job of a kind run by a user, of which only one runs at a time.
+func runKey(kind string, user *models.User) string {
+	return fmt.Sprintf("%s/%s", kind, strings.ToLower(user.Username))
+}
+
+// onboardingKey identifies the job of a hire's onboarding, of which only one runs at a time.
+func onboardingKey(hire string) string {
+	return fmt.Sprintf("onboarding/%s", strings.ToLower(hire))
+}
+
+// startedBy indicates whether the user started the job's run.
+func startedBy(user *models.User, run *jobs.Run) bool {
+	return strings.EqualFold(run.Owner(), user.Username)
+}
+
+// streamJob relays the events of a job's run of a kind over the websocket, from the sequence number since, until the
+// job completes or the user disconnects. A "cancel" message from the user cancels the job.
+func (c App) streamJob(ws *websocket.Conn, user *models.User, run *jobs.Run, kind string, started bool, since int) revel.Result {
+	if event := refusal(user, run, kind); event != nil {
+		websocket.JSON.Send(ws, event)
+		return nil
+	}
+	if !started {
+		revel.INFO.Printf("The user '%s' has reattached to their job, from event %d", user.Username, since)
+	}
+
 	// In order to select between websocket messages and job events, we
 	// need to stuff websocket events into a channel.
 	newMessages := make(chan string)
@@ -108,50 +449,77 @@ func (c App) WorkloadSocket(ws *websocket.Conn) revel.Result {
 		}
 	}()
 
-	// setup and execute job
-	events := make(chan jobs.Event)
-	job := onboarding.GenerateProject{
-		ID:      user.ID,
-		Setup:   app.Setup,
-		AuthEnv: user.AuthEnv,
-		New:     events,
-	}
-	jobs.StartJob(job)
-
 	// Now listen for new events from either the websocket or the job.
 	for {
-		select {
-		case event, ok := <-events:
-			if !ok {
-				// Completed job events
-				revel.INFO.Printf("The job has completed")
-				return nil
-			}
+		events, done, changed := run.Since(since)
+		for _, event := range events {
 			revel.INFO.Printf("Sending event: %v", event)
 			if websocket.JSON.Send(ws, &event) != nil {
-				// They disconnected.
+				// They disconnected; the job goes on, and they may reattach.
 				revel.INFO.Printf("The user '%s' has disconnected", user.Username)
 				return nil
 			}
+			since = event.Sequence
+		}
+		if done {
+			// Completed job events
+			revel.INFO.Printf("The job has completed")
+			return nil
+		}
+
+		select {
+		case <-changed:
 		case msg, ok := <-newMessages:
 			// If the channel is closed, they disconnected.
 			if !ok {
 				return nil
 			}
-			revel.INFO.Printf("Recieved: " + msg)
+			if msg == "cancel" {
+				if startedBy(user, run) {
+					revel.INFO.Printf("The user '%s' has cancelled the job", user.Username)
+					run.Cancel()
+				} else {
+					revel.INFO.Printf("The user '%s' may not cancel the job of '%s'", user.Username, run.Owner())
+				}
+				continue
+			}
+			revel.WARN.Printf("Unknown message from '%s': %q", user.Username, msg)
 		}
 	}
 }
 
+// checkpoint returns the progress of the unfinished onboarding job of the hire (by default, the user), if any.
+func (c App) checkpoint(setup *onboarding.SetupScheme, user *models.User, hire string) *onboarding.Checkpoint {
+	username := hire
+	if len(username) == 0 {
+		username = user.Username
+	}
+	return loadCheckpoint(setup, username)
+}
+
+// loadCheckpoint returns the checkpoint of a hire's onboarding, if any.
+func loadCheckpoint(setup *onboarding.SetupScheme, username string) *onboarding.Checkpoint {
+	key := onboarding.CheckpointKey(setup.GithubOrganization, setup.GithubRepository, username)
+	checkpoint, err := app.Checkpoints.GetCheckpoint(key)
+	if err != nil {
+		revel.ERROR.Printf("Could not load checkpoint '%s': %v", key, err)
+		return nil
+	}
+	return checkpoint
+}
+
 func (c App) currentUser() *models.User {
 	_, exists := c.Session["uid"]
 	if !exists {
 		return nil
 	}
 
-	var user *models.User
 	uid, _ := strconv.ParseInt(c.Session["uid"], 10, 0)
-	user = models.GetUser(int(uid))
+	user, err := app.Users.GetUser(int(uid))
+	if err != nil {
+		revel.ERROR.Printf("Could not load user %d: %v", uid, err)
+		return nil
+	}
 	c.ViewArgs["user"] = user
 	return user
 }
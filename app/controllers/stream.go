@@ -0,0 +1,133 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/revel/revel"
+	"github.com/samsung-cnct/container-technical-on-boarding/app/jobs"
+	"github.com/samsung-cnct/container-technical-on-boarding/app/models"
+)
+
+const (
+	// pollTimeout is how long a long-poll waits for events, short of the usual proxy timeouts.
+	pollTimeout = 25 * time.Second
+	// keepAlive is how often an idle event stream sends a comment, so that proxies don't close it.
+	keepAlive = 15 * time.Second
+)
+
+// unknownJob is the event of a client reattaching to a job which is no longer known, e.g. after a restart.
+func unknownJob(user *models.User) jobs.Event {
+	return jobs.NewError(user.ID, "The job is no longer known; reload the page to start it again", "")
+}
+
+// busyJob is the event of a client asking for a job while one of another kind runs under its key, e.g. a hire's
+// teardown while their workload is running.
+func busyJob(user *models.User, run *jobs.Run) jobs.Event {
+	return jobs.NewError(user.ID, fmt.Sprintf("A %s job is running for this onboarding; try again once it is done", run.Kind()), "")
+}
+
+// refusal returns the event refusing a client the run of a job of a kind, when it is nil (see unknownJob) or of
+// another kind (see busyJob), and otherwise nil.
+func refusal(user *models.User, run *jobs.Run, kind string) *jobs.Event {
+	var event jobs.Event
+	switch {
+	case run == nil:
+		event = unknownJob(user)
+	case run.Kind() != kind:
+		event = busyJob(user, run)
+	default:
+		return nil
+	}
+	return &event
+}
+
+// eventStream relays the events of a job's run as Server-Sent Events, from the sequence number since (each event's
+// id), until the job completes or the client disconnects. An "end" event tells the client not to reconnect.
+type eventStream struct {
+	user  *models.User
+	run   *jobs.Run
+	kind  string
+	since int
+}
+
+// Apply writes the stream, flushing it after every batch of events.
+func (stream eventStream) Apply(req *revel.Request, resp *revel.Response) {
+	resp.Out.Header().Set("Cache-Control", "no-cache")
+	resp.Out.Header().Set("X-Accel-Buffering", "no") // nginx would otherwise buffer the stream
+	resp.WriteHeader(http.StatusOK, "text/event-stream")
+	flusher, _ := resp.Out.(http.Flusher)
+
+	send := func(format string, args ...interface{}) bool {
+		if _, err := fmt.Fprintf(resp.Out, format, args...); err != nil {
+			// They disconnected; the job goes on, and they may reattach.
+			revel.INFO.Printf("The user '%s' has disconnected", stream.user.Username)
+			return false
+		}
+		return true
+	}
+
+	if event := refusal(stream.user, stream.run, stream.kind); event != nil {
+		data, _ := json.Marshal(event)
+		send("data: %s\n\nevent: end\ndata: {}\n\n", data)
+		return
+	}
+	revel.INFO.Printf("The user '%s' follows their job, from event %d", stream.user.Username, stream.since)
+
+	ticker := time.NewTicker(keepAlive)
+	defer ticker.Stop()
+	for {
+		events, done, changed := stream.run.Since(stream.since)
+		for _, event := range events {
+			data, _ := json.Marshal(event)
+			if !send("id: %d\ndata: %s\n\n", event.Sequence, data) {
+				return
+			}
+			stream.since = event.Sequence
+		}
+		if done {
+			send("event: end\ndata: {}\n\n")
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		if done {
+			// Completed job events
+			revel.INFO.Printf("The job has completed")
+			return
+		}
+
+		select {
+		case <-changed:
+		case <-ticker.C:
+			if !send(": keep-alive\n\n") {
+				return
+			}
+		case <-req.Context().Done():
+			revel.INFO.Printf("The user '%s' has disconnected", stream.user.Username)
+			return
+		}
+	}
+}
+
+// polledEvents is the reply to a long-poll: the events after the client's sequence number, and whether the job has
+// ended (after which the client stops polling).
+type polledEvents struct {
+	Events []jobs.Event
+	Done   bool
+}
+
+// pollEvents waits for the events of a job's run of a kind after the sequence number since.
+func pollEvents(user *models.User, run *jobs.Run, kind string, since int) polledEvents {
+	if event := refusal(user, run, kind); event != nil {
+		return polledEvents{Events: []jobs.Event{*event}, Done: true}
+	}
+
+	events, done := run.Wait(since, pollTimeout)
+	if events == nil {
+		events = []jobs.Event{}
+	}
+	return polledEvents{Events: events, Done: done}
+}
@@ -2,10 +2,17 @@ package app
 
 import (
 	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/masterminds/semver"
 	"github.com/revel/revel"
+	"github.com/samsung-cnct/container-technical-on-boarding/app/jobs"
 	"github.com/samsung-cnct/container-technical-on-boarding/app/jobs/onboarding"
+	"github.com/samsung-cnct/container-technical-on-boarding/app/models"
 )
 
 // Version represents the application version
@@ -14,6 +21,7 @@ type Version struct {
 	Number   string          `json:"number"`
 	Build    string          `json:"build"`
 	Semantic *semver.Version `json:"semantic"`
+	Tasks    string          `json:"tasks,omitempty"` // the version of the onboarding scheme in use
 }
 
 var (
@@ -23,14 +31,32 @@ var (
 	// BuildTime revel app build-time (ldflags)
 	BuildTime string
 
-	// Configs for onboard app loaded from conf/app.conf. These are required at startup.
+	// Configs for onboard app loaded from conf/app.conf. Most are required at startup.
 	Configs = make(map[string]string)
 
-	// Setup contains settings for the onboarding github job
-	Setup *onboarding.SetupScheme
+	// Scheme holds the settings for the onboarding github job, reloaded when the tasks file changes; see CurrentSetup
+	Scheme *onboarding.SchemeWatcher
 
 	// Credentials contains gitub app credentials
 	Credentials *onboarding.Credentials
+
+	// Users persists authenticated users and their tokens
+	Users models.UserStore
+
+	// Checkpoints persists the progress of onboarding jobs, so that failed jobs can be resumed
+	Checkpoints onboarding.CheckpointStore
+
+	// Buddies persists which buddy each hire was paired with
+	Buddies onboarding.BuddyStore
+
+	// Runs keeps the running (and last) jobs of each user, and their events, for clients to follow and reattach to
+	Runs = jobs.NewRegistry()
+
+	// APIKeys authenticate the callers of the API which have no token of their own; they act as onboard.api.token's user
+	APIKeys []string
+
+	// Admins may tear down anyone's onboarding, rather than only their own or the ones they started
+	Admins []string
 )
 
 func init() {
@@ -54,6 +80,7 @@ func init() {
 	revel.OnAppStart(LoadConfigs)
 	revel.OnAppStart(SetupScheme)
 	revel.OnAppStart(SetupCredentials)
+	revel.OnAppStart(SetupUserStore)
 }
 
 // HeaderFilter is used by the revel server
@@ -71,9 +98,21 @@ const (
 	OnboardOrgName          string = "onboard.org"
 	OnboardRepoName         string = "onboard.repo"
 	OnboardTasksFileName    string = "onboard.tasks.file"
-	OnboardUserName         string = "onboard.user"
+	OnboardTasksReloadName  string = "onboard.tasks.reload"
+	OnboardStoreFileName    string = "onboard.store.file"
+	OnboardProviderName     string = "onboard.provider"
+	OnboardGitLabURLName    string = "onboard.gitlab.url"
+	OnboardAPIKeysName      string = "onboard.api.keys"
+	OnboardAPITokenName     string = "onboard.api.token"
+	OnboardAdminsName       string = "onboard.admins"
 )
 
+// DefaultStoreFile is used when no onboard.store.file is configured
+const DefaultStoreFile = "onboarding.db"
+
+// DefaultTasksReload is how often the tasks file is checked for changes when no onboard.tasks.reload is configured
+const DefaultTasksReload = 10 * time.Second
+
 // SetupVersion for revel web app from revel configs
 func SetupVersion() {
 	name := revel.Config.StringDefault("app.name", "")
@@ -98,37 +137,132 @@ func SetupVersion() {
 
 // LoadConfigs for onboarding workflow
 func LoadConfigs() {
-	Configs[OnboardClientIDName] = revel.Config.StringDefault(OnboardClientIDName, "")
-	Configs[OnboardClientSecretName] = revel.Config.StringDefault(OnboardClientSecretName, "")
-	Configs[OnboardOrgName] = revel.Config.StringDefault(OnboardOrgName, "")
-	Configs[OnboardRepoName] = revel.Config.StringDefault(OnboardRepoName, "")
-	Configs[OnboardTasksFileName] = revel.Config.StringDefault(OnboardTasksFileName, "")
-
-	for env, value := range Configs {
-		if len(value) == 0 {
-			revel.ERROR.Fatalf("The '%s' property is required on startup. check the conf/app.conf", env)
+	required := []string{
+		OnboardClientIDName,
+		OnboardClientSecretName,
+		OnboardOrgName,
+		OnboardRepoName,
+		OnboardTasksFileName,
+	}
+
+	for _, name := range required {
+		Configs[name] = revel.Config.StringDefault(name, "")
+		if len(Configs[name]) == 0 {
+			revel.ERROR.Fatalf("The '%s' property is required on startup. check the conf/app.conf", name)
+		}
+	}
+
+	// Optional; GitHub is used unless a GitLab instance is configured.
+	Configs[OnboardProviderName] = revel.Config.StringDefault(OnboardProviderName, "")
+	if len(Configs[OnboardProviderName]) == 0 {
+		Configs[OnboardProviderName] = onboarding.ProviderGitHub
+	}
+	Configs[OnboardGitLabURLName] = revel.Config.StringDefault(OnboardGitLabURLName, "")
+
+	if err := onboarding.ValidateProvider(Configs[OnboardProviderName], Configs[OnboardGitLabURLName]); err != nil {
+		revel.ERROR.Fatalf("Invalid provider configuration, check the conf/app.conf: %v", err)
+	}
+
+	// Optional; API keys need the provider token which their callers act with.
+	for _, key := range strings.Split(revel.Config.StringDefault(OnboardAPIKeysName, ""), ",") {
+		if key = strings.TrimSpace(key); len(key) > 0 {
+			APIKeys = append(APIKeys, key)
+		}
+	}
+	Configs[OnboardAPITokenName] = revel.Config.StringDefault(OnboardAPITokenName, "")
+	if (len(APIKeys) > 0) && (len(Configs[OnboardAPITokenName]) == 0) {
+		revel.ERROR.Fatalf("The '%s' property is required with '%s'. check the conf/app.conf", OnboardAPITokenName, OnboardAPIKeysName)
+	}
+
+	// Optional; the usernames of the admins.
+	for _, admin := range strings.Split(revel.Config.StringDefault(OnboardAdminsName, ""), ",") {
+		if admin = strings.TrimSpace(admin); len(admin) > 0 {
+			Admins = append(Admins, admin)
 		}
 	}
 	revel.INFO.Printf("Configs Loaded")
 }
 
-// SetupScheme for executing an onboarding workflow
+// IsAdmin reports whether the user of the username is one of onboard.admins
+func IsAdmin(username string) bool {
+	for _, admin := range Admins {
+		if strings.EqualFold(admin, username) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetupScheme for executing an onboarding workflow. The tasks file is then watched, and reloaded when it changes or
+// on SIGHUP; an invalid version is reported, and the previous one kept.
 func SetupScheme() {
 	configFilename := Configs[OnboardTasksFileName]
-	setup, err := onboarding.NewSetupScheme(configFilename, &Configs)
+	watcher, err := onboarding.NewSchemeWatcher(configFilename, &Configs)
 	if err != nil {
 		revel.ERROR.Fatalf("Cannat create an onboarding github setup scheme: %v", err)
 	}
-	Setup = setup
-	revel.INFO.Printf("Scheme Setup")
+	Scheme = watcher
+	revel.INFO.Printf("Scheme Setup (version %s)", CurrentSetup().Version)
+
+	interval := DefaultTasksReload
+	if setting := revel.Config.StringDefault(OnboardTasksReloadName, ""); len(setting) > 0 {
+		if interval, err = time.ParseDuration(setting); err != nil {
+			revel.ERROR.Fatalf("Invalid '%s' duration '%s', check the conf/app.conf: %v", OnboardTasksReloadName, setting, err)
+		}
+	}
+
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	go watcher.Watch(interval, hangup, nil, func(replaced bool, err error) {
+		switch {
+		case err != nil:
+			revel.ERROR.Printf("Keeping version %s of the onboarding scheme, as '%s' is invalid:\n%v", CurrentSetup().Version, configFilename, err)
+		case replaced:
+			revel.INFO.Printf("Reloaded the onboarding scheme (version %s)", CurrentSetup().Version)
+		}
+	})
 }
 
-// SetupCredentials for github oauth2 authorization code grant workflow
+// CurrentSetup returns the latest valid onboarding setup scheme. A request (or job) should call it once, and keep
+// the scheme it returns throughout.
+func CurrentSetup() *onboarding.SetupScheme {
+	return Scheme.Current()
+}
+
+// SetupCredentials for github (or gitlab) oauth2 authorization code grant workflow
 func SetupCredentials() {
+	provider := Configs[OnboardProviderName]
+	scopes := []string{"user", "repo", "issues", "milestones"}
+	if provider == onboarding.ProviderGitLab {
+		scopes = []string{"api"}
+	}
+
+	setup := CurrentSetup()
 	Credentials = &onboarding.Credentials{
-		ClientID:     Setup.ClientID,
-		ClientSecret: Setup.ClientSecret,
-		Scopes:       []string{"user", "repo", "issues", "milestones"},
+		ClientID:     setup.ClientID,
+		ClientSecret: setup.ClientSecret,
+		Scopes:       scopes,
+		Provider:     provider,
+		BaseURL:      Configs[OnboardGitLabURLName],
+	}
+	revel.INFO.Printf("Credentials Setup (%s)", provider)
+}
+
+// SetupUserStore opens the persistent store of users, keyed by the app secret, which also holds job checkpoints
+// and buddy assignments
+func SetupUserStore() {
+	filename := revel.Config.StringDefault(OnboardStoreFileName, "")
+	if len(filename) == 0 {
+		filename = DefaultStoreFile
+	}
+
+	secret := revel.Config.StringDefault("app.secret", "")
+	store, err := models.NewBoltStore(filename, secret, Credentials)
+	if err != nil {
+		revel.ERROR.Fatalf("Cannot open the user store '%s': %v", filename, err)
 	}
-	revel.INFO.Printf("Credentials Setup")
+	Users = store
+	Checkpoints = store
+	Buddies = store
+	revel.INFO.Printf("User Store Setup (%s)", filename)
 }
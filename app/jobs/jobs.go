@@ -11,20 +11,21 @@ import (
 // Event of a job
 type Event struct {
 	SessionID int    // The user session id
-	Type      string // "start", "progress", "complete", and "error"
+	Type      string // "start", "progress", "plan", "complete", "cancelled", and "error"
 	Timestamp int    // Unix timestamp (secs)
-	Text      string // What the job progress is (if Type == "progress")
+	Text      string // What the job progress is (if Type == "progress" or "plan")
 	Error     string // Source error (if Type == "error")
+	Sequence  int    // Its position in the events of the job, from 1 (see Run)
 }
 
 // NewEvent creates a new job event
 func NewEvent(sid int, typ string, msg string) Event {
-	return Event{sid, typ, int(time.Now().Unix()), msg, ""}
+	return Event{sid, typ, int(time.Now().Unix()), msg, "", 0}
 }
 
 // NewError creates a new job error event
 func NewError(sid int, msg string, err string) Event {
-	return Event{sid, "error", int(time.Now().Unix()), msg, err}
+	return Event{sid, "error", int(time.Now().Unix()), msg, err, 0}
 }
 
 // StartJob is used start the revel job
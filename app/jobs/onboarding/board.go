@@ -0,0 +1,74 @@
+/*
+This module keeps track of where the issues' cards are on a project's board, so that a run (or its plan) reads the
+board once (see FetchBoardCards) rather than once for each of its tasks.
+*/
+
+package onboarding
+
+import (
+	"github.com/google/go-github/github"
+)
+
+type (
+	// BoardCards maps the issues on a board to their cards, and the columns these are in.
+	BoardCards struct {
+		Columns []*github.ProjectColumn // in the board's order
+		cards   map[string]boardCard    // by the URL of the issue they hold
+	}
+
+	boardCard struct {
+		card   *github.ProjectCard
+		column int // index in Columns
+	}
+)
+
+func newBoardCards(columns []*github.ProjectColumn) *BoardCards {
+	return &BoardCards{Columns: columns, cards: make(map[string]boardCard)}
+}
+
+// columnIndex returns the index of a column on the board, or -1 when it is not on the board.
+func (board *BoardCards) columnIndex(column *github.ProjectColumn) int {
+	for index, candidate := range board.Columns {
+		if candidate.GetID() == column.GetID() {
+			return index
+		}
+	}
+	return -1
+}
+
+// add records the card of an issue, in the column at an index, unless the issue already has one (in an earlier
+// column, as the board is read in order).
+func (board *BoardCards) add(url string, card *github.ProjectCard, column int) {
+	if _, ok := board.cards[url]; !ok && (len(url) > 0) {
+		board.cards[url] = boardCard{card: card, column: column}
+	}
+}
+
+// place records that the card of an issue was created in, or moved to, a column.
+func (board *BoardCards) place(issue *github.Issue, card *github.ProjectCard, column *github.ProjectColumn) {
+	if len(issue.GetURL()) > 0 {
+		board.cards[issue.GetURL()] = boardCard{card: card, column: board.columnIndex(column)}
+	}
+}
+
+// Placement tells what CreateCardForIssue does with the card of an issue, given the column it starts in: CardCreated
+// when the issue has no card, CardMoved when its card is in an earlier column, and otherwise CardPresent (e.g. where
+// the hire moved it, or when the column is not on the board). It returns the card, if any, and its column.
+func (board *BoardCards) Placement(issue *github.Issue, column *github.ProjectColumn) (*github.ProjectCard, *github.ProjectColumn, string) {
+	if (board == nil) || (issue == nil) || (len(issue.GetURL()) == 0) {
+		return nil, nil, CardCreated
+	}
+	placed, ok := board.cards[issue.GetURL()]
+	if !ok {
+		return nil, nil, CardCreated
+	}
+
+	var current *github.ProjectColumn
+	if (placed.column >= 0) && (placed.column < len(board.Columns)) {
+		current = board.Columns[placed.column]
+	}
+	if placed.column >= board.columnIndex(column) {
+		return placed.card, current, CardPresent
+	}
+	return placed.card, current, CardMoved
+}
@@ -0,0 +1,186 @@
+/*
+This module pairs each hire with a buddy (or mentor) from the setup scheme's pool, by round-robin or by least load,
+and records the pairings, so that a hire keeps their buddy across runs and the load is spread over the pool.
+*/
+
+package onboarding
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Strategies for picking a buddy from a pool.
+const (
+	BuddyRoundRobin = "round_robin" // the member after the one picked last
+	BuddyLeastLoad  = "least_load"  // the member paired with the fewest hires
+)
+
+type (
+	// BuddiesEntry declares the pool of buddies (by username) hires are paired with. It may be given as just the pool.
+	BuddiesEntry struct {
+		Pool     []string `yaml:"pool"`
+		Strategy string   `yaml:"strategy,omitempty"` // round_robin (the default) or least_load
+	}
+
+	// BuddyAssignment records the buddy a hire was paired with.
+	BuddyAssignment struct {
+		Key        string    `json:"key"` // see CheckpointKey
+		Hire       string    `json:"hire"`
+		Buddy      string    `json:"buddy"`
+		Role       string    `json:"role,omitempty"`
+		AssignedAt time.Time `json:"assigned_at"`
+	}
+
+	// BuddyStore persists buddy assignments. Implementations must be safe for concurrent use.
+	BuddyStore interface {
+		// GetBuddyAssignment returns an assignment by key, or nil when there is none.
+		GetBuddyAssignment(key string) (*BuddyAssignment, error)
+		// ListBuddyAssignments returns all the assignments, in no particular order.
+		ListBuddyAssignments() ([]BuddyAssignment, error)
+		// SaveBuddyAssignment stores an assignment by its key.
+		SaveBuddyAssignment(assignment *BuddyAssignment) error
+		// DeleteBuddyAssignment removes an assignment, if present.
+		DeleteBuddyAssignment(key string) error
+	}
+)
+
+// buddyMutex serializes picking and recording buddies, so that concurrent jobs don't pick from the same state.
+var buddyMutex sync.Mutex
+
+// UnmarshalYAML accepts a buddy pool declared either as a list of usernames, or in full.
+func (buddies *BuddiesEntry) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var pool []string
+	if err := unmarshal(&pool); err == nil {
+		buddies.Pool = pool
+		return nil
+	}
+
+	type plainBuddies BuddiesEntry
+	return unmarshal((*plainBuddies)(buddies))
+}
+
+// BuddiesForRole returns the buddy pool of a role: its own when it declares one, or else the scheme's.
+func (setup *SetupScheme) BuddiesForRole(role string) *BuddiesEntry {
+	if entry, ok := setup.Roles[role]; ok && (len(entry.Buddies.Pool) > 0) {
+		return &entry.Buddies
+	}
+	return &setup.Buddies
+}
+
+func (setup *SetupScheme) validateBuddies() error {
+	pools := map[string]*BuddiesEntry{"": &setup.Buddies}
+	for _, name := range setup.RoleNames() {
+		entry := setup.Roles[name]
+		pools[name] = &entry.Buddies
+	}
+
+	for role, pool := range pools {
+		where := "The buddy pool"
+		if len(role) > 0 {
+			where = fmt.Sprintf("The buddy pool of role '%s'", role)
+		}
+		switch pool.Strategy {
+		case "", BuddyRoundRobin, BuddyLeastLoad:
+		default:
+			return fmt.Errorf("%s has unknown strategy '%s'; expected %s or %s", where, pool.Strategy, BuddyRoundRobin, BuddyLeastLoad)
+		}
+		seen := make(map[string]bool)
+		for _, username := range pool.Pool {
+			if len(username) == 0 {
+				return fmt.Errorf("%s has an empty username", where)
+			}
+			if seen[strings.ToLower(username)] {
+				return fmt.Errorf("%s lists '%s' twice", where, username)
+			}
+			seen[strings.ToLower(username)] = true
+		}
+	}
+	return nil
+}
+
+// pick chooses a buddy from the pool, given the assignments so far; it returns an empty username for an empty pool.
+func (buddies *BuddiesEntry) pick(assignments []BuddyAssignment) string {
+	if len(buddies.Pool) == 0 {
+		return ""
+	}
+
+	members := make(map[string]int) // pool indexes, by lowercase username
+	for index, username := range buddies.Pool {
+		members[strings.ToLower(username)] = index
+	}
+
+	if buddies.Strategy == BuddyLeastLoad {
+		load := make([]int, len(buddies.Pool))
+		for _, assignment := range assignments {
+			if index, ok := members[strings.ToLower(assignment.Buddy)]; ok {
+				load[index]++
+			}
+		}
+		least := 0
+		for index := range load {
+			if load[index] < load[least] {
+				least = index
+			}
+		}
+		return buddies.Pool[least]
+	}
+
+	last := -1
+	var lastAt time.Time
+	for _, assignment := range assignments {
+		if index, ok := members[strings.ToLower(assignment.Buddy)]; ok && ((last < 0) || assignment.AssignedAt.After(lastAt)) {
+			last, lastAt = index, assignment.AssignedAt
+		}
+	}
+	return buddies.Pool[(last+1)%len(buddies.Pool)]
+}
+
+// buddyFor returns the hire's buddy: the one recorded for them, or else one picked from the role's pool, which is
+// recorded unless dryRun is set. It also indicates whether the buddy was just picked. The buddy is empty when the
+// hire has none, and the pool is empty.
+func (job GenerateProject) buddyFor(username string, role string, dryRun bool) (string, bool, error) {
+	setup := job.Setup
+	key := CheckpointKey(setup.GithubOrganization, setup.GithubRepository, username)
+
+	buddyMutex.Lock()
+	defer buddyMutex.Unlock()
+
+	var assignments []BuddyAssignment
+	if job.Buddies != nil {
+		recorded, err := job.Buddies.GetBuddyAssignment(key)
+		if err != nil {
+			return "", false, err
+		}
+		if recorded != nil {
+			return recorded.Buddy, false, nil
+		}
+		if assignments, err = job.Buddies.ListBuddyAssignments(); err != nil {
+			return "", false, err
+		}
+	}
+
+	buddy := setup.BuddiesForRole(role).pick(assignments)
+	if (len(buddy) == 0) || dryRun || (job.Buddies == nil) {
+		return buddy, len(buddy) > 0, nil
+	}
+
+	assignment := BuddyAssignment{Key: key, Hire: username, Buddy: buddy, Role: role, AssignedAt: time.Now()}
+	if err := job.Buddies.SaveBuddyAssignment(&assignment); err != nil {
+		return "", false, err
+	}
+	return buddy, true, nil
+}
+
+// releaseBuddy forgets the buddy of a hire whose onboarding is torn down, lightening the buddy's load.
+func releaseBuddy(buddies BuddyStore, key string) {
+	if buddies == nil {
+		return
+	}
+	if err := buddies.DeleteBuddyAssignment(key); err != nil {
+		log.Printf("Cannot delete buddy assignment '%s': %v", key, err)
+	}
+}
@@ -0,0 +1,189 @@
+package onboarding
+
+/*
+This module's tests focus on exercising the `buddy.go` module.
+It requires the GitHub Client mock/fixtures implemented in `github_client_test.go`
+*/
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+// testBuddyStore keeps buddy assignments in a map, as a persistent store would.
+type testBuddyStore map[string]BuddyAssignment
+
+func (store testBuddyStore) GetBuddyAssignment(key string) (*BuddyAssignment, error) {
+	assignment, ok := store[key]
+	if !ok {
+		return nil, nil
+	}
+	return &assignment, nil
+}
+
+func (store testBuddyStore) ListBuddyAssignments() ([]BuddyAssignment, error) {
+	var assignments []BuddyAssignment
+	for _, assignment := range store {
+		assignments = append(assignments, assignment)
+	}
+	return assignments, nil
+}
+
+func (store testBuddyStore) SaveBuddyAssignment(assignment *BuddyAssignment) error {
+	store[assignment.Key] = *assignment
+	return nil
+}
+
+func (store testBuddyStore) DeleteBuddyAssignment(key string) error {
+	delete(store, key)
+	return nil
+}
+
+func TestPickBuddy(t *testing.T) {
+	now := time.Now()
+	assignments := []BuddyAssignment{
+		{Key: "a", Buddy: "alice", AssignedAt: now.Add(-3 * time.Hour)},
+		{Key: "b", Buddy: "Bob", AssignedAt: now.Add(-2 * time.Hour)},
+		{Key: "c", Buddy: "alice", AssignedAt: now.Add(-1 * time.Hour)},
+		{Key: "d", Buddy: "someone else", AssignedAt: now},
+	}
+
+	roundRobin := BuddiesEntry{Pool: []string{"alice", "bob", "carol"}}
+	assertEqual(t, roundRobin.pick(nil), "alice", "First pick, actual %v, expected %v")
+	assertEqual(t, roundRobin.pick(assignments), "bob", "Round robin pick, actual %v, expected %v")
+	assertEqual(t, roundRobin.pick(assignments[:2]), "carol", "Round robin pick after bob, actual %v, expected %v")
+
+	leastLoad := BuddiesEntry{Pool: []string{"alice", "bob", "carol"}, Strategy: BuddyLeastLoad}
+	assertEqual(t, leastLoad.pick(assignments), "carol", "Least load pick, actual %v, expected %v")
+	leastLoad.Pool = leastLoad.Pool[:2]
+	assertEqual(t, leastLoad.pick(assignments), "bob", "Least load pick of a smaller pool, actual %v, expected %v")
+
+	empty := BuddiesEntry{}
+	assertEqual(t, empty.pick(assignments), "", "Pick from an empty pool, actual %v, expected %v")
+}
+
+func TestConfigBuddies(t *testing.T) {
+	scheme := SetupScheme{}
+	err := scheme.ingest([]byte(`
+buddies: [alice, bob]
+roles:
+    sre:
+        buddies:
+            pool: [carol]
+            strategy: least_load
+    backend:
+        name: Backend
+`), &map[string]string{})
+
+	if err != nil {
+		t.Fatalf("Loading buddies failed with error: %v", err)
+	}
+
+	assertEqual(t, strings.Join(scheme.BuddiesForRole("").Pool, ","), "alice,bob", "Shared pool, actual %v, expected %v")
+	assertEqual(t, scheme.BuddiesForRole("sre").Strategy, BuddyLeastLoad, "Strategy of a role's pool, actual %v, expected %v")
+	assertEqual(t, strings.Join(scheme.BuddiesForRole("backend").Pool, ","), "alice,bob", "Pool of a role without one, actual %v, expected %v")
+
+	invalid := []string{`
+buddies:
+    pool: [alice]
+    strategy: random
+`, `
+buddies: [alice, Alice]
+`, `
+roles:
+    sre:
+        buddies: [""]
+`}
+
+	for index, yaml := range invalid {
+		scheme := SetupScheme{}
+		if err := scheme.ingest([]byte(yaml), &map[string]string{}); err == nil {
+			t.Errorf("Case %d: expected a buddy pool error", index)
+		}
+	}
+}
+
+func TestBuddyWorkload(t *testing.T) {
+	client := prepareGitHubClientTest()
+	cache := client.Client.(TestGitHubClient).Cache
+	buddies := testBuddyStore{}
+	setup := preparePlanSetup()
+	setup.Buddies = BuddiesEntry{Pool: []string{"alice", "bob"}}
+	setup.Tasks[1].Owners = []indirectAssignee{{GithubUsername: "$buddy"}}
+
+	job := GenerateProject{ID: 42, Setup: setup, AuthEnv: &AuthEnvironment{workflowClient: client}, Hire: "newhire", Buddies: buddies}
+
+	// A dry run shows the pick, without recording it.
+	plan, err := job.Plan()
+	assertIsNil(t, err, "Plan produced an error?! %v")
+	assertEqual(t, plan.Buddy, "alice", "Planned buddy, actual %v, expected %v")
+	assertEqual(t, plan.Changes[len(plan.Changes)-1].String(), "create buddy - alice (picked by round_robin)", "Planned buddy change, actual %v, expected %v")
+	assertEqual(t, len(buddies), 0, "Buddies recorded by a dry run, actual %d, expected %d")
+
+	events := runJobEvents(job)
+	assertNoErrorEvents(t, events, "Workload failed")
+	assertEqual(t, countEvents(events, "Paired @newhire with buddy @alice"), 1, "Pairing events, actual %d, expected %d")
+
+	key := CheckpointKey("testOrganization", "testRepository", "newhire")
+	assertEqual(t, buddies[key].Buddy, "alice", "Recorded buddy, actual %v, expected %v")
+
+	issues, _ := cache["issues"].([]*github.Issue)
+	assertEqual(t, strings.Join(issueAssignees(issues[1]), ","), "alice,test", "Assignees of the buddy's task, actual %v, expected %v")
+	assertEqual(t, strings.Join(issueAssignees(issues[0]), ","), "test", "Assignees of another task, actual %v, expected %v")
+
+	repo, _ := client.GetRepository("testOrganization", "testRepository")
+	milestone, _ := repo.GetMilestoneByTitle(github.String("Welcome @newhire!"))
+	assert(t, strings.HasSuffix(milestone.GetDescription(), "Your buddy is @alice."), "Expected the buddy in the milestone description, found %q", milestone.GetDescription())
+
+	// The hire keeps their buddy; the next hire gets the next one.
+	job.Sync = true
+	events = runJobEvents(job)
+	assertNoErrorEvents(t, events, "Sync failed")
+	assertEqual(t, countEvents(events, "Paired"), 0, "Pairing events of a sync, actual %d, expected %d")
+
+	plan, _ = GenerateProject{ID: 43, Setup: setup, AuthEnv: job.AuthEnv, Hire: "nexthire", Buddies: buddies}.Plan()
+	assertEqual(t, plan.Buddy, "bob", "Buddy of the next hire, actual %v, expected %v")
+
+	// Tearing down releases the buddy.
+	teardown := TeardownProject{ID: 42, Setup: setup, AuthEnv: job.AuthEnv, Username: "newhire", Buddies: buddies}
+	assertNoErrorEvents(t, runTeardownEvents(teardown), "Teardown failed")
+	assertEqual(t, len(buddies), 0, "Buddies recorded after teardown, actual %d, expected %d")
+
+	// An unknown buddy fails the job, and is not recorded.
+	cache["unknownUser"] = "alice"
+	job.Sync = false
+	events = runJobEvents(job)
+	assertEqual(t, events[len(events)-1].Type, "error", "Last event for an unknown buddy, actual %v, expected %v")
+	assertEqual(t, len(buddies), 0, "Buddies recorded for an unknown buddy, actual %d, expected %d")
+}
+
+func TestBuddyReleasedOnFailure(t *testing.T) {
+	client := prepareGitHubClientTest()
+	buddies := testBuddyStore{}
+	setup := preparePlanSetup()
+	setup.Buddies = BuddiesEntry{Pool: []string{"alice", "bob"}}
+
+	// The run fails before anything introduces the buddy.
+	job := GenerateProject{ID: 42, Setup: setup, AuthEnv: &AuthEnvironment{workflowClient: client}, Hire: "newhire", Buddies: buddies, StartDate: "someday"}
+	events := runJobEvents(job)
+	assertEqual(t, countEvents(events, "Paired @newhire with buddy @alice"), 1, "Pairing events, actual %d, expected %d")
+	assertEqual(t, events[len(events)-1].Type, "error", "Last event of a failed run, actual %v, expected %v")
+	assertEqual(t, len(buddies), 0, "Buddies recorded after a failed run, actual %d, expected %d")
+
+	// As is a cancelled one.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	job.StartDate = ""
+	job.Context = ctx
+	events = runJobEvents(job)
+	assertEqual(t, events[len(events)-1].Type, "cancelled", "Last event of a cancelled run, actual %v, expected %v")
+	assertEqual(t, len(buddies), 0, "Buddies recorded after a cancelled run, actual %d, expected %d")
+
+	job.Context = nil
+	assertNoErrorEvents(t, runJobEvents(job), "Workload failed")
+	assertEqual(t, len(buddies), 1, "Buddies recorded after a run, actual %d, expected %d")
+}
@@ -0,0 +1,245 @@
+/*
+This module computes the deadlines of an onboarding on the team's business calendar: working days in the team's
+timezone, skipping weekends and the holidays declared in the setup scheme or an iCalendar file.
+*/
+
+package onboarding
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// dateLayout is the format of the dates in the setup scheme, and of a job's start date.
+const dateLayout = "2006-01-02"
+
+// Deadlines are set at noon, so that they fall on the same date in UTC for most timezones.
+const deadlineHour = 12
+
+type (
+	// ScheduleEntry declares how the deadlines of an onboarding are computed from the hire's start date.
+	ScheduleEntry struct {
+		BusinessDays int            `yaml:"business_days,omitempty"` // the milestone's; three weeks, to a Friday, when 0
+		Timezone     string         `yaml:"timezone,omitempty"`      // e.g. America/Los_Angeles; the server's when empty
+		Holidays     []HolidayEntry `yaml:"holidays,omitempty"`
+		Calendar     string         `yaml:"calendar,omitempty"` // an iCalendar file of holidays, relative to the scheme
+	}
+
+	// HolidayEntry declares a day off. It may be given as just its date (YYYY-MM-DD).
+	HolidayEntry struct {
+		Date string `yaml:"date"`
+		Name string `yaml:"name,omitempty"`
+	}
+
+	// BusinessCalendar tells working days from weekends and holidays, in a timezone.
+	BusinessCalendar struct {
+		Location *time.Location
+		holidays map[string]string // names, by date
+	}
+
+	// Schedule is the deadlines of an onboarding starting on a given date.
+	Schedule struct {
+		Calendar     *BusinessCalendar
+		Start        time.Time
+		BusinessDays int
+	}
+)
+
+// UnmarshalYAML accepts a holiday declared either by its date alone, or in full.
+func (holiday *HolidayEntry) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var date string
+	if err := unmarshal(&date); err == nil {
+		holiday.Date = date
+		return nil
+	}
+
+	type plainHoliday HolidayEntry
+	return unmarshal((*plainHoliday)(holiday))
+}
+
+// calendarFile returns the path of the schedule's iCalendar file, relative to baseDir, or "" when there is none.
+func (schedule *ScheduleEntry) calendarFile(baseDir string) string {
+	if (len(schedule.Calendar) == 0) || filepath.IsAbs(schedule.Calendar) {
+		return schedule.Calendar
+	}
+	return filepath.Join(baseDir, schedule.Calendar)
+}
+
+// NewBusinessCalendar prepares the calendar of a schedule, reading its iCalendar file (if any) relative to baseDir.
+func NewBusinessCalendar(schedule *ScheduleEntry, baseDir string) (*BusinessCalendar, error) {
+	calendar := BusinessCalendar{Location: time.Local, holidays: make(map[string]string)}
+
+	if len(schedule.Timezone) > 0 {
+		location, err := time.LoadLocation(schedule.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("Unknown timezone '%s': %v", schedule.Timezone, err)
+		}
+		calendar.Location = location
+	}
+
+	holidays := schedule.Holidays
+	if filename := schedule.calendarFile(baseDir); len(filename) > 0 {
+		data, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		fromFile, err := parseICalendar(data)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid holiday calendar '%s': %v", schedule.Calendar, err)
+		}
+		holidays = append(append([]HolidayEntry{}, holidays...), fromFile...)
+	}
+
+	for _, holiday := range holidays {
+		day, err := time.Parse(dateLayout, holiday.Date)
+		if err != nil {
+			return nil, fmt.Errorf("Holiday '%s' has invalid date '%s'; expected YYYY-MM-DD", holiday.Name, holiday.Date)
+		}
+		calendar.holidays[day.Format(dateLayout)] = holiday.Name
+	}
+
+	return &calendar, nil
+}
+
+// parseICalendar reads the all-day events of an iCalendar (RFC 5545) file as holidays; events spanning several
+// days (DTEND being exclusive) produce a holiday for each day. Only the date part of DTSTART and DTEND is used.
+func parseICalendar(data []byte) ([]HolidayEntry, error) {
+	text := strings.Replace(string(data), "\r\n", "\n", -1)
+	text = strings.Replace(strings.Replace(text, "\n ", "", -1), "\n\t", "", -1) // unfold long lines
+
+	var holidays []HolidayEntry
+	var inEvent bool
+	var start, end, summary string
+
+	for _, line := range strings.Split(text, "\n") {
+		colon := strings.Index(line, ":")
+		if colon < 0 {
+			continue
+		}
+		name, value := strings.ToUpper(line[:colon]), strings.TrimSpace(line[colon+1:])
+		if semicolon := strings.Index(name, ";"); semicolon >= 0 {
+			name = name[:semicolon] // ignore parameters, e.g. VALUE=DATE
+		}
+
+		switch {
+		case (name == "BEGIN") && (value == "VEVENT"):
+			inEvent = true
+			start, end, summary = "", "", ""
+		case (name == "END") && (value == "VEVENT"):
+			inEvent = false
+			from, err := parseICalendarDate(start)
+			if err != nil {
+				return nil, fmt.Errorf("event '%s' has invalid DTSTART '%s'", summary, start)
+			}
+			to := from.AddDate(0, 0, 1)
+			if len(end) > 0 {
+				if until, err := parseICalendarDate(end); (err == nil) && until.After(from) {
+					to = until
+				}
+			}
+			for day := from; day.Before(to); day = day.AddDate(0, 0, 1) {
+				holidays = append(holidays, HolidayEntry{Date: day.Format(dateLayout), Name: summary})
+			}
+		case inEvent && (name == "DTSTART"):
+			start = value
+		case inEvent && (name == "DTEND"):
+			end = value
+		case inEvent && (name == "SUMMARY"):
+			summary = value
+		}
+	}
+
+	return holidays, nil
+}
+
+func parseICalendarDate(value string) (time.Time, error) {
+	if len(value) < 8 {
+		return time.Time{}, fmt.Errorf("invalid date '%s'", value)
+	}
+	return time.Parse("20060102", value[:8])
+}
+
+// IsBusinessDay indicates whether a day is neither on a weekend nor a holiday.
+func (calendar *BusinessCalendar) IsBusinessDay(day time.Time) bool {
+	day = day.In(calendar.Location)
+	if (day.Weekday() == time.Saturday) || (day.Weekday() == time.Sunday) {
+		return false
+	}
+	_, holiday := calendar.holidays[day.Format(dateLayout)]
+	return !holiday
+}
+
+// NthBusinessDay returns the n-th business day from a start date, counting the start date as the first when it is
+// a business day itself.
+func (calendar *BusinessCalendar) NthBusinessDay(start time.Time, n int) time.Time {
+	day := start
+	for !calendar.IsBusinessDay(day) {
+		day = day.AddDate(0, 0, 1)
+	}
+	for counted := 1; counted < n; {
+		day = day.AddDate(0, 0, 1)
+		if calendar.IsBusinessDay(day) {
+			counted++
+		}
+	}
+	return day
+}
+
+// PreviousBusinessDay returns the day itself when it is a business day, or else the closest business day before it.
+func (calendar *BusinessCalendar) PreviousBusinessDay(day time.Time) time.Time {
+	for !calendar.IsBusinessDay(day) {
+		day = day.AddDate(0, 0, -1)
+	}
+	return day
+}
+
+// NewSchedule computes the schedule of an onboarding starting on a date (YYYY-MM-DD), or today when date is empty.
+func (setup *SetupScheme) NewSchedule(date string) (*Schedule, error) {
+	calendar, err := NewBusinessCalendar(&setup.Schedule, setup.baseDir)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now().In(calendar.Location)
+	if len(date) > 0 {
+		if start, err = time.ParseInLocation(dateLayout, date, calendar.Location); err != nil {
+			return nil, fmt.Errorf("Invalid start date '%s'; expected YYYY-MM-DD", date)
+		}
+	}
+	start = time.Date(start.Year(), start.Month(), start.Day(), deadlineHour, 0, 0, 0, calendar.Location)
+
+	return &Schedule{Calendar: calendar, Start: start, BusinessDays: setup.Schedule.BusinessDays}, nil
+}
+
+// StartDate formats the schedule's start date, e.g. to resume the same schedule later.
+func (schedule *Schedule) StartDate() string {
+	return schedule.Start.Format(dateLayout)
+}
+
+// MilestoneDue returns the due date of the onboarding: its last business day, or by default the Friday of the third
+// full week after the start date (moved back to the previous business day when that Friday is a holiday).
+func (schedule *Schedule) MilestoneDue() time.Time {
+	if schedule.BusinessDays > 0 {
+		return schedule.Calendar.NthBusinessDay(schedule.Start, schedule.BusinessDays)
+	}
+	return schedule.Calendar.PreviousBusinessDay(getMilestoneDueTime(&schedule.Start))
+}
+
+// PhaseDue returns the due date of a phase's milestone: its own due offset, or else the onboarding's.
+func (schedule *Schedule) PhaseDue(phase *PhaseEntry) time.Time {
+	if phase.Due > 0 {
+		return schedule.Calendar.NthBusinessDay(schedule.Start, phase.Due)
+	}
+	return schedule.MilestoneDue()
+}
+
+// TaskDue returns the due date of a task, for tasks declaring a due offset.
+func (schedule *Schedule) TaskDue(task *TaskEntry) (time.Time, bool) {
+	if task.Due < 1 {
+		return time.Time{}, false
+	}
+	return schedule.Calendar.NthBusinessDay(schedule.Start, task.Due), true
+}
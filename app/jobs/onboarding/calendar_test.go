@@ -0,0 +1,146 @@
+package onboarding
+
+/*
+This module's tests focus on exercising the `calendar.go` module.
+*/
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+const testICalendarFixture = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"DTSTART;VALUE=DATE:20170704\r\n" +
+	"SUMMARY:Independence Day\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"DTSTART;VALUE=DATE:20171123\r\n" +
+	"DTEND;VALUE=DATE:20171125\r\n" +
+	"SUMMARY:Thanks\r\n" +
+	" giving\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestParseICalendar(t *testing.T) {
+	holidays, err := parseICalendar([]byte(testICalendarFixture))
+	assertIsNil(t, err, "Parsing the calendar produced an error?! %v")
+	assertEqual(t, len(holidays), 3, "Holidays, actual %d, expected %d")
+	assertEqual(t, holidays[0].Date, "2017-07-04", "First holiday, actual %v, expected %v")
+	assertEqual(t, holidays[2].Date, "2017-11-24", "Last day of a two day event, actual %v, expected %v")
+	assertEqual(t, holidays[2].Name, "Thanksgiving", "Name of a folded summary, actual %v, expected %v")
+
+	_, err = parseICalendar([]byte("BEGIN:VEVENT\nSUMMARY:Undated\nEND:VEVENT\n"))
+	assert(t, err != nil, "Expected an error for an event without a start date")
+}
+
+func TestBusinessCalendar(t *testing.T) {
+	schedule := ScheduleEntry{Timezone: "UTC", Holidays: []HolidayEntry{{Date: "2017-07-04", Name: "Independence Day"}}}
+	calendar, err := NewBusinessCalendar(&schedule, "")
+	assertIsNil(t, err, "Preparing the calendar produced an error?! %v")
+
+	monday := time.Date(2017, 7, 3, 12, 0, 0, 0, time.UTC)
+	assert(t, calendar.IsBusinessDay(monday), "Expected Monday to be a business day")
+	assert(t, !calendar.IsBusinessDay(monday.AddDate(0, 0, 1)), "Expected the holiday not to be a business day")
+	assert(t, !calendar.IsBusinessDay(monday.AddDate(0, 0, -1)), "Expected Sunday not to be a business day")
+
+	cases := []struct {
+		start    time.Time
+		n        int
+		expected string
+	}{
+		{monday, 1, "2017-07-03"},
+		{monday, 2, "2017-07-05"},
+		{monday, 5, "2017-07-10"},
+		{monday.AddDate(0, 0, -2), 1, "2017-07-03"}, // starting on a Saturday
+	}
+	for _, c := range cases {
+		day := calendar.NthBusinessDay(c.start, c.n)
+		assertEqual(t, day.Format(dateLayout), c.expected, "Business day, actual %v, expected %v")
+	}
+
+	assertEqual(t, calendar.PreviousBusinessDay(monday.AddDate(0, 0, 1)).Format(dateLayout), "2017-07-03", "Previous business day, actual %v, expected %v")
+
+	_, err = NewBusinessCalendar(&ScheduleEntry{Timezone: "Nowhere/Special"}, "")
+	assert(t, err != nil, "Expected an error for an unknown timezone")
+	_, err = NewBusinessCalendar(&ScheduleEntry{Holidays: []HolidayEntry{{Date: "July 4th"}}}, "")
+	assert(t, err != nil, "Expected an error for an invalid holiday date")
+}
+
+func TestScheduleDueDates(t *testing.T) {
+	setup := SetupScheme{Schedule: ScheduleEntry{Timezone: "UTC", Holidays: []HolidayEntry{{Date: "2017-07-14"}}}}
+
+	// By default, three weeks to a Friday; that Friday being a holiday, the Thursday before.
+	schedule, err := setup.NewSchedule("2017-06-19")
+	assertIsNil(t, err, "Scheduling produced an error?! %v")
+	assertEqual(t, schedule.StartDate(), "2017-06-19", "Start date, actual %v, expected %v")
+	assertEqual(t, schedule.MilestoneDue().Format(dateLayout), "2017-07-13", "Default milestone due date, actual %v, expected %v")
+	assertEqual(t, schedule.MilestoneDue().Hour(), deadlineHour, "Hour of the due date, actual %v, expected %v")
+
+	setup.Schedule.BusinessDays = 10
+	schedule, _ = setup.NewSchedule("2017-07-03")
+	assertEqual(t, schedule.MilestoneDue().Format(dateLayout), "2017-07-17", "Milestone due date in business days, actual %v, expected %v")
+
+	due, ok := schedule.TaskDue(&TaskEntry{Due: 3})
+	assert(t, ok, "Expected a due date for a task with a due offset")
+	assertEqual(t, due.Format(dateLayout), "2017-07-05", "Task due date, actual %v, expected %v")
+	_, ok = schedule.TaskDue(&TaskEntry{})
+	assert(t, !ok, "Expected no due date for a task without a due offset")
+
+	_, err = setup.NewSchedule("07/03/2017")
+	assert(t, err != nil, "Expected an error for an invalid start date")
+}
+
+func TestIssueBodyDueDate(t *testing.T) {
+	setup := SetupScheme{Schedule: ScheduleEntry{Timezone: "UTC"}}
+	schedule, _ := setup.NewSchedule("2017-07-03")
+	task := TaskEntry{Title: "b", Description: "Do it.\n", DependsOn: []string{"a"}, Due: 2}
+
+	body := issueBody(&task, map[string]int{"a": 7}, schedule)
+	assertEqual(t, body, "Do it.\n\nBlocked by #7\n\nDue by Tuesday, July 4, 2017", "Issue body, actual %q, expected %q")
+	assertEqual(t, issueBody(&task, nil, nil), "Do it.\n", "Issue body without a schedule, actual %q, expected %q")
+
+	kept := preserveDueNote("Do it.\n\nDue by Friday, July 7, 2017", body)
+	assertEqual(t, kept, "Do it.\n\nDue by Tuesday, July 4, 2017", "Preserved due date, actual %q, expected %q")
+}
+
+func TestScheduledWorkload(t *testing.T) {
+	client := prepareGitHubClientTest()
+	setup := preparePlanSetup()
+	setup.Schedule = ScheduleEntry{Timezone: "UTC", BusinessDays: 10}
+	setup.Tasks[1].Due = 2
+
+	job := GenerateProject{
+		ID:        42,
+		Setup:     setup,
+		AuthEnv:   &AuthEnvironment{workflowClient: client},
+		StartDate: "2017-07-03",
+	}
+	assertNoErrorEvents(t, runJobEvents(job), "Workload failed")
+
+	cache := client.Client.(TestGitHubClient).Cache
+	repo, _ := client.GetRepository("testOrganization", "testRepository")
+	title := welcomeTitle(job.AuthEnv.Username())
+	milestone, _ := repo.GetMilestoneByTitle(&title)
+	assertEqual(t, milestone.GetDueOn().Format(dateLayout), "2017-07-14", "Milestone due date, actual %v, expected %v")
+
+	issues, _ := cache["issues"].([]*github.Issue)
+	assert(t, strings.HasSuffix(issues[1].GetBody(), "Due by Tuesday, July 4, 2017"), "Expected a due date in the body, found %q", issues[1].GetBody())
+
+	// Syncing without a start date keeps the due dates, rather than counting from today.
+	job.StartDate = ""
+	job.Sync = true
+	plan, err := job.Plan()
+	assertIsNil(t, err, "Plan produced an error?! %v")
+	assertEqual(t, plan.Count(PlanUpdate), 0, "Planned updates, actual %d, expected %d")
+
+	// A new start date moves them.
+	job.StartDate = "2017-07-10"
+	assertNoErrorEvents(t, runJobEvents(job), "Sync failed")
+	issues, _ = cache["issues"].([]*github.Issue)
+	assert(t, strings.HasSuffix(issues[1].GetBody(), "Due by Tuesday, July 11, 2017"), "Expected a new due date in the body, found %q", issues[1].GetBody())
+}
@@ -0,0 +1,153 @@
+/*
+This module records the progress of GenerateProject runs, so that a run which failed halfway
+(e.g. on a rate limit, or a network blip) can be resumed from its last successful step.
+*/
+
+package onboarding
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+type (
+	// Checkpoint is the progress of a GenerateProject run for a user: the resources it has prepared so far.
+	Checkpoint struct {
+		Key         string                     `json:"key"` // see CheckpointKey
+		Role        string                     `json:"role,omitempty"`
+		StartDate   string                     `json:"start_date,omitempty"` // YYYY-MM-DD
+		LabelsReady bool                       `json:"labels_ready,omitempty"`
+		Milestones  map[string]int             `json:"milestones,omitempty"` // numbers, by title
+		ProjectID   int                        `json:"project_id,omitempty"`
+		ColumnIDs   map[string]int             `json:"column_ids,omitempty"` // by column name
+		Issues      map[string]CheckpointIssue `json:"issues,omitempty"`     // by task title
+		UpdatedAt   time.Time                  `json:"updated_at"`
+	}
+
+	// CheckpointIssue is an issue created by a run, and whether its card was placed on the project.
+	CheckpointIssue struct {
+		ID     int    `json:"id"`
+		Number int    `json:"number"`
+		URL    string `json:"url,omitempty"` // identifies the issue's card
+		Card   bool   `json:"card,omitempty"`
+	}
+
+	// CheckpointStore persists checkpoints. Implementations must be safe for concurrent use.
+	CheckpointStore interface {
+		// GetCheckpoint returns a checkpoint by key, or nil when there is none.
+		GetCheckpoint(key string) (*Checkpoint, error)
+		// SaveCheckpoint stores a checkpoint by its key.
+		SaveCheckpoint(checkpoint *Checkpoint) error
+		// DeleteCheckpoint removes a checkpoint, if present.
+		DeleteCheckpoint(key string) error
+	}
+)
+
+// CheckpointKey identifies the checkpoint of a user's onboarding in a repository.
+func CheckpointKey(organization string, repository string, username string) string {
+	return fmt.Sprintf("%s/%s/%s", organization, repository, username)
+}
+
+// NewCheckpoint creates an empty checkpoint, for a run starting from scratch.
+func NewCheckpoint(key string, role string) *Checkpoint {
+	return &Checkpoint{
+		Key:        key,
+		Role:       role,
+		Milestones: make(map[string]int),
+		ColumnIDs:  make(map[string]int),
+		Issues:     make(map[string]CheckpointIssue),
+	}
+}
+
+// Started indicates whether the run got past its first step.
+func (checkpoint *Checkpoint) Started() bool {
+	return checkpoint.LabelsReady || (len(checkpoint.Milestones) > 0)
+}
+
+// milestone stands in for a milestone recorded by the checkpoint; only its number is known.
+func (checkpoint *Checkpoint) milestone(title string) (*github.Milestone, bool) {
+	number, ok := checkpoint.Milestones[title]
+	if !ok {
+		return nil, false
+	}
+	return &github.Milestone{Number: github.Int(number), Title: github.String(title)}, true
+}
+
+// columns stands in for the checkpoint's project columns, mapped by name.
+func (checkpoint *Checkpoint) columns() map[string](*github.ProjectColumn) {
+	columns := make(map[string](*github.ProjectColumn))
+	for name, id := range checkpoint.ColumnIDs {
+		columns[name] = &github.ProjectColumn{ID: github.Int(id), Name: github.String(name)}
+	}
+	return columns
+}
+
+func newCheckpointIssue(issue *github.Issue, card bool) CheckpointIssue {
+	return CheckpointIssue{ID: issue.GetID(), Number: issue.GetNumber(), URL: issue.GetURL(), Card: card}
+}
+
+// issue stands in for an issue created by the run; only its ID, number and URL are known.
+func (checkpoint *Checkpoint) issue(title string) (*github.Issue, bool) {
+	recorded, ok := checkpoint.Issues[title]
+	if !ok {
+		return nil, false
+	}
+	issue := github.Issue{
+		ID:     github.Int(recorded.ID),
+		Number: github.Int(recorded.Number),
+		Title:  github.String(title),
+		URL:    github.String(recorded.URL),
+	}
+	return &issue, true
+}
+
+// loadCheckpoint returns the checkpoint a run continues from: the stored one when resuming, or else a new one.
+func (job GenerateProject) loadCheckpoint(username string) *Checkpoint {
+	key := CheckpointKey(job.Setup.GithubOrganization, job.Setup.GithubRepository, username)
+
+	if job.Resume && (job.Checkpoints != nil) {
+		checkpoint, err := job.Checkpoints.GetCheckpoint(key)
+		if err != nil {
+			log.Printf("Cannot load checkpoint '%s', starting over: %v", key, err)
+		} else if checkpoint != nil {
+			if checkpoint.Milestones == nil {
+				checkpoint.Milestones = make(map[string]int)
+			}
+			if checkpoint.ColumnIDs == nil {
+				checkpoint.ColumnIDs = make(map[string]int)
+			}
+			if checkpoint.Issues == nil {
+				checkpoint.Issues = make(map[string]CheckpointIssue)
+			}
+			return checkpoint
+		}
+	}
+
+	checkpoint := NewCheckpoint(key, job.Role)
+	checkpoint.StartDate = job.StartDate
+	return checkpoint
+}
+
+// saveCheckpoint records the run's progress; failing to do so only costs the ability to resume.
+func (job GenerateProject) saveCheckpoint(checkpoint *Checkpoint) {
+	if job.Checkpoints == nil {
+		return
+	}
+	checkpoint.UpdatedAt = time.Now()
+	if err := job.Checkpoints.SaveCheckpoint(checkpoint); err != nil {
+		log.Printf("Cannot save checkpoint '%s': %v", checkpoint.Key, err)
+	}
+}
+
+// clearCheckpoint forgets a completed run.
+func (job GenerateProject) clearCheckpoint(checkpoint *Checkpoint) {
+	if job.Checkpoints == nil {
+		return
+	}
+	if err := job.Checkpoints.DeleteCheckpoint(checkpoint.Key); err != nil {
+		log.Printf("Cannot delete checkpoint '%s': %v", checkpoint.Key, err)
+	}
+}
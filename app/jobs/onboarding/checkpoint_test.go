@@ -0,0 +1,151 @@
+package onboarding
+
+/*
+This module's tests focus on exercising the `checkpoint.go` module.
+It requires the GitHub Client mock/fixtures implemented in `github_client_test.go`
+*/
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/github"
+	"github.com/samsung-cnct/container-technical-on-boarding/app/jobs"
+)
+
+// testCheckpointStore round-trips checkpoints through JSON, as a persistent store would.
+type testCheckpointStore map[string][]byte
+
+func (store testCheckpointStore) GetCheckpoint(key string) (*Checkpoint, error) {
+	data, ok := store[key]
+	if !ok {
+		return nil, nil
+	}
+	checkpoint := Checkpoint{}
+	err := json.Unmarshal(data, &checkpoint)
+	return &checkpoint, err
+}
+
+func (store testCheckpointStore) SaveCheckpoint(checkpoint *Checkpoint) error {
+	data, err := json.Marshal(checkpoint)
+	store[checkpoint.Key] = data
+	return err
+}
+
+func (store testCheckpointStore) DeleteCheckpoint(key string) error {
+	delete(store, key)
+	return nil
+}
+
+func countEvents(events []jobs.Event, prefix string) int {
+	count := 0
+	for _, event := range events {
+		if strings.HasPrefix(event.Text, prefix) {
+			count++
+		}
+	}
+	return count
+}
+
+func TestResumeWorkload(t *testing.T) {
+	client := prepareGitHubClientTest()
+	cache := client.Client.(TestGitHubClient).Cache
+	store := testCheckpointStore{}
+
+	setup := preparePlanSetup()
+	setup.Tasks = append(setup.Tasks, TaskEntry{Title: "test3", Description: "test", Assignee: indirectAssignee{GithubUsername: "test"}})
+
+	job := GenerateProject{
+		ID:          42,
+		Setup:       setup,
+		AuthEnv:     &AuthEnvironment{workflowClient: client},
+		Checkpoints: store,
+	}
+
+	cache["failingTitle"] = "test2"
+	events := runJobEvents(job)
+	assertEqual(t, events[len(events)-1].Type, "error", "Last event of a failing run, actual %v, expected %v")
+
+	key := CheckpointKey("testOrganization", "testRepository", job.AuthEnv.Username())
+	checkpoint, _ := store.GetCheckpoint(key)
+	if checkpoint == nil {
+		t.Fatalf("Expected a checkpoint after a failing run")
+	}
+	assertEqual(t, len(checkpoint.Milestones), 1, "Checkpoint milestones, actual %d, expected %d")
+	assertEqual(t, len(checkpoint.ColumnIDs), len(defaultBoard), "Checkpoint columns, actual %d, expected %d")
+	assertEqual(t, len(checkpoint.Issues), 1, "Checkpoint issues, actual %d, expected %d")
+	assert(t, checkpoint.Issues["test1"].Card, "Checkpoint should record the card of test1")
+
+	delete(cache, "failingTitle")
+	job.Resume = true
+	events = runJobEvents(job)
+	assertNoErrorEvents(t, events, "Resumed workload failed")
+
+	assertEqual(t, countEvents(events, "Resuming from the checkpoint"), 1, "Resuming events, actual %d, expected %d")
+	assertEqual(t, countEvents(events, "Creating Milestone"), 0, "Milestones created on resume, actual %d, expected %d")
+	assertEqual(t, countEvents(events, "Creating Project"), 0, "Projects created on resume, actual %d, expected %d")
+	assertEqual(t, countEvents(events, "Preparing Issue"), 2, "Issues prepared on resume, actual %d, expected %d")
+
+	issues, _ := cache["issues"].([]*github.Issue)
+	assertEqual(t, len(issues), 3, "Issues after resuming, actual %d, expected %d")
+
+	checkpoint, _ = store.GetCheckpoint(key)
+	assert(t, checkpoint == nil, "Checkpoint of a completed run should be deleted, found %v", checkpoint)
+}
+
+func TestResumeWithoutCheckpoint(t *testing.T) {
+	client := prepareGitHubClientTest()
+	job := GenerateProject{
+		ID:          42,
+		Setup:       preparePlanSetup(),
+		AuthEnv:     &AuthEnvironment{workflowClient: client},
+		Resume:      true,
+		Checkpoints: testCheckpointStore{},
+	}
+
+	events := runJobEvents(job)
+	assertNoErrorEvents(t, events, "Resuming without a checkpoint failed")
+	assertEqual(t, countEvents(events, "Resuming from the checkpoint"), 0, "Resuming events, actual %d, expected %d")
+	assertEqual(t, countEvents(events, "Preparing Issue"), 2, "Issues prepared, actual %d, expected %d")
+}
+
+func TestResumeUsesCheckpointRole(t *testing.T) {
+	client := prepareGitHubClientTest()
+	store := testCheckpointStore{}
+
+	setup := preparePlanSetup()
+	setup.Roles = map[string]RoleEntry{
+		"sre": {Tasks: []TaskEntry{{Title: "pager", Description: "test", Assignee: indirectAssignee{GithubUsername: "test"}}}},
+	}
+
+	job := GenerateProject{
+		ID:          42,
+		Setup:       setup,
+		AuthEnv:     &AuthEnvironment{workflowClient: client},
+		Resume:      true,
+		Checkpoints: store,
+	}
+	key := CheckpointKey("testOrganization", "testRepository", job.AuthEnv.Username())
+	store.SaveCheckpoint(NewCheckpoint(key, "sre"))
+
+	assertNoErrorEvents(t, runJobEvents(job), "Resumed workload failed")
+
+	issues, _ := client.Client.(TestGitHubClient).Cache["issues"].([]*github.Issue)
+	assertEqual(t, len(issues), 3, "Issues of the checkpoint's role, actual %d, expected %d")
+}
+
+func TestTeardownDeletesCheckpoint(t *testing.T) {
+	client := prepareGitHubClientTest()
+	cache := client.Client.(TestGitHubClient).Cache
+	store := testCheckpointStore{}
+	auth := &AuthEnvironment{workflowClient: client}
+	setup := preparePlanSetup()
+
+	cache["failingTitle"] = "test2"
+	runJobEvents(GenerateProject{ID: 42, Setup: setup, AuthEnv: auth, Checkpoints: store})
+	assertEqual(t, len(store), 1, "Checkpoints after a failing run, actual %d, expected %d")
+
+	assertNoErrorEvents(t, runTeardownEvents(TeardownProject{ID: 42, Setup: setup, AuthEnv: auth, Checkpoints: store}), "Teardown failed")
+	assertEqual(t, len(store), 0, "Checkpoints after teardown, actual %d, expected %d")
+}
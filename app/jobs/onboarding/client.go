@@ -1,4 +1,4 @@
-/*	This package abstracts authentication with GitHub's API.
+/*	This package abstracts authentication with GitHub's (or GitLab's) API.
 	Primarily this extract OAuth integration from the rest of the business logic,
 	enabling better testing of business logic without dependency on GitHub's actual
 	service.
@@ -11,6 +11,7 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"strings"
 
 	"github.com/google/go-github/github"
 	uuid "github.com/satori/go.uuid"
@@ -18,6 +19,12 @@ import (
 	githuboauth "golang.org/x/oauth2/github"
 )
 
+// Supported values of Credentials.Provider.
+const (
+	ProviderGitHub = "github"
+	ProviderGitLab = "gitlab"
+)
+
 type (
 	// AuthEnvironment provides a simple model for OAuth context abstraction.
 	AuthEnvironment struct {
@@ -25,18 +32,48 @@ type (
 		Config         *oauth2.Config
 		StateString    string
 		AccessToken    *oauth2.Token
-		workflowClient *WorkflowClient
+		Provider       string
+		BaseURL        string
+		workflowClient iClientAccess
 	}
 
-	// Credentials for a GitHub application, integrating with GitHub API.
+	// Credentials for a GitHub (or GitLab) application, integrating with its API.
 	Credentials struct {
 		ClientID     string
 		ClientSecret string
 		Scopes       []string
+		Provider     string // ProviderGitHub (the default) or ProviderGitLab
+		BaseURL      string // root URL of a self-hosted GitLab, e.g. https://gitlab.example.com
 	}
 )
 
-// NewAuthEnvironment prepares a new OAuth2 authenticated GitHub login environment.
+// ValidateProvider checks that a provider is supported, and has the settings it requires.
+func ValidateProvider(provider string, baseURL string) error {
+	switch provider {
+	case "", ProviderGitHub:
+		return nil
+	case ProviderGitLab:
+		if len(baseURL) == 0 {
+			return errors.New("A GitLab URL is required for the gitlab provider")
+		}
+		return nil
+	}
+	return fmt.Errorf("Unknown provider '%s'; expected '%s' or '%s'", provider, ProviderGitHub, ProviderGitLab)
+}
+
+// endpoint returns the OAuth2 endpoint of the credentials' provider.
+func (creds *Credentials) endpoint() oauth2.Endpoint {
+	if creds.Provider == ProviderGitLab {
+		base := strings.TrimRight(creds.BaseURL, "/")
+		return oauth2.Endpoint{
+			AuthURL:  base + "/oauth/authorize",
+			TokenURL: base + "/oauth/token",
+		}
+	}
+	return githuboauth.Endpoint
+}
+
+// NewAuthEnvironment prepares a new OAuth2 authenticated GitHub (or GitLab) login environment.
 func (creds *Credentials) NewAuthEnvironment() *AuthEnvironment {
 	var (
 		authContext = oauth2.NoContext
@@ -44,7 +81,7 @@ func (creds *Credentials) NewAuthEnvironment() *AuthEnvironment {
 			ClientID:     creds.ClientID,
 			ClientSecret: creds.ClientSecret,
 			Scopes:       creds.Scopes,
-			Endpoint:     githuboauth.Endpoint,
+			Endpoint:     creds.endpoint(),
 		}
 		oauthStateString = uuid.NewV4().String()
 	)
@@ -52,9 +89,19 @@ func (creds *Credentials) NewAuthEnvironment() *AuthEnvironment {
 		Context:     authContext,
 		Config:      &config,
 		StateString: oauthStateString,
+		Provider:    creds.Provider,
+		BaseURL:     creds.BaseURL,
 	}
 }
 
+// RestoreAuthEnvironment rebuilds a login environment from a previously saved OAuth2 state and token.
+func (creds *Credentials) RestoreAuthEnvironment(state string, token *oauth2.Token) *AuthEnvironment {
+	auth := creds.NewAuthEnvironment()
+	auth.StateString = state
+	auth.AccessToken = token
+	return auth
+}
+
 // AuthCodeURL gets and returns the oauth2 providers authorization URL
 func (auth *AuthEnvironment) AuthCodeURL() string {
 	oauthStateString := auth.StateString
@@ -63,7 +110,10 @@ func (auth *AuthEnvironment) AuthCodeURL() string {
 	return url
 }
 
-func (auth *AuthEnvironment) newWorkflowClient() (*WorkflowClient, error) {
+// newWorkflowClient connects to the provider's API, making every request in ctx (the environment's when nil), so that
+// cancelling ctx interrupts them. Rate limits are waited out, and transient failures retried, telling notify (if
+// set) of each wait.
+func (auth *AuthEnvironment) newWorkflowClient(ctx context.Context, notify RetryNotifier) (iClientAccess, error) {
 	if auth.workflowClient != nil {
 		return auth.workflowClient, nil
 	}
@@ -73,9 +123,25 @@ func (auth *AuthEnvironment) newWorkflowClient() (*WorkflowClient, error) {
 	}
 
 	oauthClient := auth.Config.Client(auth.Context, auth.AccessToken)
-	githubClient := github.NewClient(oauthClient)
-	workflow := WorkflowClient{auth.Context, NewGitHubWrapper(githubClient)}
-	return &workflow, nil
+	oauthClient.Transport = NewRetryTransport(oauthClient.Transport, notify)
+	if ctx == nil {
+		ctx = auth.Context
+	}
+
+	switch auth.Provider {
+	case "", ProviderGitHub:
+		githubClient := github.NewClient(oauthClient)
+		workflow := WorkflowClient{ctx, NewGitHubWrapper(githubClient)}
+		return &workflow, nil
+	case ProviderGitLab:
+		gitlabClient, err := NewGitLabClient(ctx, oauthClient, auth.BaseURL)
+		if err != nil {
+			return nil, err
+		}
+		return gitlabClient, nil
+	}
+
+	return nil, fmt.Errorf("Unknown provider '%s'", auth.Provider)
 }
 
 // SetupAccessToken gets and sets an oauth2 access token based on a recieved oauth2 code
@@ -91,17 +157,35 @@ func (auth *AuthEnvironment) SetupAccessToken(code string) (*oauth2.Token, error
 
 }
 
-// GithubUsername retrives the github username via that git api
-func (auth *AuthEnvironment) GithubUsername() string {
+// Username retrieves the authenticated user's username via the provider's API
+func (auth *AuthEnvironment) Username() string {
 	if auth.AccessToken == nil {
 		return ""
 	}
 
-	oauthClient := auth.Config.Client(auth.Context, auth.AccessToken)
-	githubClient := github.NewClient(oauthClient)
-	githubUser, _, err := githubClient.Users.Get(auth.Context, "")
+	client, err := auth.newWorkflowClient(nil, nil)
+	if err != nil {
+		log.Printf("Failed to get user: %v", err)
+		return ""
+	}
+
+	username, err := client.currentUsername()
+	if err != nil {
+		log.Printf("Failed to get user: %v", err)
+	}
+	return username
+}
+
+// ResolveUsername validates a username with the provider, returning the user's login as the provider spells it.
+func (auth *AuthEnvironment) ResolveUsername(username string) (string, error) {
+	client, err := auth.newWorkflowClient(nil, nil)
+	if err != nil {
+		return "", err
+	}
+
+	user, err := client.resolveUser(&username)
 	if err != nil {
-		log.Printf("Failed to get github user: %v", err)
+		return "", err
 	}
-	return *githubUser.Login
+	return user.GetLogin(), nil
 }
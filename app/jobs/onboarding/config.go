@@ -1,24 +1,85 @@
 package onboarding
 
 import (
-	"bytes"
-	"html/template"
+	"fmt"
 	"io/ioutil"
-	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var labelColorPattern = regexp.MustCompile("^[0-9a-fA-F]{6}$")
 
-	yaml "gopkg.in/yaml.v2"
+// Column automation presets, as offered by GitHub's project boards.
+const (
+	PresetTodo       = "todo"
+	PresetInProgress = "in_progress"
+	PresetDone       = "done"
 )
 
+// defaultBoard is the layout of projects whose scheme declares no columns.
+var defaultBoard = []ColumnEntry{
+	{Name: "Backlog", Preset: PresetTodo},
+	{Name: "In Progress", Preset: PresetInProgress},
+	{Name: "Review"},
+	{Name: "Done", Preset: PresetDone},
+}
+
 type (
 	// TaskEntry represents individual tasks to be assigned
+	// Its issue is assigned to the Assignee and to every one of the Owners, e.g. the new hire and their buddy.
 	TaskEntry struct {
 		Title       string
-		Assignee    indirectAssignee `yaml:"assignee"`
-		Description string           `yaml:"description,omitempty"`
+		Assignee    indirectAssignee   `yaml:"assignee"`
+		Owners      []indirectAssignee `yaml:"owners,omitempty"`
+		Description string             `yaml:"description,omitempty"`
+		DependsOn   []string           `yaml:"depends_on,omitempty"` // titles of prerequisite tasks
+		Labels      []LabelEntry       `yaml:"labels,omitempty"`
+		Column      string             `yaml:"column,omitempty"` // the board column the task's card starts in
+		Due         int                `yaml:"due,omitempty"`    // the business day it's due on, the start date being the first
+		Phase       string             `yaml:"phase,omitempty"`  // the name of the task's phase; the first phase when empty
+	}
+
+	// PhaseEntry declares a stage of the onboarding (e.g. "week 1", "month 1"), which gets a milestone of its own.
+	// In the milestone's title and description, $username stands for the hire's username.
+	PhaseEntry struct {
+		Name        string `yaml:"name"`
+		Title       string `yaml:"title,omitempty"` // "<name>: Welcome @$username!" when empty
+		Description string `yaml:"description,omitempty"`
+		Due         int    `yaml:"due,omitempty"` // the business day it's due on; the schedule's milestone due date when 0
+	}
+
+	// ColumnEntry declares a column of the project board. It may be given as just its name.
+	// Preset names the automation the column is meant for: "todo", "in_progress" or "done".
+	ColumnEntry struct {
+		Name   string `yaml:"name"`
+		Preset string `yaml:"preset,omitempty"`
+	}
+
+	// LabelEntry declares a repository label. It may be given as just its name,
+	// in which case the color and description come from another declaration (if any).
+	LabelEntry struct {
+		Name        string `yaml:"name"`
+		Color       string `yaml:"color,omitempty"` // hexadecimal, without the leading '#'
+		Description string `yaml:"description,omitempty"`
 	}
 
+	// indirectAssignee is a username, or a reference by name to one of the scheme's task_owners (resolved on load).
 	indirectAssignee struct {
 		GithubUsername string `yaml:"github_username"`
+
+		owner string // the name of the task owner it refers to
+	}
+
+	// RoleEntry tailors the shared tasks for a track of new hires, e.g. backend engineers or SREs.
+	// Role tasks are added to the shared tasks, replacing any shared task with the same title.
+	RoleEntry struct {
+		Name    string       `yaml:"name"`
+		Tasks   []TaskEntry  `yaml:"tasks,omitempty"`
+		Remove  []string     `yaml:"remove,omitempty"`  // titles of shared tasks which don't apply to the role
+		Buddies BuddiesEntry `yaml:"buddies,omitempty"` // the scheme's pool when empty
 	}
 
 	// SetupScheme represents the whole workload to be scheduled.
@@ -27,8 +88,19 @@ type (
 		ClientSecret       string                      `yaml:"clientSecret"`
 		GithubOrganization string                      `yaml:"githubOrganization"`
 		GithubRepository   string                      `yaml:"githubRepository"`
+		Include            []string                    `yaml:"include,omitempty"` // files, directories or globs, merged first
 		Tasks              []TaskEntry                 `yaml:"tasks"`
 		TaskOwners         map[string]indirectAssignee `yaml:"task_owners"`
+		Roles              map[string]RoleEntry        `yaml:"roles,omitempty"`
+		Labels             []LabelEntry                `yaml:"labels,omitempty"`  // applied to every generated issue
+		Columns            []ColumnEntry               `yaml:"columns,omitempty"` // the board layout, in order
+		Schedule           ScheduleEntry               `yaml:"schedule,omitempty"`
+		Phases             []PhaseEntry                `yaml:"phases,omitempty"` // in order; a single milestone when none
+		Buddies            BuddiesEntry                `yaml:"buddies,omitempty"`
+
+		Version string   `yaml:"-"` // a hash of the scheme's files, telling its versions apart
+		baseDir string   // of the scheme's file, for the files it refers to
+		sources []string // the files and directories the scheme was read from
 	}
 )
 
@@ -36,43 +108,589 @@ func (assignee *indirectAssignee) String() string {
 	return assignee.GithubUsername
 }
 
-func (setup *SetupScheme) ingest(data []byte, environ *map[string]string) error {
-	var rendered bytes.Buffer
+// UnmarshalYAML accepts an assignee declared either by the name of a task owner, or with its username. Task owners
+// themselves must be declared with their username; see lint.
+func (assignee *indirectAssignee) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var owner string
+	if err := unmarshal(&owner); err == nil {
+		assignee.owner = owner
+		return nil
+	}
+
+	type plainAssignee indirectAssignee
+	return unmarshal((*plainAssignee)(assignee))
+}
 
-	context := map[string]map[string]string{
-		"Environ": *environ,
+// UnmarshalYAML accepts a label declared either by name alone, or in full.
+func (label *LabelEntry) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var name string
+	if err := unmarshal(&name); err == nil {
+		label.Name = name
+		return nil
 	}
 
-	tpl, err := template.New("config").Parse(string(data))
+	type plainLabel LabelEntry
+	return unmarshal((*plainLabel)(label))
+}
 
-	if err != nil {
+// UnmarshalYAML accepts a column declared either by name alone, or in full.
+func (column *ColumnEntry) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var name string
+	if err := unmarshal(&name); err == nil {
+		column.Name = name
+		return nil
+	}
+
+	type plainColumn ColumnEntry
+	return unmarshal((*plainColumn)(column))
+}
+
+// Board returns the declared columns of the project board, or the default layout when none are declared.
+func (setup *SetupScheme) Board() []ColumnEntry {
+	if len(setup.Columns) == 0 {
+		return defaultBoard
+	}
+	return setup.Columns
+}
+
+// ColumnNames lists the names of the board's columns, in order.
+func (setup *SetupScheme) ColumnNames() []string {
+	var names []string
+	for _, column := range setup.Board() {
+		names = append(names, column.Name)
+	}
+	return names
+}
+
+// StartColumn names the column a task's card is placed in: the task's own choice, or else
+// the board's first "todo" column, or else its first column.
+func (setup *SetupScheme) StartColumn(task *TaskEntry) string {
+	if len(task.Column) > 0 {
+		return task.Column
+	}
+	board := setup.Board()
+	for _, column := range board {
+		if column.Preset == PresetTodo {
+			return column.Name
+		}
+	}
+	return board[0].Name
+}
+
+// PhaseList returns the declared phases, or else the single phase of an onboarding without phases,
+// whose milestone is titled (like the project) "Welcome @user!".
+func (setup *SetupScheme) PhaseList() []PhaseEntry {
+	if len(setup.Phases) == 0 {
+		return []PhaseEntry{{}}
+	}
+	return setup.Phases
+}
+
+// TaskPhase returns the phase of a task: the one it names, or else the first.
+func (setup *SetupScheme) TaskPhase(task *TaskEntry) PhaseEntry {
+	phases := setup.PhaseList()
+	for _, phase := range phases {
+		if phase.Name == task.Phase {
+			return phase
+		}
+	}
+	return phases[0]
+}
+
+// PhasesForTasks lists the phases (in order) which at least one of the given tasks belongs to.
+func (setup *SetupScheme) PhasesForTasks(tasks []TaskEntry) []PhaseEntry {
+	used := make(map[string]bool)
+	for _, task := range tasks {
+		used[setup.TaskPhase(&task).Name] = true
+	}
+
+	var phases []PhaseEntry
+	for _, phase := range setup.PhaseList() {
+		if used[phase.Name] {
+			phases = append(phases, phase)
+		}
+	}
+	return phases
+}
+
+// expandOwners replaces $username (or ${username}), i.e. the hire, and $buddy, i.e. the hire's buddy, in a phase's
+// text or a task's assignee.
+func expandOwners(text string, username string, buddy string) string {
+	return os.Expand(text, func(name string) string {
+		switch name {
+		case "username":
+			return username
+		case "buddy":
+			return buddy
+		}
+		return ""
+	})
+}
+
+// TaskAssignees returns the usernames a task's issue is assigned to, for a hire and their buddy, without duplicates;
+// an assignee of $username stands for the hire, and one of $buddy for the buddy (it is dropped when there is none).
+func (setup *SetupScheme) TaskAssignees(task *TaskEntry, username string, buddy string) []string {
+	assignees := []string{}
+	seen := make(map[string]bool)
+	for _, assignee := range append([]indirectAssignee{task.Assignee}, task.Owners...) {
+		login := expandOwners(assignee.GithubUsername, username, buddy)
+		if (len(login) == 0) || seen[strings.ToLower(login)] {
+			continue
+		}
+		seen[strings.ToLower(login)] = true
+		assignees = append(assignees, login)
+	}
+	return assignees
+}
+
+// MilestoneTitle returns the title of the phase's milestone, for a hire.
+func (phase *PhaseEntry) MilestoneTitle(username string) string {
+	switch {
+	case len(phase.Title) > 0:
+		return expandOwners(phase.Title, username, "")
+	case len(phase.Name) > 0:
+		return fmt.Sprintf("%s: %s", phase.Name, welcomeTitle(username))
+	}
+	return welcomeTitle(username)
+}
+
+// MilestoneDescription returns the description of the phase's milestone, for a hire; it introduces the hire's buddy
+// (if any), unless the description already mentions them.
+func (phase *PhaseEntry) MilestoneDescription(username string, buddy string) string {
+	description := welcomeDescription(username)
+	if len(phase.Description) > 0 {
+		description = expandOwners(phase.Description, username, buddy)
+	}
+	if (len(buddy) > 0) && !strings.Contains(description, "@"+buddy) {
+		description = fmt.Sprintf("%s\n\nYour buddy is @%s.", strings.TrimRight(description, "\n"), buddy)
+	}
+	return description
+}
+
+// IssueLabels lists the names of the labels applied to a task's issue: the scheme's labels, then the task's own.
+func (setup *SetupScheme) IssueLabels(task *TaskEntry) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, label := range append(append([]LabelEntry{}, setup.Labels...), task.Labels...) {
+		key := strings.ToLower(label.Name)
+		if !seen[key] {
+			seen[key] = true
+			names = append(names, label.Name)
+		}
+	}
+	return names
+}
+
+// LabelsForTasks collects the declarations of every label applied to the issues of the given tasks.
+// Label names are matched without regard to case, as GitHub does; where a label is declared more than once,
+// the first color and description given are used.
+func (setup *SetupScheme) LabelsForTasks(tasks []TaskEntry) []LabelEntry {
+	var labels []LabelEntry
+	index := make(map[string]int)
+
+	declared := append([]LabelEntry{}, setup.Labels...)
+	for _, task := range tasks {
+		declared = append(declared, task.Labels...)
+	}
+
+	for _, label := range declared {
+		key := strings.ToLower(label.Name)
+		position, ok := index[key]
+		if !ok {
+			index[key] = len(labels)
+			labels = append(labels, label)
+			continue
+		}
+		if len(labels[position].Color) == 0 {
+			labels[position].Color = label.Color
+		}
+		if len(labels[position].Description) == 0 {
+			labels[position].Description = label.Description
+		}
+	}
+
+	return labels
+}
+
+// RoleNames lists the identifiers of the roles declared in the scheme, in sorted order.
+func (setup *SetupScheme) RoleNames() []string {
+	names := make([]string, 0, len(setup.Roles))
+	for name := range setup.Roles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// TasksForRole returns the shared tasks, with the additions and removals of the named role applied.
+// An empty role selects only the shared tasks.
+func (setup *SetupScheme) TasksForRole(role string) ([]TaskEntry, error) {
+	if len(role) == 0 {
+		return orderTasks(setup.Tasks)
+	}
+
+	entry, ok := setup.Roles[role]
+	if !ok {
+		return nil, fmt.Errorf("Unknown role '%s'", role)
+	}
+
+	removed := make(map[string]bool)
+	for _, title := range entry.Remove {
+		removed[title] = true
+	}
+
+	overrides := make(map[string]TaskEntry)
+	for _, task := range entry.Tasks {
+		overrides[task.Title] = task
+	}
+
+	var tasks []TaskEntry
+	for _, task := range setup.Tasks {
+		if removed[task.Title] {
+			delete(removed, task.Title)
+			continue
+		}
+		if override, ok := overrides[task.Title]; ok {
+			task = override
+			delete(overrides, task.Title)
+		}
+		tasks = append(tasks, task)
+	}
+
+	for _, title := range entry.Remove {
+		if removed[title] {
+			return nil, fmt.Errorf("Role '%s' removes unknown task '%s'", role, title)
+		}
+	}
+
+	for _, task := range entry.Tasks {
+		if _, ok := overrides[task.Title]; ok {
+			tasks = append(tasks, task)
+		}
+	}
+
+	return orderTasks(tasks)
+}
+
+// orderTasks sorts tasks so that each follows its prerequisites, otherwise keeping their declared order.
+// Prerequisites which are not among the tasks (e.g. removed by a role) are ignored.
+func orderTasks(tasks []TaskEntry) ([]TaskEntry, error) {
+	present := make(map[string]bool)
+	for _, task := range tasks {
+		present[task.Title] = true
+	}
+
+	placed := make(map[string]bool)
+	placedIndex := make([]bool, len(tasks))
+	ordered := make([]TaskEntry, 0, len(tasks))
+
+	for len(ordered) < len(tasks) {
+		// Always place the earliest declared task which is ready.
+		progress := false
+		for index, task := range tasks {
+			if placedIndex[index] {
+				continue
+			}
+			ready := true
+			for _, title := range task.DependsOn {
+				if present[title] && !placed[title] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				placed[task.Title] = true
+				placedIndex[index] = true
+				ordered = append(ordered, task)
+				progress = true
+				break
+			}
+		}
+
+		if !progress {
+			var blocked []string
+			for index, task := range tasks {
+				if !placedIndex[index] {
+					blocked = append(blocked, task.Title)
+				}
+			}
+			return nil, fmt.Errorf("Task dependencies form a cycle among: %s", strings.Join(blocked, ", "))
+		}
+	}
+
+	return ordered, nil
+}
+
+// validateDependencies ensures every prerequisite names a declared task, and that no task list
+// (shared, or for any role) contains a dependency cycle.
+func (setup *SetupScheme) validateDependencies() error {
+	declared := make(map[string]bool)
+	allTasks := setup.Tasks
+	for _, name := range setup.RoleNames() {
+		allTasks = append(allTasks, setup.Roles[name].Tasks...)
+	}
+	for _, task := range allTasks {
+		declared[task.Title] = true
+	}
+
+	for _, task := range allTasks {
+		for _, title := range task.DependsOn {
+			if !declared[title] {
+				return fmt.Errorf("Task '%s' depends on unknown task '%s'", task.Title, title)
+			}
+		}
+	}
+
+	for _, role := range append([]string{""}, setup.RoleNames()...) {
+		if _, err := setup.TasksForRole(role); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateLabels ensures every label has a name, and every declared color is a hexadecimal RGB value.
+func (setup *SetupScheme) validateLabels() error {
+	declared := append([]LabelEntry{}, setup.Labels...)
+	for _, task := range setup.Tasks {
+		declared = append(declared, task.Labels...)
+	}
+	for _, name := range setup.RoleNames() {
+		for _, task := range setup.Roles[name].Tasks {
+			declared = append(declared, task.Labels...)
+		}
+	}
+
+	for _, label := range declared {
+		if len(strings.TrimSpace(label.Name)) == 0 {
+			return fmt.Errorf("Labels must have a name")
+		}
+		if (len(label.Color) > 0) && !labelColorPattern.MatchString(label.Color) {
+			return fmt.Errorf("Label '%s' has invalid color '%s'; expected 6 hexadecimal digits", label.Name, label.Color)
+		}
+	}
+
+	return nil
+}
+
+// validateColumns ensures the board's columns have distinct names and known presets,
+// and that every task starts in one of them.
+func (setup *SetupScheme) validateColumns() error {
+	declared := make(map[string]bool)
+	for _, column := range setup.Columns {
+		if len(strings.TrimSpace(column.Name)) == 0 {
+			return fmt.Errorf("Columns must have a name")
+		}
+		if declared[column.Name] {
+			return fmt.Errorf("Column '%s' is declared more than once", column.Name)
+		}
+		declared[column.Name] = true
+
+		switch column.Preset {
+		case "", PresetTodo, PresetInProgress, PresetDone:
+		default:
+			return fmt.Errorf("Column '%s' has unknown preset '%s'; expected %s, %s or %s", column.Name, column.Preset, PresetTodo, PresetInProgress, PresetDone)
+		}
+	}
+
+	names := make(map[string]bool)
+	for _, name := range setup.ColumnNames() {
+		names[name] = true
+	}
+
+	allTasks := setup.Tasks
+	for _, name := range setup.RoleNames() {
+		allTasks = append(allTasks, setup.Roles[name].Tasks...)
+	}
+	for _, task := range allTasks {
+		if (len(task.Column) > 0) && !names[task.Column] {
+			return fmt.Errorf("Task '%s' starts in unknown column '%s'", task.Title, task.Column)
+		}
+	}
+
+	return nil
+}
+
+// validateSchedule ensures the business calendar can be prepared, and that no task is due after the onboarding.
+func (setup *SetupScheme) validateSchedule() error {
+	if _, err := NewBusinessCalendar(&setup.Schedule, setup.baseDir); err != nil {
 		return err
 	}
 
-	if err = tpl.Execute(&rendered, context); err != nil {
+	if setup.Schedule.BusinessDays < 0 {
+		return fmt.Errorf("The schedule's business_days must not be negative")
+	}
+
+	allTasks := setup.Tasks
+	for _, name := range setup.RoleNames() {
+		allTasks = append(allTasks, setup.Roles[name].Tasks...)
+	}
+	for _, task := range allTasks {
+		if task.Due < 0 {
+			return fmt.Errorf("Task '%s' has a negative due offset", task.Title)
+		}
+		if (setup.Schedule.BusinessDays > 0) && (task.Due > setup.Schedule.BusinessDays) {
+			return fmt.Errorf("Task '%s' is due after the onboarding's %d business days", task.Title, setup.Schedule.BusinessDays)
+		}
+	}
+
+	return nil
+}
+
+// validatePhases ensures the phases have distinct names and milestone titles, and due dates within the onboarding,
+// and that every task belongs to a declared phase, and isn't due after it.
+func (setup *SetupScheme) validatePhases() error {
+	declared := make(map[string]PhaseEntry)
+	titles := make(map[string]bool)
+	for _, phase := range setup.Phases {
+		if len(strings.TrimSpace(phase.Name)) == 0 {
+			return fmt.Errorf("Phases must have a name")
+		}
+		if _, ok := declared[phase.Name]; ok {
+			return fmt.Errorf("Phase '%s' is declared more than once", phase.Name)
+		}
+		declared[phase.Name] = phase
+
+		title := phase.MilestoneTitle("username")
+		if titles[title] {
+			return fmt.Errorf("Phase '%s' has the milestone title of another phase", phase.Name)
+		}
+		titles[title] = true
+
+		if (phase.Due < 0) || ((setup.Schedule.BusinessDays > 0) && (phase.Due > setup.Schedule.BusinessDays)) {
+			return fmt.Errorf("Phase '%s' is due outside of the onboarding's business days", phase.Name)
+		}
+	}
+
+	allTasks := setup.Tasks
+	for _, name := range setup.RoleNames() {
+		allTasks = append(allTasks, setup.Roles[name].Tasks...)
+	}
+	for _, task := range allTasks {
+		if _, ok := declared[task.Phase]; (len(task.Phase) > 0) && !ok {
+			return fmt.Errorf("Task '%s' belongs to unknown phase '%s'", task.Title, task.Phase)
+		}
+		if phase := setup.TaskPhase(&task); (phase.Due > 0) && (task.Due > phase.Due) {
+			return fmt.Errorf("Task '%s' is due after its phase '%s'", task.Title, phase.Name)
+		}
+	}
+
+	return nil
+}
+
+// resolveOwners replaces the references to task owners, in the shared and the roles' tasks, by the owners' usernames.
+func (setup *SetupScheme) resolveOwners() error {
+	resolve := func(tasks []TaskEntry) error {
+		for index := range tasks {
+			task := &tasks[index]
+			assignees := []*indirectAssignee{&task.Assignee}
+			for owner := range task.Owners {
+				assignees = append(assignees, &task.Owners[owner])
+			}
+			for _, assignee := range assignees {
+				if len(assignee.owner) == 0 {
+					continue
+				}
+				owner, ok := setup.TaskOwners[assignee.owner]
+				if !ok {
+					return fmt.Errorf("Task '%s' refers to unknown task owner '%s'", task.Title, assignee.owner)
+				}
+				*assignee = owner
+			}
+		}
+		return nil
+	}
+
+	if err := resolve(setup.Tasks); err != nil {
 		return err
 	}
+	for _, name := range setup.RoleNames() {
+		if err := resolve(setup.Roles[name].Tasks); err != nil {
+			return fmt.Errorf("In role '%s': %v", name, err)
+		}
+	}
+	return nil
+}
 
-	if err = yaml.Unmarshal(rendered.Bytes(), &setup); err != nil {
-		log.Fatal(err)
+// ingest loads and validates the scheme of a file, with the files it includes. Problems are reported as ConfigErrors:
+// all of those found while parsing, or else while linting, or else the first found by the other validations.
+func (setup *SetupScheme) ingest(data []byte, environ *map[string]string) error {
+	return setup.compose(newSchemeLoader(environ, ""), data)
+}
+
+func (setup *SetupScheme) compose(loader *schemeLoader, data []byte) error {
+	errs := loader.add("", setup.baseDir, data)
+	setup.Version = schemeVersion(loader.contents.Bytes())
+	setup.sources = loader.sources
+	if len(errs) > 0 {
+		return errs.sorted()
 	}
 
-	return err
+	for _, file := range loader.files {
+		setup.merge(file.setup)
+	}
+
+	// The holiday calendar is watched along with the scheme's files, and tells its versions apart too.
+	if filename := setup.Schedule.calendarFile(setup.baseDir); len(filename) > 0 {
+		setup.sources = append(setup.sources, filename)
+		if data, err := ioutil.ReadFile(filename); err == nil {
+			setup.Version = schemeVersion(append(append([]byte{}, loader.contents.Bytes()...), data...))
+		}
+	}
+
+	for _, file := range loader.files {
+		errs = append(errs, file.setup.lint(file.lines, setup.TaskOwners).inFile(file.filename)...)
+	}
+	if len(errs) > 0 {
+		return errs.sorted()
+	}
+
+	lines := loader.files[len(loader.files)-1].lines // the scheme's own file
+	validations := []struct {
+		field    string
+		validate func() error
+	}{
+		{"task_owners", setup.resolveOwners},
+		{"labels", setup.validateLabels},
+		{"columns", setup.validateColumns},
+		{"schedule", setup.validateSchedule},
+		{"phases", setup.validatePhases},
+		{"buddies", setup.validateBuddies},
+		{"tasks", setup.validateDependencies},
+	}
+	for _, validation := range validations {
+		if err := validation.validate(); err != nil {
+			return ConfigErrors{{Line: lines.find(validation.field), Field: validation.field, Message: err.Error()}}
+		}
+	}
+
+	return nil
 }
 
 func (setup *SetupScheme) load(filename string, environ *map[string]string) error {
+	loader := newSchemeLoader(environ, filename)
+	setup.sources = loader.sources
+
 	data, err := ioutil.ReadFile(filename)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
-	return setup.ingest(data, environ)
+	setup.baseDir = filepath.Dir(filename)
+	err = setup.compose(loader, data)
+	if errs, ok := err.(ConfigErrors); ok {
+		return errs.inFile(filename)
+	}
+	return err
 }
 
 // NewSetupScheme constructs a SetupScheme instance, the combined effect of a template file and environment variables.
 func NewSetupScheme(filename string, environ *map[string]string) (*SetupScheme, error) {
 	setup := SetupScheme{}
-	setup.load(filename, environ)
+	if err := setup.load(filename, environ); err != nil {
+		return nil, err
+	}
 
 	return &setup, nil
 }
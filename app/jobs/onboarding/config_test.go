@@ -1,6 +1,7 @@
 package onboarding
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -63,3 +64,418 @@ func TestConfigYAMLParser(t *testing.T) {
 	}
 
 }
+
+const testRolesYamlDataFixture = `
+githubOrganization: testOrg
+githubRepository: testRepo
+task_owners:
+    testOwner: &owner
+        github_username: testUsername
+tasks:
+    - title: shared task one
+      assignee: *owner
+    - title: shared task two
+      assignee: *owner
+      description: generic description
+roles:
+    backend:
+        name: Backend Engineer
+        tasks:
+            - title: backend task
+              assignee: *owner
+            - title: shared task two
+              assignee: *owner
+              description: backend description
+    pm:
+        name: Product Manager
+        remove:
+            - shared task one
+`
+
+func TestConfigRoles(t *testing.T) {
+	scheme := SetupScheme{}
+	err := scheme.ingest([]byte(testRolesYamlDataFixture), &map[string]string{})
+
+	if err != nil {
+		t.Fatalf("Loading sample YAML failed with error: %v", err)
+	}
+
+	names := scheme.RoleNames()
+	assertEqual(t, len(names), 2, "Roles counted actual: %d, expected: %d")
+	assertEqual(t, names[0], "backend", "First role actual: %v, expected: %v")
+	assertEqual(t, scheme.Roles["backend"].Name, "Backend Engineer", "Role name actual: %v, expected: %v")
+
+	testCases := []struct {
+		role   string
+		titles []string
+	}{
+		{"", []string{"shared task one", "shared task two"}},
+		{"backend", []string{"shared task one", "shared task two", "backend task"}},
+		{"pm", []string{"shared task two"}},
+	}
+
+	for _, thisCase := range testCases {
+		tasks, err := scheme.TasksForRole(thisCase.role)
+		if err != nil {
+			t.Errorf("TasksForRole(%s) produced an error?! %v", thisCase.role, err)
+			continue
+		}
+		if len(tasks) != len(thisCase.titles) {
+			t.Errorf("TasksForRole(%s) counted actual: %d, expected: %d", thisCase.role, len(tasks), len(thisCase.titles))
+			continue
+		}
+		for index, title := range thisCase.titles {
+			assertEqual(t, tasks[index].Title, title, "Role task title actual: %v, expected: %v")
+		}
+	}
+
+	tasks, _ := scheme.TasksForRole("backend")
+	assertEqual(t, tasks[1].Description, "backend description", "Role override description actual: %v, expected: %v")
+
+	broken := SetupScheme{}
+	brokenYaml := testRolesYamlDataFixture + `
+    broken:
+        remove:
+            - no such task
+`
+	if err = broken.ingest([]byte(brokenYaml), &map[string]string{}); err == nil {
+		t.Errorf("Expected an error removing an unknown task")
+	}
+
+	if _, err = scheme.TasksForRole("nonexistent"); err == nil {
+		t.Errorf("Expected an error selecting an unknown role")
+	}
+}
+
+func TestConfigDependencies(t *testing.T) {
+	testCases := []struct {
+		yaml   string
+		titles []string
+		valid  bool
+	}{
+		{`
+tasks:
+    - title: three
+      depends_on: [two]
+    - title: one
+    - title: two
+      depends_on: [one]
+    - title: four
+`, []string{"one", "two", "three", "four"}, true},
+		{`
+tasks:
+    - title: one
+      depends_on: [two]
+    - title: two
+      depends_on: [one]
+`, nil, false},
+		{`
+tasks:
+    - title: one
+      depends_on: [missing]
+`, nil, false},
+		{`
+tasks:
+    - title: one
+    - title: two
+roles:
+    cyclic:
+        tasks:
+            - title: one
+              depends_on: [two]
+            - title: two
+              depends_on: [one]
+`, nil, false},
+	}
+
+	for index, thisCase := range testCases {
+		scheme := SetupScheme{}
+		err := scheme.ingest([]byte(thisCase.yaml), &map[string]string{})
+
+		if !thisCase.valid {
+			if err == nil {
+				t.Errorf("Case %d: expected a dependency error", index)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("Case %d: loading failed with error: %v", index, err)
+			continue
+		}
+
+		tasks, _ := scheme.TasksForRole("")
+		for position, title := range thisCase.titles {
+			assertEqual(t, tasks[position].Title, title, "Ordered task title actual: %v, expected: %v")
+		}
+	}
+}
+
+func TestConfigLabels(t *testing.T) {
+	scheme := SetupScheme{}
+	err := scheme.ingest([]byte(`
+labels:
+    - name: onboarding
+      color: 0E8A16
+      description: Generated by the onboarding tool
+tasks:
+    - title: one
+      labels: [docs, Onboarding]
+    - title: two
+      labels:
+        - name: docs
+          color: c5def5
+`), &map[string]string{})
+
+	if err != nil {
+		t.Fatalf("Loading labels failed with error: %v", err)
+	}
+
+	names := scheme.IssueLabels(&scheme.Tasks[0])
+	assertEqual(t, len(names), 2, "Labels of the first issue, actual %d, expected %d")
+	assertEqual(t, names[0], "onboarding", "First issue label, actual %v, expected %v")
+	assertEqual(t, names[1], "docs", "Second issue label, actual %v, expected %v")
+
+	labels := scheme.LabelsForTasks(scheme.Tasks)
+	assertEqual(t, len(labels), 2, "Labels declared, actual %d, expected %d")
+	assertEqual(t, labels[0].Color, "0E8A16", "Scheme label color, actual %v, expected %v")
+	assertEqual(t, labels[1].Name, "docs", "Task label name, actual %v, expected %v")
+	assertEqual(t, labels[1].Color, "c5def5", "Task label color from a later declaration, actual %v, expected %v")
+
+	invalid := []string{`
+labels:
+    - name: onboarding
+      color: "#0E8A16"
+`, `
+tasks:
+    - title: one
+      labels:
+        - color: 0E8A16
+`}
+
+	for index, yaml := range invalid {
+		scheme := SetupScheme{}
+		if err := scheme.ingest([]byte(yaml), &map[string]string{}); err == nil {
+			t.Errorf("Case %d: expected a label error", index)
+		}
+	}
+}
+
+func TestConfigColumns(t *testing.T) {
+	scheme := SetupScheme{}
+	if err := scheme.ingest([]byte(`
+tasks:
+    - title: one
+`), &map[string]string{}); err != nil {
+		t.Fatalf("Loading tasks failed with error: %v", err)
+	}
+	assertEqual(t, len(scheme.Board()), len(defaultBoard), "Default board columns, actual %d, expected %d")
+	assertEqual(t, scheme.StartColumn(&scheme.Tasks[0]), "Backlog", "Default start column, actual %v, expected %v")
+
+	scheme = SetupScheme{}
+	err := scheme.ingest([]byte(`
+columns:
+    - Ideas
+    - name: To Do
+      preset: todo
+    - name: Doing
+      preset: in_progress
+    - name: Done
+      preset: done
+tasks:
+    - title: one
+    - title: two
+      column: Doing
+`), &map[string]string{})
+
+	if err != nil {
+		t.Fatalf("Loading columns failed with error: %v", err)
+	}
+
+	assertEqual(t, len(scheme.ColumnNames()), 4, "Board columns, actual %d, expected %d")
+	assertEqual(t, scheme.ColumnNames()[0], "Ideas", "First column, actual %v, expected %v")
+	assertEqual(t, scheme.Columns[2].Preset, PresetInProgress, "Column preset, actual %v, expected %v")
+	assertEqual(t, scheme.StartColumn(&scheme.Tasks[0]), "To Do", "Start column of the todo preset, actual %v, expected %v")
+	assertEqual(t, scheme.StartColumn(&scheme.Tasks[1]), "Doing", "Start column of a task, actual %v, expected %v")
+
+	invalid := []string{`
+columns: [Backlog, Backlog]
+`, `
+columns:
+    - name: Backlog
+      preset: triage
+`, `
+columns: [Backlog, Done]
+tasks:
+    - title: one
+      column: Review
+`, `
+tasks:
+    - title: one
+      column: Later
+`}
+
+	for index, yaml := range invalid {
+		scheme := SetupScheme{}
+		if err := scheme.ingest([]byte(yaml), &map[string]string{}); err == nil {
+			t.Errorf("Case %d: expected a column error", index)
+		}
+	}
+}
+
+func TestConfigSchedule(t *testing.T) {
+	scheme := SetupScheme{}
+	err := scheme.ingest([]byte(`
+schedule:
+    business_days: 15
+    timezone: UTC
+    holidays:
+        - 2017-07-04
+        - date: "2017-12-25"
+          name: Christmas
+tasks:
+    - title: one
+      due: 3
+`), &map[string]string{})
+
+	if err != nil {
+		t.Fatalf("Loading the schedule failed with error: %v", err)
+	}
+	assertEqual(t, scheme.Schedule.BusinessDays, 15, "Business days, actual %d, expected %d")
+	assertEqual(t, len(scheme.Schedule.Holidays), 2, "Holidays, actual %d, expected %d")
+	assertEqual(t, scheme.Schedule.Holidays[0].Date, "2017-07-04", "Holiday by date alone, actual %v, expected %v")
+	assertEqual(t, scheme.Schedule.Holidays[1].Name, "Christmas", "Holiday name, actual %v, expected %v")
+	assertEqual(t, scheme.Tasks[0].Due, 3, "Task due offset, actual %d, expected %d")
+
+	invalid := []string{`
+schedule:
+    timezone: Nowhere/Special
+`, `
+schedule:
+    holidays: [tomorrow]
+`, `
+schedule:
+    calendar: missing.ics
+`, `
+schedule:
+    business_days: 5
+tasks:
+    - title: one
+      due: 6
+`}
+
+	for index, yaml := range invalid {
+		scheme := SetupScheme{}
+		if err := scheme.ingest([]byte(yaml), &map[string]string{}); err == nil {
+			t.Errorf("Case %d: expected a schedule error", index)
+		}
+	}
+}
+
+func TestConfigPhases(t *testing.T) {
+	scheme := SetupScheme{}
+	err := scheme.ingest([]byte(`
+phases:
+    - name: week 1
+      title: "Week 1 - Welcome @$username!"
+      description: Getting set up.
+      due: 5
+    - name: month 1
+tasks:
+    - title: one
+    - title: two
+      phase: month 1
+`), &map[string]string{})
+
+	if err != nil {
+		t.Fatalf("Loading phases failed with error: %v", err)
+	}
+
+	phases := scheme.PhaseList()
+	assertEqual(t, len(phases), 2, "Phases, actual %d, expected %d")
+	assertEqual(t, phases[0].MilestoneTitle("newhire"), "Week 1 - Welcome @newhire!", "Milestone title, actual %v, expected %v")
+	assertEqual(t, phases[1].MilestoneTitle("newhire"), "month 1: Welcome @newhire!", "Default milestone title, actual %v, expected %v")
+	assertEqual(t, phases[1].MilestoneDescription("newhire", ""), welcomeDescription("newhire"), "Default milestone description, actual %v, expected %v")
+	assertEqual(t, scheme.TaskPhase(&scheme.Tasks[0]).Name, "week 1", "Phase of a task without one, actual %v, expected %v")
+	assertEqual(t, scheme.TaskPhase(&scheme.Tasks[1]).Name, "month 1", "Phase of a task, actual %v, expected %v")
+	assertEqual(t, len(scheme.PhasesForTasks(scheme.Tasks[1:])), 1, "Phases of the second task, actual %d, expected %d")
+
+	unphased := SetupScheme{}
+	assertEqual(t, len(unphased.PhaseList()), 1, "Phases of a scheme without any, actual %d, expected %d")
+	assertEqual(t, unphased.PhaseList()[0].MilestoneTitle("newhire"), welcomeTitle("newhire"), "Milestone title without phases, actual %v, expected %v")
+
+	invalid := []string{`
+phases: [{name: week 1}, {name: week 1}]
+`, `
+phases: [{name: one, title: Welcome}, {name: two, title: Welcome}]
+`, `
+phases: [{name: week 1}]
+tasks:
+    - title: one
+      phase: week 2
+`, `
+phases: [{name: week 1, due: 5}]
+tasks:
+    - title: one
+      due: 6
+`, `
+schedule:
+    business_days: 10
+phases: [{name: month 1, due: 20}]
+`}
+
+	for index, yaml := range invalid {
+		scheme := SetupScheme{}
+		if err := scheme.ingest([]byte(yaml), &map[string]string{}); err == nil {
+			t.Errorf("Case %d: expected a phase error", index)
+		}
+	}
+}
+
+func TestConfigOwners(t *testing.T) {
+	scheme := SetupScheme{}
+	err := scheme.ingest([]byte(`
+task_owners:
+    new_hire:
+        github_username: $username
+    buddy:
+        github_username: Buddy
+tasks:
+    - title: one
+      assignee: new_hire
+      owners: [buddy, {github_username: manager}, {github_username: buddy}]
+roles:
+    sre:
+        tasks:
+            - title: pager
+              owners: [buddy]
+`), &map[string]string{})
+
+	if err != nil {
+		t.Fatalf("Loading owners failed with error: %v", err)
+	}
+
+	assignees := scheme.TaskAssignees(&scheme.Tasks[0], "newhire", "")
+	assertEqual(t, strings.Join(assignees, ","), "newhire,Buddy,manager", "Task assignees, actual %v, expected %v")
+	assertEqual(t, scheme.Roles["sre"].Tasks[0].Owners[0].String(), "Buddy", "Role task owner, actual %v, expected %v")
+	assertEqual(t, len(scheme.TaskAssignees(&TaskEntry{}, "newhire", "")), 0, "Assignees of an unassigned task, actual %d, expected %d")
+
+	invalid := []string{`
+tasks:
+    - title: one
+      assignee: nobody
+`, `
+roles:
+    sre:
+        tasks:
+            - title: pager
+              owners: [nobody]
+`}
+
+	for index, yaml := range invalid {
+		scheme := SetupScheme{}
+		if err := scheme.ingest([]byte(yaml), &map[string]string{}); err == nil {
+			t.Errorf("Case %d: expected an unknown owner error", index)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/go-github/github"
@@ -80,6 +81,9 @@ func prepareGitHubAPIResponse() *github.Response {
 }
 
 func (users *TestUsers) Get(ctx context.Context, username string) (*github.User, *github.Response, error) {
+	if (*users.Cache)["unknownUser"] == username {
+		return nil, nil, fmt.Errorf("GET users/%s: 404 Not Found", username)
+	}
 	realname := "Test User"
 	return &github.User{
 		Login: &username,
@@ -87,8 +91,54 @@ func (users *TestUsers) Get(ctx context.Context, username string) (*github.User,
 	}, nil, nil
 }
 
+// stateMatches filters by state as GitHub does, listing only open items by default.
+func stateMatches(state string, filter string) bool {
+	if len(state) == 0 {
+		state = "open"
+	}
+	switch filter {
+	case "all":
+		return true
+	case "":
+		return state == "open"
+	}
+	return state == filter
+}
+
 func (issues *TestIssues) ListMilestones(ctx context.Context, owner string, repo string, opts *github.MilestoneListOptions) ([]*github.Milestone, *github.Response, error) {
-	return nil, prepareGitHubAPIResponse(), nil
+	var resultMilestones []*github.Milestone
+	milestones, _ := ((*issues.Cache)["milestones"]).([]*github.Milestone)
+	for _, milestone := range milestones {
+		if stateMatches(milestone.GetState(), opts.State) {
+			resultMilestones = append(resultMilestones, milestone)
+		}
+	}
+	return resultMilestones, prepareGitHubAPIResponse(), nil
+}
+
+func (issues *TestIssues) EditMilestone(ctx context.Context, owner string, repo string, number int, milestone *github.Milestone) (*github.Milestone, *github.Response, error) {
+	milestones, _ := ((*issues.Cache)["milestones"]).([]*github.Milestone)
+	for _, existing := range milestones {
+		if existing.GetNumber() == number {
+			if milestone.State != nil {
+				state := milestone.GetState()
+				existing.State = &state
+			}
+			return existing, prepareGitHubAPIResponse(), nil
+		}
+	}
+	return nil, nil, fmt.Errorf("Not Found: milestone %d", number)
+}
+
+func (issues *TestIssues) DeleteMilestone(ctx context.Context, owner string, repo string, number int) (*github.Response, error) {
+	milestones, _ := ((*issues.Cache)["milestones"]).([]*github.Milestone)
+	for index, existing := range milestones {
+		if existing.GetNumber() == number {
+			(*issues.Cache)["milestones"] = append(milestones[:index:index], milestones[index+1:]...)
+			return prepareGitHubAPIResponse(), nil
+		}
+	}
+	return nil, fmt.Errorf("Not Found: milestone %d", number)
 }
 
 func (issues *TestIssues) CreateMilestone(ctx context.Context, owner string, repo string, opts *github.Milestone) (*github.Milestone, *github.Response, error) {
@@ -153,6 +203,10 @@ func (issues *TestIssues) ListByRepo(ctx context.Context, owner string, repo str
 	for _, issue := range resultIssues {
 		matched := false
 
+		if !stateMatches(issue.GetState(), opts.State) {
+			continue
+		}
+
 		if opts.Assignee == "*" {
 			matched = true // initially anyway
 		} else if opts.Assignee == "none" {
@@ -168,11 +222,11 @@ func (issues *TestIssues) ListByRepo(ctx context.Context, owner string, repo str
 
 		if opts.Milestone == "none" {
 			matched = matched && (issue.Milestone == nil)
+		} else if opts.Milestone == "*" {
+			matched = true
 		} else if len(opts.Milestone) > 0 && issue.Milestone != nil {
 			targetNumber, err := strconv.Atoi(opts.Milestone)
 			matched = matched && (issue.Milestone.GetNumber() == targetNumber && err == nil)
-		} else if opts.Milestone == "*" {
-			matched = true
 		}
 
 		if matched {
@@ -185,6 +239,11 @@ func (issues *TestIssues) ListByRepo(ctx context.Context, owner string, repo str
 
 func (issues *TestIssues) Create(ctx context.Context, owner string, repo string, req *github.IssueRequest) (*github.Issue, *github.Response, error) {
 
+	// Tests simulate a failing API by naming the issue whose creation fails.
+	if failing, ok := (*issues.Cache)["failingTitle"].(string); ok && (failing == req.GetTitle()) {
+		return nil, nil, fmt.Errorf("502 Bad Gateway: creating issue %s", failing)
+	}
+
 	resultIssues, ok := (*issues.Cache)["issues"].([]*github.Issue)
 
 	if !ok {
@@ -211,13 +270,32 @@ func (issues *TestIssues) Create(ctx context.Context, owner string, repo string,
 		})
 	}
 
+	issueURL := fmt.Sprintf("https://api.github.com/repos/%s/%s/issues/%d", owner, repo, issueNumber)
+
+	// Copy the request values, as the GitHub API would.
+	title := req.GetTitle()
+	body := req.GetBody()
+
+	labelList := []github.Label{}
+	if req.Labels != nil {
+		for _, name := range *req.Labels {
+			labelName := name
+			labelList = append(labelList, github.Label{Name: &labelName})
+		}
+	}
+
+	state := "open"
+
 	thisIssue := github.Issue{
 		ID:        &issueNumber,
 		Number:    &issueNumber,
-		Title:     req.Title,
-		Body:      req.Body,
+		State:     &state,
+		URL:       &issueURL,
+		Title:     &title,
+		Body:      &body,
 		Assignee:  nil,
 		Assignees: userList,
+		Labels:    labelList,
 		Milestone: &github.Milestone{
 			Number: req.Milestone,
 		},
@@ -235,6 +313,43 @@ func (issues *TestIssues) Create(ctx context.Context, owner string, repo string,
 
 func (issues *TestIssues) Edit(ctx context.Context, owner string, repo string, issueID int, req *github.IssueRequest) (*github.Issue, *github.Response, error) {
 
+	cachedIssues, _ := (*issues.Cache)["issues"].([]*github.Issue)
+	for _, issue := range cachedIssues {
+		if issue.GetNumber() != issueID {
+			continue
+		}
+		if req.Title != nil {
+			title := req.GetTitle()
+			issue.Title = &title
+		}
+		if req.Body != nil {
+			body := req.GetBody()
+			issue.Body = &body
+		}
+		if req.State != nil {
+			state := req.GetState()
+			issue.State = &state
+		}
+		if req.Assignees != nil {
+			issue.Assignees = []*github.User{}
+			for _, name := range req.GetAssignees() {
+				userName := name
+				issue.Assignees = append(issue.Assignees, &github.User{Login: &userName})
+			}
+		}
+		if req.Milestone != nil {
+			issue.Milestone = &github.Milestone{Number: github.Int(req.GetMilestone())}
+		}
+		if req.Labels != nil {
+			issue.Labels = []github.Label{}
+			for _, name := range req.GetLabels() {
+				labelName := name
+				issue.Labels = append(issue.Labels, github.Label{Name: &labelName})
+			}
+		}
+		return issue, nil, nil
+	}
+
 	thisIssue := github.Issue{
 		ID:    &issueID,
 		Title: req.Title,
@@ -248,17 +363,82 @@ func (issues *TestIssues) Edit(ctx context.Context, owner string, repo string, i
 
 }
 
+func (issues *TestIssues) ListLabels(ctx context.Context, owner string, repo string, opts *github.ListOptions) ([]*github.Label, *github.Response, error) {
+	labels, _ := ((*issues.Cache)["labels"]).([]*github.Label)
+	return labels, prepareGitHubAPIResponse(), nil
+}
+
+func (issues *TestIssues) CreateLabel(ctx context.Context, owner string, repo string, label *github.Label) (*github.Label, *github.Response, error) {
+	labels, _ := ((*issues.Cache)["labels"]).([]*github.Label)
+
+	for _, existing := range labels {
+		if strings.EqualFold(existing.GetName(), label.GetName()) {
+			return nil, nil, fmt.Errorf("Validation Failed: label '%s' already_exists", label.GetName())
+		}
+	}
+
+	labelID := len(labels) + 1
+	name := label.GetName()
+	color := label.GetColor()
+	description := label.GetDescription()
+	thisLabel := github.Label{
+		ID:          &labelID,
+		Name:        &name,
+		Color:       &color,
+		Description: &description,
+	}
+
+	// Save to cache
+	(*issues.Cache)["labels"] = append(labels, &thisLabel)
+
+	return &thisLabel, prepareGitHubAPIResponse(), nil
+}
+
+func (issues *TestIssues) EditLabel(ctx context.Context, owner string, repo string, name string, label *github.Label) (*github.Label, *github.Response, error) {
+	labels, _ := ((*issues.Cache)["labels"]).([]*github.Label)
+
+	for _, existing := range labels {
+		if existing.GetName() != name {
+			continue
+		}
+		if label.Name != nil {
+			newName := label.GetName()
+			existing.Name = &newName
+		}
+		if label.Color != nil {
+			color := label.GetColor()
+			existing.Color = &color
+		}
+		if label.Description != nil {
+			description := label.GetDescription()
+			existing.Description = &description
+		}
+		return existing, prepareGitHubAPIResponse(), nil
+	}
+
+	return nil, nil, fmt.Errorf("Not Found: label '%s'", name)
+}
+
 func (repos *TestRepositories) CreateProject(ctx context.Context, owner string, repo string, opts *github.ProjectOptions) (*github.Project, *github.Response, error) {
+	projects, _ := ((*repos.Cache)["projects"]).([]*github.Project)
+
 	projectNumber := 1001
+	projectID := 5001 + len(projects)
+	name := opts.Name
+	body := opts.Body
 	createTimestamp := github.Timestamp{Time: time.Now()}
 	thisProject := github.Project{
-		Name:      &opts.Name,
-		Body:      &opts.Body,
+		ID:        &projectID,
+		Name:      &name,
+		Body:      &body,
 		Number:    &projectNumber,
 		CreatedAt: &createTimestamp,
 		UpdatedAt: &createTimestamp,
 	}
 
+	// Save to cache
+	(*repos.Cache)["projects"] = append(projects, &thisProject)
+
 	return &thisProject, prepareGitHubAPIResponse(), nil
 }
 
@@ -278,6 +458,9 @@ func (repos *TestRepositories) ListProjects(ctx context.Context, owner string, r
 		}
 	}
 
+	created, _ := ((*repos.Cache)["projects"]).([]*github.Project)
+	resultProjects = append(resultProjects, created...)
+
 	return resultProjects, prepareGitHubAPIResponse(), nil
 
 }
@@ -311,6 +494,18 @@ func (proj *TestProjects) UpdateProject(ctx context.Context, projectID int, opts
 	return &thisProject, prepareGitHubAPIResponse(), nil
 }
 
+func (proj *TestProjects) DeleteProject(ctx context.Context, projectID int) (*github.Response, error) {
+	projects, _ := ((*proj.Cache)["projects"]).([]*github.Project)
+	for index, existing := range projects {
+		if existing.GetID() == projectID {
+			(*proj.Cache)["projects"] = append(projects[:index:index], projects[index+1:]...)
+			delete(*proj.Cache, fmt.Sprintf("project/%d/columns", projectID))
+			return prepareGitHubAPIResponse(), nil
+		}
+	}
+	return nil, fmt.Errorf("Not Found: project %d", projectID)
+}
+
 func (proj *TestProjects) CreateProjectColumn(ctx context.Context, projectID int, opts *github.ProjectColumnOptions) (*github.ProjectColumn, *github.Response, error) {
 
 	cache := *proj.Cache
@@ -370,13 +565,29 @@ func (proj *TestProjects) CreateProjectCard(ctx context.Context, columnID int, o
 		ptrCache = ((*proj.Cache)[cacheKey]).([]*github.ProjectCard)
 	}
 
-	count := len(ptrCache) + 1
+	// Card IDs are unique across columns, as on GitHub.
+	count, _ := ((*proj.Cache)["cardCounter"]).(int)
+	count++
+	(*proj.Cache)["cardCounter"] = count
 
 	card := github.ProjectCard{
 		ID:        &count,
 		CreatedAt: &github.Timestamp{Time: time.Now()},
 	}
 
+	// Cards refer to their issue by URL.
+	cachedIssues, _ := ((*proj.Cache)["issues"]).([]*github.Issue)
+	for _, issue := range cachedIssues {
+		if issue.GetID() == opt.ContentID {
+			card.ContentURL = issue.URL
+		}
+	}
+
+	// GitHub rejects a second card for the same issue.
+	if existing, _ := proj.findCard(card.GetContentURL()); existing != nil {
+		return nil, nil, fmt.Errorf("422 Validation Failed: Project already has the associated issue")
+	}
+
 	// Save to cache
 	ptrCache = append(ptrCache, &card)
 	(*proj.Cache)[cacheKey] = ptrCache
@@ -394,3 +605,58 @@ func (proj *TestProjects) ListProjectCards(ctx context.Context, columnID int, op
 
 	return ptrCache, prepareGitHubAPIResponse(), nil
 }
+
+func (proj *TestProjects) DeleteProjectCard(ctx context.Context, cardID int) (*github.Response, error) {
+	for key, value := range *proj.Cache {
+		cards, ok := value.([]*github.ProjectCard)
+		if !ok || !strings.HasPrefix(key, "cards/column/") {
+			continue
+		}
+		for index, card := range cards {
+			if card.GetID() == cardID {
+				(*proj.Cache)[key] = append(cards[:index:index], cards[index+1:]...)
+				return prepareGitHubAPIResponse(), nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("Not Found: card %d", cardID)
+}
+
+// findCard scans the cards of every column for one referring to the given issue URL, returning it and its cache key.
+func (proj *TestProjects) findCard(contentURL string) (*github.ProjectCard, string) {
+	if len(contentURL) == 0 {
+		return nil, ""
+	}
+	for key, value := range *proj.Cache {
+		cards, ok := value.([]*github.ProjectCard)
+		if !ok || !strings.HasPrefix(key, "cards/column/") {
+			continue
+		}
+		for _, card := range cards {
+			if card.GetContentURL() == contentURL {
+				return card, key
+			}
+		}
+	}
+	return nil, ""
+}
+
+func (proj *TestProjects) MoveProjectCard(ctx context.Context, cardID int, opt *github.ProjectCardMoveOptions) (*github.Response, error) {
+	for key, value := range *proj.Cache {
+		cards, ok := value.([]*github.ProjectCard)
+		if !ok || !strings.HasPrefix(key, "cards/column/") {
+			continue
+		}
+		for index, card := range cards {
+			if card.GetID() != cardID {
+				continue
+			}
+			(*proj.Cache)[key] = append(cards[:index:index], cards[index+1:]...)
+			targetKey := fmt.Sprintf("cards/column/%d", opt.ColumnID)
+			targetCards, _ := ((*proj.Cache)[targetKey]).([]*github.ProjectCard)
+			(*proj.Cache)[targetKey] = append([]*github.ProjectCard{card}, targetCards...)
+			return prepareGitHubAPIResponse(), nil
+		}
+	}
+	return nil, fmt.Errorf("Not Found: card %d", cardID)
+}
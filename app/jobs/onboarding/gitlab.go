@@ -0,0 +1,905 @@
+/*
+This module implements IRepositoryAccess and iClientAccess over the GitLab REST API (v4), for self-hosted GitLab instances.
+
+GitHub's models are kept as the common representation, mapped as follows:
+  - milestones are GitLab milestones, numbered by their (instance-wide) ID, as issues refer to them by ID;
+  - projects are issue boards, and their columns are the board's lists, each of which shows the issues with its label;
+  - issues are GitLab issues, numbered by their IID; an issue's "card" on a board is the label of one of its lists.
+*/
+
+package onboarding
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+type (
+	// GitLabClient provides access to projects (i.e. repositories) of a GitLab instance.
+	GitLabClient struct {
+		Context    context.Context
+		HTTPClient *http.Client
+		BaseURL    *url.URL // the instance's root, e.g. https://gitlab.example.com/
+	}
+
+	// GitLabRepository provides derived access to project-scoped resources, e.g. issues, boards, etc.
+	GitLabRepository struct {
+		client    *GitLabClient
+		projectID int
+		userIDs   map[string]int // resolved assignees
+		*github.Repository
+	}
+
+	gitlabUser struct {
+		ID       int    `json:"id"`
+		Username string `json:"username"`
+	}
+
+	gitlabProject struct {
+		ID                int    `json:"id"`
+		Path              string `json:"path"`
+		PathWithNamespace string `json:"path_with_namespace"`
+		WebURL            string `json:"web_url"`
+		Namespace         struct {
+			FullPath string `json:"full_path"`
+		} `json:"namespace"`
+	}
+
+	gitlabMilestone struct {
+		ID          int    `json:"id"`
+		Title       string `json:"title,omitempty"`
+		Description string `json:"description,omitempty"`
+		DueDate     string `json:"due_date,omitempty"` // YYYY-MM-DD
+		State       string `json:"state,omitempty"`
+		WebURL      string `json:"web_url,omitempty"`
+		StateEvent  string `json:"state_event,omitempty"` // "close" or "activate", in updates
+	}
+
+	gitlabIssue struct {
+		ID          int              `json:"id"`
+		IID         int              `json:"iid"`
+		Title       string           `json:"title"`
+		Description string           `json:"description"`
+		State       string           `json:"state"`
+		WebURL      string           `json:"web_url"`
+		Labels      []string         `json:"labels"`
+		Milestone   *gitlabMilestone `json:"milestone"`
+		Assignees   []gitlabUser     `json:"assignees"`
+	}
+
+	gitlabIssueOptions struct {
+		Title        *string `json:"title,omitempty"`
+		Description  *string `json:"description,omitempty"`
+		AssigneeIDs  *[]int  `json:"assignee_ids,omitempty"`
+		MilestoneID  *int    `json:"milestone_id,omitempty"`
+		Labels       *string `json:"labels,omitempty"`        // comma-separated
+		AddLabels    *string `json:"add_labels,omitempty"`    // comma-separated, added to the issue's
+		RemoveLabels *string `json:"remove_labels,omitempty"` // comma-separated, taken off the issue
+		StateEvent   *string `json:"state_event,omitempty"`   // "close" or "reopen"
+	}
+
+	gitlabLabel struct {
+		ID          int    `json:"id,omitempty"`
+		Name        string `json:"name"`
+		Color       string `json:"color,omitempty"` // with a leading '#'
+		Description string `json:"description,omitempty"`
+	}
+
+	gitlabBoard struct {
+		ID    int          `json:"id"`
+		Name  string       `json:"name"`
+		Lists []gitlabList `json:"lists"`
+	}
+
+	gitlabList struct {
+		ID       int         `json:"id"`
+		Label    gitlabLabel `json:"label"`
+		Position int         `json:"position"`
+	}
+)
+
+// NewGitLabClient prepares access to the GitLab instance at baseURL, via an (OAuth2 authenticated) HTTP client.
+func NewGitLabClient(ctx context.Context, httpClient *http.Client, baseURL string) (*GitLabClient, error) {
+	if len(baseURL) == 0 {
+		return nil, errors.New("A GitLab URL is required")
+	}
+
+	base, err := url.Parse(strings.TrimRight(baseURL, "/") + "/")
+	if err != nil {
+		return nil, fmt.Errorf("Invalid GitLab URL '%s': %v", baseURL, err)
+	}
+
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	return &GitLabClient{Context: ctx, HTTPClient: httpClient, BaseURL: base}, nil
+}
+
+// do sends a request to the API, encoding body (if any) as JSON, and decoding the response into result (if any).
+// The next page of a listing is returned, or 0 when there are no more.
+func (client *GitLabClient) do(method string, path string, query url.Values, body interface{}, result interface{}) (int, error) {
+	endpoint, err := client.BaseURL.Parse("api/v4/" + path)
+	if err != nil {
+		return 0, err
+	}
+	if query != nil {
+		endpoint.RawQuery = query.Encode()
+	}
+
+	var payload bytes.Buffer
+	if body != nil {
+		if err = json.NewEncoder(&payload).Encode(body); err != nil {
+			return 0, err
+		}
+	}
+
+	request, err := http.NewRequest(method, endpoint.String(), &payload)
+	if err != nil {
+		return 0, err
+	}
+	request = request.WithContext(client.Context)
+	request.Header.Set("Accept", "application/json")
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+
+	response, err := client.HTTPClient.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+
+	data, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return 0, err
+	}
+
+	if (response.StatusCode < 200) || (response.StatusCode > 299) {
+		return 0, fmt.Errorf("GitLab %s %s: %s %s", method, path, response.Status, strings.TrimSpace(string(data)))
+	}
+
+	if (result != nil) && (len(data) > 0) {
+		if err = json.Unmarshal(data, result); err != nil {
+			return 0, fmt.Errorf("GitLab %s %s: %v", method, path, err)
+		}
+	}
+
+	nextPage, _ := strconv.Atoi(response.Header.Get("X-Next-Page"))
+	return nextPage, nil
+}
+
+// pageQuery selects a page of a listing.
+func pageQuery(query url.Values, page int) url.Values {
+	result := url.Values{}
+	for key, values := range query {
+		result[key] = values
+	}
+	result.Set("page", strconv.Itoa(page))
+	result.Set("per_page", "100")
+	return result
+}
+
+// ProjectsURL locates the issue boards of a GitLab project.
+func (client *GitLabClient) ProjectsURL(owner string, name string) string {
+	return fmt.Sprintf("%s%s/%s/boards", client.BaseURL.String(), owner, name)
+}
+
+// GetRepository returns a GitLabRepository for the project owner/name, where owner is its group (or user) path.
+func (client *GitLabClient) GetRepository(owner string, name string) (IRepositoryAccess, error) {
+	project := gitlabProject{}
+	path := fmt.Sprintf("projects/%s", url.PathEscape(owner+"/"+name))
+	if _, err := client.do("GET", path, nil, nil, &project); err != nil {
+		return nil, fmt.Errorf("Failed GetRepository(): %v", err)
+	}
+
+	repository := github.Repository{
+		ID:       github.Int(project.ID),
+		Name:     github.String(project.Path),
+		FullName: github.String(project.PathWithNamespace),
+		HTMLURL:  github.String(project.WebURL),
+		Owner:    &github.User{Login: github.String(project.Namespace.FullPath)},
+	}
+
+	return &GitLabRepository{
+		client:     client,
+		projectID:  project.ID,
+		userIDs:    make(map[string]int),
+		Repository: &repository,
+	}, nil
+}
+
+func (client *GitLabClient) currentUsername() (string, error) {
+	user := gitlabUser{}
+	if _, err := client.do("GET", "user", nil, nil, &user); err != nil {
+		return "", err
+	}
+	return user.Username, nil
+}
+
+func (client *GitLabClient) findUser(username string) (*gitlabUser, error) {
+	var users []gitlabUser
+	query := url.Values{"username": {username}}
+	if _, err := client.do("GET", "users", query, nil, &users); err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		if strings.EqualFold(user.Username, username) {
+			return &user, nil
+		}
+	}
+	return nil, fmt.Errorf("Unknown GitLab user '%s'", username)
+}
+
+func (client *GitLabClient) resolveUser(username *string) (*github.User, error) {
+	user, err := client.findUser(*username)
+	if err != nil {
+		return nil, err
+	}
+	return &github.User{ID: github.Int(user.ID), Login: github.String(user.Username)}, nil
+}
+
+func (repo *GitLabRepository) path(format string, args ...interface{}) string {
+	return fmt.Sprintf("projects/%d/", repo.projectID) + fmt.Sprintf(format, args...)
+}
+
+func (milestone *gitlabMilestone) toGitHub() *github.Milestone {
+	result := github.Milestone{
+		ID:          github.Int(milestone.ID),
+		Number:      github.Int(milestone.ID),
+		Title:       github.String(milestone.Title),
+		Description: github.String(milestone.Description),
+		State:       github.String(milestone.State),
+		HTMLURL:     github.String(milestone.WebURL),
+	}
+	if dueOn, err := time.Parse("2006-01-02", milestone.DueDate); err == nil {
+		result.DueOn = &dueOn
+	}
+	return &result
+}
+
+func (issue *gitlabIssue) toGitHub() *github.Issue {
+	result := github.Issue{
+		ID:     github.Int(issue.ID),
+		Number: github.Int(issue.IID),
+		Title:  github.String(issue.Title),
+		Body:   github.String(issue.Description),
+		State:  github.String(issue.State),
+		URL:    github.String(issue.WebURL),
+	}
+	if issue.Milestone != nil {
+		result.Milestone = issue.Milestone.toGitHub()
+	}
+	for _, user := range issue.Assignees {
+		result.Assignees = append(result.Assignees, &github.User{ID: github.Int(user.ID), Login: github.String(user.Username)})
+	}
+	for _, name := range issue.Labels {
+		result.Labels = append(result.Labels, github.Label{Name: github.String(name)})
+	}
+	return &result
+}
+
+func (label *gitlabLabel) toGitHub() *github.Label {
+	return &github.Label{
+		ID:          github.Int(label.ID),
+		Name:        github.String(label.Name),
+		Color:       github.String(strings.TrimPrefix(label.Color, "#")),
+		Description: github.String(label.Description),
+	}
+}
+
+func (list *gitlabList) toGitHub() *github.ProjectColumn {
+	return &github.ProjectColumn{
+		ID:   github.Int(list.ID),
+		Name: github.String(list.Label.Name),
+	}
+}
+
+// fetchIssues lists the open issues of the project, or of one of its milestones (when milestone is set), narrowed by
+// the filters of query (e.g. a search, see issueQuery).
+func (repo *GitLabRepository) fetchIssues(milestone int, query url.Values) ([]*gitlabIssue, error) {
+	var resultIssues []*gitlabIssue
+	path := repo.path("issues")
+	if milestone > 0 {
+		path = repo.path("milestones/%d/issues", milestone)
+	}
+	filters := url.Values{"state": {"opened"}}
+	for key, values := range query {
+		filters[key] = values
+	}
+
+	for page := 1; page > 0; {
+		var result []*gitlabIssue
+		next, err := repo.client.do("GET", path, pageQuery(filters, page), nil, &result)
+		if err != nil {
+			return nil, err
+		}
+		for _, issue := range result {
+			if issue.State == "opened" { // the milestone's issues are listed whatever their state
+				resultIssues = append(resultIssues, issue)
+			}
+		}
+		page = next
+	}
+
+	return resultIssues, nil
+}
+
+// issueQuery narrows the listing of issues to those which may match a request, rather than listing them all for
+// each request: GitLab searches their titles (by substring), and filters them by one of the assignees (filtering
+// by several needs a paid tier). GetIssuesByRequest still checks the exact match.
+func issueQuery(request *github.IssueRequest) url.Values {
+	query := url.Values{}
+	if request.Title != nil {
+		query.Set("search", request.GetTitle())
+		query.Set("in", "title")
+	}
+	if assignees := request.GetAssignees(); len(assignees) > 0 {
+		query.Set("assignee_username", assignees[0])
+	}
+	return query
+}
+
+// GetIssuesByRequest fetches open issues, if present, by title, milestone, and assignee usernames; issues must be
+// assigned to every one of the requested assignees.
+func (repo *GitLabRepository) GetIssuesByRequest(request *github.IssueRequest) ([]*github.Issue, error) {
+	var resultIssues []*github.Issue
+
+	issues, err := repo.fetchIssues(request.GetMilestone(), issueQuery(request))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, issue := range issues {
+		if (request.Title != nil) && (request.GetTitle() != issue.Title) {
+			continue
+		}
+		if (request.GetMilestone() > 0) && ((issue.Milestone == nil) || (issue.Milestone.ID != request.GetMilestone())) {
+			continue
+		}
+		if result := issue.toGitHub(); hasAssignees(result, request.GetAssignees()) {
+			resultIssues = append(resultIssues, result)
+		}
+	}
+
+	return resultIssues, nil
+}
+
+func (repo *GitLabRepository) userID(username string) (int, error) {
+	if id, ok := repo.userIDs[username]; ok {
+		return id, nil
+	}
+	user, err := repo.client.findUser(username)
+	if err != nil {
+		return 0, err
+	}
+	repo.userIDs[username] = user.ID
+	return user.ID, nil
+}
+
+// CreateOrUpdateIssue searches existing issues in the project, and returns one matching or creates a new issue.
+// A matching issue is given the labels it lacks.
+func (repo *GitLabRepository) CreateOrUpdateIssue(assignees []string, title *string, body *string, milestone int, labels []string) (*github.Issue, error) {
+	request := newIssueRequest(assignees, title, body, milestone, labels)
+
+	issuesFound, err := repo.GetIssuesByRequest(&request)
+	if err != nil {
+		return nil, err
+	}
+	if len(issuesFound) > 0 {
+		if edit := labelEdit(issuesFound[0], labels); edit != nil {
+			return repo.EditIssue(issuesFound[0], edit)
+		}
+		return issuesFound[0], nil // found a matching issue
+	}
+
+	options := gitlabIssueOptions{Title: title, Description: body}
+	if milestone > 0 {
+		options.MilestoneID = &milestone
+	}
+	if len(assignees) > 0 {
+		ids := []int{}
+		for _, username := range assignees {
+			id, err := repo.userID(username)
+			if err != nil {
+				return nil, err
+			}
+			ids = append(ids, id)
+		}
+		options.AssigneeIDs = &ids
+	}
+	if len(labels) > 0 {
+		joined := strings.Join(labels, ",")
+		options.Labels = &joined
+	}
+
+	issue := gitlabIssue{}
+	if _, err = repo.client.do("POST", repo.path("issues"), nil, &options, &issue); err != nil {
+		return nil, err
+	}
+	return issue.toGitHub(), nil
+}
+
+func (repo *GitLabRepository) fetchMilestones() ([]*gitlabMilestone, error) {
+	var resultMilestones []*gitlabMilestone
+	query := url.Values{"state": {"active"}}
+
+	for page := 1; page > 0; {
+		var result []*gitlabMilestone
+		next, err := repo.client.do("GET", repo.path("milestones"), pageQuery(query, page), nil, &result)
+		if err != nil {
+			return nil, err
+		}
+		resultMilestones = append(resultMilestones, result...)
+		page = next
+	}
+
+	return resultMilestones, nil
+}
+
+// GetMilestoneByTitle retrieves an active milestone by name, returning nil when none matches.
+func (repo *GitLabRepository) GetMilestoneByTitle(title *string) (*github.Milestone, error) {
+	milestones, err := repo.fetchMilestones()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ms := range milestones {
+		if ms.Title == *title {
+			return ms.toGitHub(), nil
+		}
+	}
+	return nil, nil
+}
+
+// CreateOrUpdateMilestone retrieves an existing milestone by name, or creates a new one if needed.
+func (repo *GitLabRepository) CreateOrUpdateMilestone(title *string, description *string, dueDate *time.Time) (*github.Milestone, error) {
+	milestoneFound, err := repo.GetMilestoneByTitle(title)
+	if (err != nil) || (milestoneFound != nil) {
+		return milestoneFound, err
+	}
+
+	options := gitlabMilestone{Title: *title, Description: *description}
+	if dueDate != nil {
+		options.DueDate = dueDate.Format("2006-01-02")
+	}
+
+	milestone := gitlabMilestone{}
+	if _, err = repo.client.do("POST", repo.path("milestones"), nil, &options, &milestone); err != nil {
+		return nil, err
+	}
+	return milestone.toGitHub(), nil
+}
+
+func (repo *GitLabRepository) fetchBoards() ([]*gitlabBoard, error) {
+	var resultBoards []*gitlabBoard
+
+	for page := 1; page > 0; {
+		var result []*gitlabBoard
+		next, err := repo.client.do("GET", repo.path("boards"), pageQuery(nil, page), nil, &result)
+		if err != nil {
+			return nil, err
+		}
+		resultBoards = append(resultBoards, result...)
+		page = next
+	}
+
+	return resultBoards, nil
+}
+
+func (repo *GitLabRepository) boardToGitHub(board *gitlabBoard) *github.Project {
+	return &github.Project{
+		ID:     github.Int(board.ID),
+		Number: github.Int(board.ID),
+		Name:   github.String(board.Name),
+		URL:    github.String(fmt.Sprintf("%s/boards/%d", repo.GetHTMLURL(), board.ID)),
+	}
+}
+
+// GetProjectByTitle retrieves an existing issue board by name, returning nil when none matches.
+// Boards have no description, so the Body of the result is nil.
+func (repo *GitLabRepository) GetProjectByTitle(title *string) (*github.Project, error) {
+	boards, err := repo.fetchBoards()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, board := range boards {
+		if board.Name == *title {
+			return repo.boardToGitHub(board), nil
+		}
+	}
+	return nil, nil
+}
+
+// CreateOrUpdateProject retrieves an existing issue board by name, or creates a new one (with lists for columns) if needed.
+// Lists missing from an existing board are added to its right. Boards have no description, so description is ignored.
+func (repo *GitLabRepository) CreateOrUpdateProject(title *string, description *string, columns []string) (*github.Project, error) {
+	projectFound, err := repo.GetProjectByTitle(title)
+	if err != nil {
+		return nil, err
+	}
+
+	if projectFound != nil {
+		present, err := repo.ColumnsPresent(projectFound, columns)
+		if (err != nil) || present {
+			return projectFound, err
+		}
+
+		existing, err := repo.FetchMappedProjectColumns(projectFound)
+		if err != nil {
+			return nil, err
+		}
+		return projectFound, repo.addLists(projectFound.GetID(), missingColumns(existing, columns))
+	}
+
+	board := gitlabBoard{}
+	if _, err = repo.client.do("POST", repo.path("boards"), nil, &gitlabBoard{Name: *title}, &board); err != nil {
+		return nil, err
+	}
+
+	if err = repo.addLists(board.ID, columns); err != nil {
+		return nil, err
+	}
+
+	return repo.boardToGitHub(&board), nil
+}
+
+// addLists appends a list to a board for each of the named labels, creating the labels as needed.
+func (repo *GitLabRepository) addLists(boardID int, columns []string) error {
+	var labels []LabelEntry
+	for _, name := range columns {
+		if len(name) > 0 {
+			labels = append(labels, LabelEntry{Name: name})
+		}
+	}
+
+	listLabels, err := repo.EnsureLabels(labels)
+	if err != nil {
+		return err
+	}
+
+	for _, label := range listLabels {
+		options := map[string]int{"label_id": label.GetID()}
+		if _, err = repo.client.do("POST", repo.path("boards/%d/lists", boardID), nil, options, nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (repo *GitLabRepository) fetchLists(project *github.Project) ([]*gitlabList, error) {
+	var resultLists []*gitlabList
+
+	for page := 1; page > 0; {
+		var result []*gitlabList
+		next, err := repo.client.do("GET", repo.path("boards/%d/lists", project.GetID()), pageQuery(nil, page), nil, &result)
+		if err != nil {
+			return nil, err
+		}
+		for _, list := range result {
+			// The built-in lists (e.g. Open and Closed) have no label; no issue is placed on them by a label.
+			if len(list.Label.Name) > 0 {
+				resultLists = append(resultLists, list)
+			}
+		}
+		page = next
+	}
+
+	return resultLists, nil
+}
+
+// FetchMappedProjectColumns produces a string-map of the label lists on a board, named by their labels.
+func (repo *GitLabRepository) FetchMappedProjectColumns(project *github.Project) (map[string](*github.ProjectColumn), error) {
+	lists, err := repo.fetchLists(project)
+	if err != nil {
+		return nil, err
+	}
+
+	columnsFoundMap := make(map[string](*github.ProjectColumn))
+	for _, list := range lists {
+		columnsFoundMap[list.Label.Name] = list.toGitHub()
+	}
+	return columnsFoundMap, nil
+}
+
+// ColumnsPresent indicates whether the board has a list for each of the named labels.
+func (repo *GitLabRepository) ColumnsPresent(project *github.Project, columns []string) (bool, error) {
+	found, err := repo.FetchMappedProjectColumns(project)
+	if err != nil {
+		return false, err
+	}
+
+	for _, name := range columns {
+		if _, ok := found[name]; !ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (repo *GitLabRepository) fetchIssue(issue *github.Issue) (*gitlabIssue, error) {
+	result := gitlabIssue{}
+	if _, err := repo.client.do("GET", repo.path("issues/%d", issue.GetNumber()), nil, nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// addListLabel places an issue on a board's list, by adding the list's label to it (and taking it off the list it was
+// on, if any).
+func (repo *GitLabRepository) addListLabel(issue *github.Issue, column *github.ProjectColumn, previous *github.ProjectColumn) (*github.ProjectCard, error) {
+	options := gitlabIssueOptions{AddLabels: github.String(column.GetName())}
+	if previous != nil {
+		options.RemoveLabels = github.String(previous.GetName())
+	}
+
+	updated := gitlabIssue{}
+	if _, err := repo.client.do("PUT", repo.path("issues/%d", issue.GetNumber()), nil, &options, &updated); err != nil {
+		return nil, err
+	}
+
+	return &github.ProjectCard{
+		ID:         github.Int(updated.ID),
+		ContentURL: github.String(updated.WebURL),
+	}, nil
+}
+
+// FetchBoardCards reads the lists of a board, and which of them each open issue is on (the first whose label it has),
+// for CreateCardForIssue.
+func (repo *GitLabRepository) FetchBoardCards(project *github.Project) (*BoardCards, error) {
+	lists, err := repo.fetchLists(project)
+	if err != nil {
+		return nil, err
+	}
+	issues, err := repo.fetchIssues(0, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make([]*github.ProjectColumn, 0, len(lists))
+	for _, list := range lists {
+		columns = append(columns, list.toGitHub())
+	}
+	board := newBoardCards(columns)
+	for index, list := range lists {
+		for _, issue := range issues {
+			for _, name := range issue.Labels {
+				if name == list.Label.Name {
+					card := github.ProjectCard{ID: github.Int(issue.ID), ContentURL: github.String(issue.WebURL)}
+					board.add(issue.WebURL, &card, index)
+				}
+			}
+		}
+	}
+	return board, nil
+}
+
+// CreateCardForIssue idempotently puts an issue on a board's list, whose issues were read into board (which is kept
+// up to date): its label is added when the issue is on none of the board's lists, replaces the label of an earlier
+// list, and is otherwise left alone (e.g. where the hire moved it). The outcome is CardCreated, CardMoved or
+// CardPresent.
+func (repo *GitLabRepository) CreateCardForIssue(board *BoardCards, issue *github.Issue, column *github.ProjectColumn) (*github.ProjectCard, string, error) {
+	card, current, placement := board.Placement(issue, column)
+	if placement == CardPresent {
+		return card, CardPresent, nil
+	}
+
+	card, err := repo.addListLabel(issue, column, current)
+	if err != nil {
+		return nil, "", err
+	}
+	board.place(issue, card, column)
+	return card, placement, nil
+}
+
+// GetCardForIssue finds the first list of a board which shows the issue, i.e. whose label the issue has.
+// The card and its column are nil when the issue is not on the board.
+func (repo *GitLabRepository) GetCardForIssue(project *github.Project, issue *github.Issue) (*github.ProjectCard, *github.ProjectColumn, error) {
+	lists, err := repo.fetchLists(project)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	current, err := repo.fetchIssue(issue)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, list := range lists {
+		for _, name := range current.Labels {
+			if name == list.Label.Name {
+				card := github.ProjectCard{
+					ID:         github.Int(current.ID),
+					ContentURL: github.String(current.WebURL),
+				}
+				return &card, list.toGitHub(), nil
+			}
+		}
+	}
+
+	return nil, nil, nil
+}
+
+func (repo *GitLabRepository) fetchLabels() ([]*gitlabLabel, error) {
+	var resultLabels []*gitlabLabel
+
+	for page := 1; page > 0; {
+		var result []*gitlabLabel
+		next, err := repo.client.do("GET", repo.path("labels"), pageQuery(nil, page), nil, &result)
+		if err != nil {
+			return nil, err
+		}
+		resultLabels = append(resultLabels, result...)
+		page = next
+	}
+
+	return resultLabels, nil
+}
+
+// FetchMappedLabels produces a map of the project's labels, keyed by lower-cased name.
+func (repo *GitLabRepository) FetchMappedLabels() (map[string](*github.Label), error) {
+	labels, err := repo.fetchLabels()
+	if err != nil {
+		return nil, err
+	}
+
+	labelsFoundMap := make(map[string](*github.Label))
+	for _, label := range labels {
+		labelsFoundMap[strings.ToLower(label.Name)] = label.toGitHub()
+	}
+	return labelsFoundMap, nil
+}
+
+// EnsureLabels creates any missing labels in the project, and updates those whose color or description differ.
+func (repo *GitLabRepository) EnsureLabels(labels []LabelEntry) ([]*github.Label, error) {
+	var resultLabels []*github.Label
+
+	existing, err := repo.FetchMappedLabels()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range labels {
+		options := gitlabLabel{Description: entry.Description}
+		if len(entry.Color) > 0 {
+			options.Color = "#" + strings.ToLower(entry.Color)
+		}
+
+		result := gitlabLabel{}
+		found, ok := existing[strings.ToLower(entry.Name)]
+		switch {
+		case !ok:
+			options.Name = entry.Name
+			if len(options.Color) == 0 {
+				options.Color = "#" + defaultLabelColor
+			}
+			_, err = repo.client.do("POST", repo.path("labels"), nil, &options, &result)
+		case labelDiffers(found, entry):
+			options.Name = found.GetName()
+			_, err = repo.client.do("PUT", repo.path("labels"), nil, &options, &result)
+		default:
+			resultLabels = append(resultLabels, found)
+			continue
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to prepare label '%s': %v", entry.Name, err)
+		}
+		resultLabels = append(resultLabels, result.toGitHub())
+	}
+
+	return resultLabels, nil
+}
+
+// CloseIssue marks an issue as closed.
+func (repo *GitLabRepository) CloseIssue(issue *github.Issue) (*github.Issue, error) {
+	options := gitlabIssueOptions{StateEvent: github.String("close")}
+	closed := gitlabIssue{}
+	if _, err := repo.client.do("PUT", repo.path("issues/%d", issue.GetNumber()), nil, &options, &closed); err != nil {
+		return nil, err
+	}
+	return closed.toGitHub(), nil
+}
+
+// EditIssue applies an edit (e.g. from a sync) to an existing issue; of the request, the title, body, assignees,
+// milestone and state are applied.
+func (repo *GitLabRepository) EditIssue(issue *github.Issue, request *github.IssueRequest) (*github.Issue, error) {
+	options := gitlabIssueOptions{Title: request.Title, Description: request.Body, MilestoneID: request.Milestone}
+	if request.Assignees != nil {
+		ids := []int{}
+		for _, username := range request.GetAssignees() {
+			id, err := repo.userID(username)
+			if err != nil {
+				return nil, err
+			}
+			ids = append(ids, id)
+		}
+		options.AssigneeIDs = &ids
+	}
+	if request.Labels != nil {
+		// Added rather than replaced, so as not to take the issue off a board list it was moved to meanwhile.
+		joined := strings.Join(request.GetLabels(), ",")
+		options.AddLabels = &joined
+	}
+	if request.GetState() == "closed" {
+		options.StateEvent = github.String("close")
+	}
+
+	edited := gitlabIssue{}
+	if _, err := repo.client.do("PUT", repo.path("issues/%d", issue.GetNumber()), nil, &options, &edited); err != nil {
+		return nil, err
+	}
+	return edited.toGitHub(), nil
+}
+
+// DeleteCardForIssue takes an issue off a board, by removing the labels of all the board's lists from it.
+// It reports whether the issue was on the board.
+func (repo *GitLabRepository) DeleteCardForIssue(project *github.Project, issue *github.Issue) (bool, error) {
+	lists, err := repo.fetchLists(project)
+	if err != nil {
+		return false, err
+	}
+
+	current, err := repo.fetchIssue(issue)
+	if err != nil {
+		return false, err
+	}
+
+	listLabels := make(map[string]bool)
+	for _, list := range lists {
+		listLabels[list.Label.Name] = true
+	}
+
+	remaining := []string{}
+	for _, name := range current.Labels {
+		if !listLabels[name] {
+			remaining = append(remaining, name)
+		}
+	}
+
+	if len(remaining) == len(current.Labels) {
+		return false, nil // not on the board
+	}
+
+	joined := strings.Join(remaining, ",")
+	options := gitlabIssueOptions{Labels: &joined}
+	if _, err = repo.client.do("PUT", repo.path("issues/%d", issue.GetNumber()), nil, &options, nil); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// DeleteProject removes an issue board; the labels of its lists are kept.
+func (repo *GitLabRepository) DeleteProject(project *github.Project) error {
+	_, err := repo.client.do("DELETE", repo.path("boards/%d", project.GetID()), nil, nil, nil)
+	return err
+}
+
+// CloseMilestone marks a milestone as closed, keeping it (and its title) in the project.
+func (repo *GitLabRepository) CloseMilestone(milestone *github.Milestone) (*github.Milestone, error) {
+	options := gitlabMilestone{StateEvent: "close"}
+	closed := gitlabMilestone{}
+	if _, err := repo.client.do("PUT", repo.path("milestones/%d", milestone.GetID()), nil, &options, &closed); err != nil {
+		return nil, err
+	}
+	return closed.toGitHub(), nil
+}
+
+// DeleteMilestone removes a milestone from the project; its issues are kept.
+func (repo *GitLabRepository) DeleteMilestone(milestone *github.Milestone) error {
+	_, err := repo.client.do("DELETE", repo.path("milestones/%d", milestone.GetID()), nil, nil, nil)
+	return err
+}
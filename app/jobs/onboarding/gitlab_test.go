@@ -0,0 +1,637 @@
+package onboarding
+
+/*
+This module's tests exercise the `gitlab.go` module, against a fake GitLab API served by httptest.
+The fake keeps its state in memory, and implements only the requests made by GitLabRepository.
+*/
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-github/github"
+	"golang.org/x/oauth2"
+)
+
+type fakeGitLab struct {
+	sync.Mutex
+	*httptest.Server
+
+	Users      []gitlabUser
+	Milestones []*gitlabMilestone
+	Issues     []*gitlabIssue
+	Labels     []*gitlabLabel
+	Boards     []*gitlabBoard
+	nextID     int
+
+	// RateLimited project requests are rejected (with "Retry-After: 0") before they are served again.
+	RateLimited int
+	// Listings counts the requests listing every open issue of the project, unfiltered.
+	Listings int
+}
+
+func newFakeGitLab() *fakeGitLab {
+	fake := &fakeGitLab{
+		Users:  []gitlabUser{{ID: 1, Username: "newhire"}, {ID: 2, Username: "test"}},
+		nextID: 100,
+	}
+	fake.Server = httptest.NewServer(http.HandlerFunc(fake.serve))
+	return fake
+}
+
+func (fake *fakeGitLab) id() int {
+	fake.nextID++
+	return fake.nextID
+}
+
+func (fake *fakeGitLab) labelNamed(name string) *gitlabLabel {
+	for _, label := range fake.Labels {
+		if strings.EqualFold(label.Name, name) {
+			return label
+		}
+	}
+	return nil
+}
+
+func (fake *fakeGitLab) issueByIID(iid string) *gitlabIssue {
+	for _, issue := range fake.Issues {
+		if strconv.Itoa(issue.IID) == iid {
+			return issue
+		}
+	}
+	return nil
+}
+
+func (fake *fakeGitLab) applyIssueOptions(issue *gitlabIssue, options *gitlabIssueOptions) {
+	if options.Title != nil {
+		issue.Title = *options.Title
+	}
+	if options.Description != nil {
+		issue.Description = *options.Description
+	}
+	if options.Labels != nil {
+		issue.Labels = splitLabels(*options.Labels)
+	}
+	if options.AddLabels != nil {
+		for _, label := range splitLabels(*options.AddLabels) {
+			if !strings.Contains(","+strings.Join(issue.Labels, ",")+",", ","+label+",") {
+				issue.Labels = append(issue.Labels, label)
+			}
+		}
+	}
+	if options.RemoveLabels != nil {
+		removed := "," + *options.RemoveLabels + ","
+		var kept []string
+		for _, label := range issue.Labels {
+			if !strings.Contains(removed, ","+label+",") {
+				kept = append(kept, label)
+			}
+		}
+		issue.Labels = kept
+	}
+	if options.MilestoneID != nil {
+		for _, milestone := range fake.Milestones {
+			if milestone.ID == *options.MilestoneID {
+				issue.Milestone = milestone
+			}
+		}
+	}
+	if options.AssigneeIDs != nil {
+		issue.Assignees = nil
+		for _, id := range *options.AssigneeIDs {
+			for _, user := range fake.Users {
+				if user.ID == id {
+					issue.Assignees = append(issue.Assignees, user)
+				}
+			}
+		}
+	}
+	if (options.StateEvent != nil) && (*options.StateEvent == "close") {
+		issue.State = "closed"
+	}
+}
+
+func splitLabels(labels string) []string {
+	if len(labels) == 0 {
+		return []string{}
+	}
+	return strings.Split(labels, ",")
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
+
+func (fake *fakeGitLab) serve(w http.ResponseWriter, r *http.Request) {
+	fake.Lock()
+	defer fake.Unlock()
+
+	route := r.Method + " " + r.URL.EscapedPath()
+
+	if route == "POST /oauth/token" {
+		writeJSON(w, 200, map[string]interface{}{"access_token": "gitlab-token", "token_type": "bearer"})
+		return
+	}
+
+	if r.Header.Get("Authorization") != "Bearer gitlab-token" {
+		writeJSON(w, 401, map[string]string{"message": "401 Unauthorized"})
+		return
+	}
+
+	if (fake.RateLimited > 0) && strings.HasPrefix(r.URL.Path, "/api/v4/projects/") {
+		fake.RateLimited--
+		w.Header().Set("Retry-After", "0")
+		writeJSON(w, 429, map[string]string{"message": "429 Too Many Requests"})
+		return
+	}
+
+	path := strings.Split(strings.TrimPrefix(r.URL.EscapedPath(), "/api/v4/"), "/")
+
+	switch {
+	case route == "GET /api/v4/user":
+		writeJSON(w, 200, fake.Users[0])
+
+	case route == "GET /api/v4/users":
+		var found []gitlabUser
+		for _, user := range fake.Users {
+			if user.Username == r.URL.Query().Get("username") {
+				found = append(found, user)
+			}
+		}
+		writeJSON(w, 200, found)
+
+	case route == "GET /api/v4/projects/testOrganization%2FtestRepository":
+		project := gitlabProject{ID: 7, Path: "testRepository", PathWithNamespace: "testOrganization/testRepository",
+			WebURL: fake.URL + "/testOrganization/testRepository"}
+		project.Namespace.FullPath = "testOrganization"
+		writeJSON(w, 200, project)
+
+	case (len(path) < 3) || (path[0] != "projects") || (path[1] != "7"):
+		writeJSON(w, 404, map[string]string{"message": "404 Not Found"})
+
+	default:
+		fake.serveProject(w, r, path[2:])
+	}
+}
+
+func (fake *fakeGitLab) serveProject(w http.ResponseWriter, r *http.Request, path []string) {
+	resource := strings.Join(path, "/")
+	if (len(path) == 3) && (path[0] == "boards") && (path[2] == "lists") {
+		resource = "boards/:id/lists"
+	}
+	if (len(path) == 2) && (path[0] == "issues") {
+		resource = "issues/:iid"
+	}
+	if (len(path) == 2) && (path[0] == "milestones") {
+		resource = "milestones/:id"
+	}
+	if (len(path) == 2) && (path[0] == "boards") {
+		resource = "boards/:id"
+	}
+	if (len(path) == 3) && (path[0] == "milestones") && (path[2] == "issues") {
+		resource = "milestones/:id/issues"
+	}
+
+	switch r.Method + " " + resource {
+	case "GET milestones":
+		found := []*gitlabMilestone{}
+		for _, milestone := range fake.Milestones {
+			if milestone.State == r.URL.Query().Get("state") {
+				found = append(found, milestone)
+			}
+		}
+		writeJSON(w, 200, found)
+
+	case "PUT milestones/:id", "DELETE milestones/:id":
+		for index, milestone := range fake.Milestones {
+			if strconv.Itoa(milestone.ID) != path[1] {
+				continue
+			}
+			if r.Method == "DELETE" {
+				fake.Milestones = append(fake.Milestones[:index:index], fake.Milestones[index+1:]...)
+				w.WriteHeader(204)
+				return
+			}
+			options := gitlabMilestone{}
+			json.NewDecoder(r.Body).Decode(&options)
+			if options.StateEvent == "close" {
+				milestone.State = "closed"
+			}
+			writeJSON(w, 200, milestone)
+			return
+		}
+		writeJSON(w, 404, map[string]string{"message": "404 Milestone Not Found"})
+
+	case "POST milestones":
+		milestone := gitlabMilestone{}
+		json.NewDecoder(r.Body).Decode(&milestone)
+		milestone.ID = fake.id()
+		milestone.State = "active"
+		fake.Milestones = append(fake.Milestones, &milestone)
+		writeJSON(w, 201, milestone)
+
+	case "GET issues":
+		query := r.URL.Query()
+		search, assignee := query.Get("search"), query.Get("assignee_username")
+		if (len(search) == 0) && (len(assignee) == 0) {
+			fake.Listings++
+		}
+		found := []*gitlabIssue{}
+		for _, issue := range fake.Issues {
+			if (issue.State != query.Get("state")) || !strings.Contains(issue.Title, search) {
+				continue
+			}
+			assigned := len(assignee) == 0
+			for _, user := range issue.Assignees {
+				assigned = assigned || (user.Username == assignee)
+			}
+			if assigned {
+				found = append(found, issue)
+			}
+		}
+		writeJSON(w, 200, found)
+
+	case "GET milestones/:id/issues":
+		found := []*gitlabIssue{}
+		for _, issue := range fake.Issues {
+			if (issue.Milestone != nil) && (strconv.Itoa(issue.Milestone.ID) == path[1]) {
+				found = append(found, issue)
+			}
+		}
+		writeJSON(w, 200, found)
+
+	case "POST issues":
+		options := gitlabIssueOptions{}
+		json.NewDecoder(r.Body).Decode(&options)
+		issue := gitlabIssue{ID: fake.id(), IID: len(fake.Issues) + 1, State: "opened", Labels: []string{}}
+		issue.WebURL = fmt.Sprintf("%s/testOrganization/testRepository/issues/%d", fake.URL, issue.IID)
+		fake.applyIssueOptions(&issue, &options)
+		fake.Issues = append(fake.Issues, &issue)
+		writeJSON(w, 201, issue)
+
+	case "GET issues/:iid", "PUT issues/:iid":
+		issue := fake.issueByIID(path[1])
+		if issue == nil {
+			writeJSON(w, 404, map[string]string{"message": "404 Not Found"})
+			return
+		}
+		if r.Method == "PUT" {
+			options := gitlabIssueOptions{}
+			json.NewDecoder(r.Body).Decode(&options)
+			fake.applyIssueOptions(issue, &options)
+		}
+		writeJSON(w, 200, issue)
+
+	case "GET labels":
+		writeJSON(w, 200, fake.Labels)
+
+	case "POST labels":
+		label := gitlabLabel{}
+		json.NewDecoder(r.Body).Decode(&label)
+		if fake.labelNamed(label.Name) != nil {
+			writeJSON(w, 409, map[string]string{"message": "Label already exists"})
+			return
+		}
+		label.ID = fake.id()
+		fake.Labels = append(fake.Labels, &label)
+		writeJSON(w, 201, label)
+
+	case "PUT labels":
+		options := gitlabLabel{}
+		json.NewDecoder(r.Body).Decode(&options)
+		label := fake.labelNamed(options.Name)
+		if label == nil {
+			writeJSON(w, 404, map[string]string{"message": "404 Label Not Found"})
+			return
+		}
+		if len(options.Color) > 0 {
+			label.Color = options.Color
+		}
+		if len(options.Description) > 0 {
+			label.Description = options.Description
+		}
+		writeJSON(w, 200, label)
+
+	case "GET boards":
+		writeJSON(w, 200, fake.Boards)
+
+	case "POST boards":
+		board := gitlabBoard{}
+		json.NewDecoder(r.Body).Decode(&board)
+		board.ID = fake.id()
+		board.Lists = []gitlabList{}
+		fake.Boards = append(fake.Boards, &board)
+		writeJSON(w, 201, board)
+
+	case "DELETE boards/:id":
+		for index, board := range fake.Boards {
+			if strconv.Itoa(board.ID) == path[1] {
+				fake.Boards = append(fake.Boards[:index:index], fake.Boards[index+1:]...)
+				w.WriteHeader(204)
+				return
+			}
+		}
+		writeJSON(w, 404, map[string]string{"message": "404 Board Not Found"})
+
+	case "GET boards/:id/lists", "POST boards/:id/lists":
+		var board *gitlabBoard
+		for _, candidate := range fake.Boards {
+			if strconv.Itoa(candidate.ID) == path[1] {
+				board = candidate
+			}
+		}
+		if board == nil {
+			writeJSON(w, 404, map[string]string{"message": "404 Board Not Found"})
+			return
+		}
+		if r.Method == "POST" {
+			options := map[string]int{}
+			json.NewDecoder(r.Body).Decode(&options)
+			for _, label := range fake.Labels {
+				if label.ID == options["label_id"] {
+					list := gitlabList{ID: fake.id(), Label: *label, Position: len(board.Lists)}
+					board.Lists = append(board.Lists, list)
+					writeJSON(w, 201, list)
+					return
+				}
+			}
+			writeJSON(w, 400, map[string]string{"message": "Label not found"})
+			return
+		}
+		writeJSON(w, 200, board.Lists)
+
+	default:
+		writeJSON(w, 404, map[string]string{"message": "404 Not Found"})
+	}
+}
+
+func prepareGitLabTest(t *testing.T) (*fakeGitLab, *AuthEnvironment) {
+	fake := newFakeGitLab()
+	creds := Credentials{
+		ClientID:     "TEST_CLIENT_ID",
+		ClientSecret: "TEST_CLIENT_SECRET",
+		Scopes:       []string{"api"},
+		Provider:     ProviderGitLab,
+		BaseURL:      fake.URL,
+	}
+	auth := creds.RestoreAuthEnvironment("", &oauth2.Token{AccessToken: "gitlab-token", TokenType: "bearer"})
+	return fake, auth
+}
+
+func TestGitLabAuthEnvironment(t *testing.T) {
+	fake, _ := prepareGitLabTest(t)
+	defer fake.Close()
+
+	creds := Credentials{ClientID: "TEST_CLIENT_ID", Provider: ProviderGitLab, BaseURL: fake.URL + "/"}
+	auth := creds.NewAuthEnvironment()
+
+	authURL := auth.AuthCodeURL()
+	assert(t, strings.HasPrefix(authURL, fake.URL+"/oauth/authorize?"), "Unexpected GitLab authorization URL: %s", authURL)
+
+	if _, err := auth.SetupAccessToken("test-code"); err != nil {
+		t.Fatalf("SetupAccessToken produced an error?! %v", err)
+	}
+	assertEqual(t, auth.Username(), "newhire", "GitLab username, actual %v, expected %v")
+
+	assertIsNil(t, ValidateProvider(ProviderGitLab, fake.URL), "GitLab provider should be valid, found %v")
+	assert(t, ValidateProvider(ProviderGitLab, "") != nil, "GitLab provider requires a URL")
+	assert(t, ValidateProvider("bitbucket", "") != nil, "Unknown providers are invalid")
+}
+
+func TestGitLabWorkload(t *testing.T) {
+	fake, auth := prepareGitLabTest(t)
+	defer fake.Close()
+
+	setup := preparePlanSetup()
+	setup.Labels = []LabelEntry{{Name: "onboarding", Color: "0e8a16"}}
+	job := GenerateProject{
+		ID:      42,
+		Setup:   setup,
+		AuthEnv: auth,
+	}
+
+	for attempt := 0; attempt < 2; attempt++ {
+		fake.Listings = 0
+		events := runJobEvents(job)
+		for _, event := range events {
+			if event.Type == "error" {
+				t.Fatalf("GitLab workload attempt %d failed: %s; %s", attempt, event.Text, event.Error)
+			}
+		}
+		last := events[len(events)-1]
+		assert(t, strings.Contains(last.Text, fake.URL+"/testOrganization/testRepository/boards"),
+			"Completion should link to the boards: %s", last.Text)
+		// Issues are searched for; only the board's cards need every open issue.
+		assertEqual(t, fake.Listings, 1, "Unfiltered listings of the issues, actual %d, expected %d")
+	}
+
+	assertEqual(t, len(fake.Milestones), 1, "GitLab milestones, actual %d, expected %d")
+	assertEqual(t, fake.Milestones[0].Title, "Welcome @newhire!", "GitLab milestone title, actual %v, expected %v")
+	assertEqual(t, len(fake.Boards), 1, "GitLab boards, actual %d, expected %d")
+	assertEqual(t, len(fake.Boards[0].Lists), len(defaultBoard), "GitLab board lists, actual %d, expected %d")
+	assertEqual(t, len(fake.Labels), 1+len(defaultBoard), "GitLab labels, actual %d, expected %d")
+	assertEqual(t, len(fake.Issues), len(setup.Tasks), "GitLab issues, actual %d, expected %d")
+
+	for _, issue := range fake.Issues {
+		assertEqual(t, strings.Join(issue.Labels, ","), "onboarding,Backlog", "GitLab issue labels, actual %v, expected %v")
+		assertEqual(t, issue.Milestone.ID, fake.Milestones[0].ID, "GitLab issue milestone, actual %v, expected %v")
+		assertEqual(t, issue.Assignees[0].Username, "test", "GitLab issue assignee, actual %v, expected %v")
+	}
+
+	// An issue which lost a label (e.g. generated before it was declared) has it added back, keeping its others.
+	fake.Issues[0].Labels = []string{"Backlog"}
+	plan, err := job.Plan()
+	if err != nil {
+		t.Fatalf("Plan produced an error?! %v", err)
+	}
+	for _, change := range plan.Changes {
+		if (change.Resource == "issue") && (change.Title == "test1") {
+			assertEqual(t, change.Reason, "labels differs", "Planned issue change, actual %q, expected %q")
+		}
+	}
+	assertNoErrorEvents(t, runJobEvents(job), "Repeated GitLab workload failed")
+	assertEqual(t, strings.Join(fake.Issues[0].Labels, ","), "Backlog,onboarding", "GitLab issue labels added back, actual %v, expected %v")
+
+	plan, err = job.Plan()
+	if err != nil {
+		t.Fatalf("Plan produced an error?! %v", err)
+	}
+	for _, change := range plan.Changes {
+		if change.Action != PlanUnchanged {
+			t.Errorf("Expected no changes after a GitLab workload, found: %s", change)
+		}
+	}
+}
+
+func TestGitLabUnknownAssignee(t *testing.T) {
+	fake, auth := prepareGitLabTest(t)
+	defer fake.Close()
+
+	client, _ := auth.newWorkflowClient(nil, nil)
+	repo, err := client.GetRepository("testOrganization", "testRepository")
+	if err != nil {
+		t.Fatalf("GetRepository produced an error?! %v", err)
+	}
+
+	assignee, title, body := "nobody", "test", "test"
+	if _, err = repo.CreateOrUpdateIssue([]string{assignee}, &title, &body, 0, nil); err == nil {
+		t.Errorf("Expected an error assigning an issue to an unknown user")
+	}
+
+	if _, err = client.GetRepository("testOrganization", "missing"); err == nil {
+		t.Errorf("Expected an error fetching an unknown project")
+	}
+}
+
+func TestGitLabTeardown(t *testing.T) {
+	fake, auth := prepareGitLabTest(t)
+	defer fake.Close()
+
+	setup := preparePlanSetup()
+	assertNoErrorEvents(t, runJobEvents(GenerateProject{ID: 42, Setup: setup, AuthEnv: auth}), "GitLab workload failed")
+	assertNoErrorEvents(t, runTeardownEvents(TeardownProject{ID: 42, Setup: setup, AuthEnv: auth}), "GitLab teardown failed")
+
+	assertEqual(t, len(fake.Boards), 0, "GitLab boards after teardown, actual %d, expected %d")
+	assertEqual(t, fake.Milestones[0].State, "closed", "GitLab milestone state after teardown, actual %v, expected %v")
+	for _, issue := range fake.Issues {
+		assertEqual(t, issue.State, "closed", "GitLab issue state after teardown, actual %v, expected %v")
+		assertEqual(t, len(issue.Labels), 0, "GitLab issue list labels after teardown, actual %d, expected %d")
+	}
+
+	assertNoErrorEvents(t, runTeardownEvents(TeardownProject{ID: 42, Setup: setup, AuthEnv: auth}), "Repeated GitLab teardown failed")
+}
+
+func TestGitLabSync(t *testing.T) {
+	fake, auth := prepareGitLabTest(t)
+	defer fake.Close()
+
+	setup := preparePlanSetup()
+	setup.Tasks[0].Description = "- [ ] Slack\n- [ ] Email\n"
+	job := GenerateProject{ID: 42, Setup: setup, AuthEnv: auth}
+	assertNoErrorEvents(t, runJobEvents(job), "GitLab workload failed")
+
+	fake.Issues[0].Description = "- [x] Slack\n- [ ] Email"
+	setup.Tasks[0].Description = "- [ ] Slack\n- [ ] Email\n- [ ] Calendar\n"
+	setup.Tasks[1].Assignee.GithubUsername = "newhire"
+
+	job.Sync = true
+	assertNoErrorEvents(t, runJobEvents(job), "GitLab sync failed")
+
+	assertEqual(t, len(fake.Issues), len(setup.Tasks), "GitLab issues after a sync, actual %d, expected %d")
+	assertEqual(t, fake.Issues[0].Description, "- [x] Slack\n- [ ] Email\n- [ ] Calendar\n", "GitLab synced description, actual %q, expected %q")
+	assertEqual(t, fake.Issues[1].Assignees[0].Username, "newhire", "GitLab synced assignee, actual %v, expected %v")
+	assertEqual(t, len(fake.Issues[1].Assignees), 1, "GitLab synced assignees, actual %d, expected %d")
+
+	plan, err := job.Plan()
+	if err != nil {
+		t.Fatalf("Plan produced an error?! %v", err)
+	}
+	for _, change := range plan.Changes {
+		if change.Action != PlanUnchanged {
+			t.Errorf("Expected no changes after a GitLab sync, found: %s", change)
+		}
+	}
+}
+
+func TestGitLabRateLimit(t *testing.T) {
+	fake, auth := prepareGitLabTest(t)
+	defer fake.Close()
+
+	fake.RateLimited = 2
+	events := runJobEvents(GenerateProject{ID: 42, Setup: preparePlanSetup(), AuthEnv: auth})
+	assertNoErrorEvents(t, events, "Rate limited GitLab workload failed")
+	assertEqual(t, countEvents(events, "Waiting 0s for the rate limit to reset"), 2, "Rate limit waits, actual %d, expected %d")
+	assertEqual(t, len(fake.Issues), 2, "GitLab issues, actual %d, expected %d")
+}
+
+func TestGitLabCardPlacement(t *testing.T) {
+	fake, auth := prepareGitLabTest(t)
+	defer fake.Close()
+
+	job := GenerateProject{ID: 42, Setup: preparePlanSetup(), AuthEnv: auth}
+	assertNoErrorEvents(t, runJobEvents(job), "GitLab workload failed")
+
+	// The hire moved the first issue along the board; re-running leaves it there.
+	fake.Issues[0].Labels = []string{"Review"}
+	events := runJobEvents(job)
+	assertNoErrorEvents(t, events, "Repeated GitLab workload failed")
+	assertEqual(t, countEvents(events, "Already on board - "), 2, "Issues already on board, actual %d, expected %d")
+	assertEqual(t, strings.Join(fake.Issues[0].Labels, ","), "Review", "Labels of the moved issue, actual %v, expected %v")
+
+	client, _ := auth.newWorkflowClient(nil, nil)
+	repo, _ := client.GetRepository("testOrganization", "testRepository")
+	project, _ := repo.GetProjectByTitle(github.String("Welcome @newhire!"))
+	columns, _ := repo.FetchMappedProjectColumns(project)
+	issues, _ := repo.GetIssuesByRequest(&github.IssueRequest{Title: github.String("test2")})
+
+	board, err := repo.FetchBoardCards(project)
+	assertIsNil(t, err, "Fetching the board's issues produced an error?! %v")
+	_, placed, err := repo.CreateCardForIssue(board, issues[0], columns["In Progress"])
+	assertIsNil(t, err, "Moving an issue produced an error?! %v")
+	assertEqual(t, placed, CardMoved, "Placement in a later list, actual %v, expected %v")
+	assertEqual(t, strings.Join(fake.Issues[1].Labels, ","), "In Progress", "Labels of the moved issue, actual %v, expected %v")
+}
+
+func TestGitLabAddsMissingLists(t *testing.T) {
+	fake, auth := prepareGitLabTest(t)
+	defer fake.Close()
+
+	setup := preparePlanSetup()
+	setup.Columns = []ColumnEntry{{Name: "To Do", Preset: PresetTodo}, {Name: "Done", Preset: PresetDone}}
+	job := GenerateProject{ID: 42, Setup: setup, AuthEnv: auth}
+	assertNoErrorEvents(t, runJobEvents(job), "GitLab workload failed")
+	assertEqual(t, len(fake.Boards[0].Lists), 2, "GitLab board lists, actual %d, expected %d")
+
+	setup.Columns = append(setup.Columns, ColumnEntry{Name: "Blocked"})
+	setup.Tasks[0].Column = "Blocked"
+	assertNoErrorEvents(t, runJobEvents(job), "Repeated GitLab workload failed")
+	assertEqual(t, len(fake.Boards), 1, "GitLab boards, actual %d, expected %d")
+	assertEqual(t, len(fake.Boards[0].Lists), 3, "GitLab board lists after adding one, actual %d, expected %d")
+	assertEqual(t, strings.Join(fake.Issues[0].Labels, ","), "Blocked", "Labels of the moved issue, actual %v, expected %v")
+}
+
+func TestGitLabBuiltInLists(t *testing.T) {
+	fake, auth := prepareGitLabTest(t)
+	defer fake.Close()
+
+	job := GenerateProject{ID: 42, Setup: preparePlanSetup(), AuthEnv: auth}
+	assertNoErrorEvents(t, runJobEvents(job), "GitLab workload failed")
+
+	// The Open and Closed lists have no label, and are not columns.
+	board := fake.Boards[0]
+	board.Lists = append([]gitlabList{{ID: fake.id()}}, append(board.Lists, gitlabList{ID: fake.id()})...)
+
+	client, _ := auth.newWorkflowClient(nil, nil)
+	repo, _ := client.GetRepository("testOrganization", "testRepository")
+	project, _ := repo.GetProjectByTitle(github.String("Welcome @newhire!"))
+	columns, err := repo.FetchMappedProjectColumns(project)
+	assertIsNil(t, err, "Fetching the lists produced an error?! %v")
+	assertEqual(t, len(columns), len(defaultBoard), "GitLab board columns, actual %d, expected %d")
+	_, unnamed := columns[""]
+	assert(t, !unnamed, "Expected no column for the lists without a label")
+
+	events := runJobEvents(job)
+	assertNoErrorEvents(t, events, "Repeated GitLab workload failed")
+	assertEqual(t, countEvents(events, "Already on board - "), 2, "Issues already on board, actual %d, expected %d")
+}
+
+func TestGitLabUnknownHire(t *testing.T) {
+	fake, auth := prepareGitLabTest(t)
+	defer fake.Close()
+
+	_, err := auth.ResolveUsername("nobody")
+	assert(t, err != nil, "Expected an error resolving an unknown GitLab user")
+
+	job := GenerateProject{ID: 42, Setup: preparePlanSetup(), AuthEnv: auth, Hire: "nobody"}
+	events := runJobEvents(job)
+	assertEqual(t, events[len(events)-1].Type, "error", "Last event for an unknown hire, actual %v, expected %v")
+	assertEqual(t, len(fake.Milestones), 0, "GitLab milestones, actual %d, expected %d")
+}
@@ -0,0 +1,294 @@
+/*
+This module composes a setup scheme from several files, so that teams can own their sections of it: a scheme may
+`include` files, directories (their .yaml and .yml files) or globs, relative to itself. The included files are
+merged in order, then the including file itself; a later declaration overrides an earlier one with the same title
+(tasks) or name (task owners, roles, labels, phases).
+*/
+
+package onboarding
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+type (
+	// schemeFile is one of the files a scheme is composed of, as parsed.
+	schemeFile struct {
+		filename string // empty for the scheme's own file, which its problems are located in by load
+		setup    *SetupScheme
+		lines    schemeLines
+	}
+
+	// schemeLoader reads the files of a scheme, following their includes.
+	schemeLoader struct {
+		environ   *map[string]string
+		files     []schemeFile    // in the order they are merged
+		including map[string]bool // the files being read, by absolute path, to detect cycles
+		contents  bytes.Buffer    // of every file read, telling the scheme's versions apart
+		sources   []string        // the files and directories read, to watch for changes
+	}
+)
+
+func newSchemeLoader(environ *map[string]string, filename string) *schemeLoader {
+	loader := schemeLoader{environ: environ, including: make(map[string]bool)}
+	if len(filename) > 0 {
+		loader.including[absolutePath(filename)] = true
+		loader.sources = append(loader.sources, filename)
+	}
+	return &loader
+}
+
+func absolutePath(filename string) string {
+	if path, err := filepath.Abs(filename); err == nil {
+		return path
+	}
+	return filename
+}
+
+// parseScheme renders the template of a scheme's file, and parses it.
+func parseScheme(data []byte, environ *map[string]string) (*SetupScheme, schemeLines, ConfigErrors) {
+	var rendered bytes.Buffer
+
+	context := map[string]map[string]string{
+		"Environ": *environ,
+	}
+
+	if errs := checkEnviron(data, *environ); len(errs) > 0 {
+		return nil, nil, errs
+	}
+
+	tpl, err := template.New("config").Parse(string(data))
+
+	if err != nil {
+		return nil, nil, parseErrors(err)
+	}
+
+	if err = tpl.Execute(&rendered, context); err != nil {
+		return nil, nil, parseErrors(err)
+	}
+
+	setup := SetupScheme{}
+	lines := newSchemeLines(rendered.String())
+	if err = yaml.UnmarshalStrict(rendered.Bytes(), &setup); err != nil {
+		return nil, nil, lines.locate(parseErrors(err)).sorted()
+	}
+	return &setup, lines, nil
+}
+
+// add parses a file of the scheme, and reads the files it includes before it.
+func (loader *schemeLoader) add(filename string, baseDir string, data []byte) ConfigErrors {
+	loader.contents.Write(data)
+
+	setup, lines, errs := parseScheme(data, loader.environ)
+	if len(errs) > 0 {
+		return errs.inFile(filename)
+	}
+	setup.baseDir = baseDir
+
+	for index, pattern := range setup.Include {
+		field := fmt.Sprintf("include[%d]", index)
+		filenames, err := loader.expand(baseDir, pattern)
+		for _, included := range filenames {
+			if err != nil {
+				break
+			}
+			err = loader.read(included)
+		}
+		if included, ok := err.(ConfigErrors); ok {
+			errs = append(errs, included...)
+		} else if err != nil {
+			errs = append(errs, ConfigError{File: filename, Line: lines.find(field), Field: field, Message: fmt.Sprintf("Cannot include '%s': %v", pattern, err)})
+		}
+	}
+
+	loader.files = append(loader.files, schemeFile{filename: filename, setup: setup, lines: lines})
+	return errs
+}
+
+// read adds an included file.
+func (loader *schemeLoader) read(filename string) error {
+	key := absolutePath(filename)
+	if loader.including[key] {
+		return fmt.Errorf("'%s' includes itself", filename)
+	}
+
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	loader.sources = append(loader.sources, filename)
+
+	loader.including[key] = true
+	defer delete(loader.including, key)
+	if errs := loader.add(filename, filepath.Dir(filename), data); len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// expand lists the files an include stands for: a file, the .yaml and .yml files of a directory (in name order),
+// or those matching a glob (a glob may match none).
+func (loader *schemeLoader) expand(baseDir string, pattern string) ([]string, error) {
+	path := pattern
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(baseDir, path)
+	}
+
+	paths := []string{path}
+	if strings.ContainsAny(path, "*?[") {
+		matches, err := filepath.Glob(path)
+		if err != nil {
+			return nil, err
+		}
+		paths = matches
+		loader.sources = append(loader.sources, filepath.Dir(path))
+	}
+
+	var filenames []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			filenames = append(filenames, path)
+			continue
+		}
+
+		loader.sources = append(loader.sources, path)
+		var inDir []string
+		for _, extension := range []string{"*.yaml", "*.yml"} {
+			matches, _ := filepath.Glob(filepath.Join(path, extension))
+			inDir = append(inDir, matches...)
+		}
+		sort.Strings(inDir)
+		filenames = append(filenames, inDir...)
+	}
+	return filenames, nil
+}
+
+// merge applies the declarations of another file of the scheme over the scheme's.
+func (setup *SetupScheme) merge(other *SetupScheme) {
+	for _, setting := range []struct{ value, override *string }{
+		{&setup.ClientID, &other.ClientID},
+		{&setup.ClientSecret, &other.ClientSecret},
+		{&setup.GithubOrganization, &other.GithubOrganization},
+		{&setup.GithubRepository, &other.GithubRepository},
+	} {
+		if len(*setting.override) > 0 {
+			*setting.value = *setting.override
+		}
+	}
+	setup.Include = append(setup.Include, other.Include...)
+
+	setup.Tasks = mergeTasks(setup.Tasks, other.Tasks)
+
+	if (setup.TaskOwners == nil) && (len(other.TaskOwners) > 0) {
+		setup.TaskOwners = make(map[string]indirectAssignee)
+	}
+	for name, owner := range other.TaskOwners {
+		setup.TaskOwners[name] = owner
+	}
+
+	if (setup.Roles == nil) && (len(other.Roles) > 0) {
+		setup.Roles = make(map[string]RoleEntry)
+	}
+	for name, role := range other.Roles {
+		merged, ok := setup.Roles[name]
+		if !ok {
+			setup.Roles[name] = role
+			continue
+		}
+		if len(role.Name) > 0 {
+			merged.Name = role.Name
+		}
+		merged.Tasks = mergeTasks(merged.Tasks, role.Tasks)
+		merged.Remove = append(merged.Remove, role.Remove...)
+		merged.Buddies.merge(&role.Buddies)
+		setup.Roles[name] = merged
+	}
+
+	declared := len(setup.Labels) // duplicates within a file are left to the validations
+	for _, label := range other.Labels {
+		replaced := false
+		for index := range setup.Labels[:declared] {
+			if strings.EqualFold(setup.Labels[index].Name, label.Name) {
+				setup.Labels[index], replaced = label, true
+			}
+		}
+		if !replaced {
+			setup.Labels = append(setup.Labels, label)
+		}
+	}
+
+	if len(other.Columns) > 0 {
+		setup.Columns = other.Columns // the board's layout is declared as a whole
+	}
+
+	if other.Schedule.BusinessDays != 0 {
+		setup.Schedule.BusinessDays = other.Schedule.BusinessDays
+	}
+	if len(other.Schedule.Timezone) > 0 {
+		setup.Schedule.Timezone = other.Schedule.Timezone
+	}
+	setup.Schedule.Holidays = append(setup.Schedule.Holidays, other.Schedule.Holidays...)
+	if calendar := other.Schedule.Calendar; len(calendar) > 0 {
+		if !filepath.IsAbs(calendar) && (other.baseDir != setup.baseDir) {
+			// Relative to the file declaring it, rather than the scheme's.
+			if path, err := filepath.Rel(setup.baseDir, filepath.Join(other.baseDir, calendar)); err == nil {
+				calendar = path
+			}
+		}
+		setup.Schedule.Calendar = calendar
+	}
+
+	declared = len(setup.Phases)
+	for _, phase := range other.Phases {
+		replaced := false
+		for index := range setup.Phases[:declared] {
+			if setup.Phases[index].Name == phase.Name {
+				setup.Phases[index], replaced = phase, true
+			}
+		}
+		if !replaced {
+			setup.Phases = append(setup.Phases, phase)
+		}
+	}
+
+	setup.Buddies.merge(&other.Buddies)
+}
+
+// mergeTasks replaces the tasks which have the title of an override, and appends the other overrides.
+func mergeTasks(tasks []TaskEntry, overrides []TaskEntry) []TaskEntry {
+	merged := append([]TaskEntry{}, tasks...)
+	for _, override := range overrides {
+		replaced := false
+		for index := range tasks {
+			if merged[index].Title == override.Title {
+				merged[index], replaced = override, true
+			}
+		}
+		if !replaced {
+			merged = append(merged, override)
+		}
+	}
+	return merged
+}
+
+func (buddies *BuddiesEntry) merge(other *BuddiesEntry) {
+	if len(other.Pool) > 0 {
+		buddies.Pool = other.Pool
+	}
+	if len(other.Strategy) > 0 {
+		buddies.Strategy = other.Strategy
+	}
+}
@@ -0,0 +1,151 @@
+package onboarding
+
+/*
+This module's tests focus on exercising the `include.go` module.
+*/
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeSchemeFiles writes the files of a scheme, by path relative to a new temporary directory, which it returns.
+func writeSchemeFiles(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "onboarding")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		filename := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(filename), 0700)
+		if err = ioutil.WriteFile(filename, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestIncludeMerge(t *testing.T) {
+	dir := writeSchemeFiles(t, map[string]string{
+		"tasks.yaml": `
+githubOrganization: "{{ index .Environ "onboard.org" }}"
+include:
+    - common.yaml
+    - teams
+    - "roles/*.yaml"
+task_owners:
+    new_hire:
+        github_username: $username
+labels: [onboarding]
+tasks:
+    - title: Read the handbook
+      description: The team's own handbook
+      assignee: new_hire
+`,
+		"common.yaml": `
+tasks:
+    - title: Read the handbook
+      description: The company's handbook
+      assignee: new_hire
+    - title: Set up a laptop
+      assignee: new_hire
+labels:
+    - name: onboarding
+      color: 0e8a16
+`,
+		"teams/a.yaml": `
+githubRepository: "{{ index .Environ "onboard.repo" }}"
+tasks:
+    - title: Join the on-call rotation
+      assignee: lead
+task_owners:
+    lead:
+        github_username: octocat
+`,
+		"teams/b.yml": `
+tasks:
+    - title: Join the on-call rotation
+      assignee: lead
+      depends_on: [Set up a laptop]
+`,
+		"teams/notes.txt": "not a scheme",
+		"roles/sre.yaml": `
+roles:
+    sre:
+        tasks:
+            - title: Learn the pager
+              assignee: new_hire
+`,
+	})
+	defer os.RemoveAll(dir)
+
+	setup, err := NewSetupScheme(filepath.Join(dir, "tasks.yaml"), &map[string]string{"onboard.org": "org", "onboard.repo": "repo"})
+	if err != nil {
+		t.Fatalf("NewSetupScheme produced an error?! %v", err)
+	}
+
+	assertEqual(t, setup.GithubOrganization, "org", "Organization, actual %s, expected %s")
+	assertEqual(t, setup.GithubRepository, "repo", "Repository, actual %s, expected %s")
+
+	var titles []string
+	for _, task := range setup.Tasks {
+		titles = append(titles, task.Title)
+	}
+	assertEqual(t, strings.Join(titles, ", "), "Read the handbook, Set up a laptop, Join the on-call rotation", "Tasks, actual %s, expected %s")
+	assertEqual(t, setup.Tasks[0].Description, "The team's own handbook", "Overridden description, actual %s, expected %s")
+	assertEqual(t, len(setup.Tasks[2].DependsOn), 1, "Prerequisites of the task overridden by teams/b.yml, actual %d, expected %d")
+	assertEqual(t, setup.Tasks[2].Assignee.GithubUsername, "octocat", "Owner from an included file, actual %s, expected %s")
+
+	assertEqual(t, len(setup.Labels), 1, "Labels, actual %d, expected %d")
+	assertEqual(t, setup.Labels[0].Color, "", "Label color overridden by the including file, actual %q, expected %q")
+	assertEqual(t, len(setup.Roles["sre"].Tasks), 1, "Role tasks from a glob, actual %d, expected %d")
+
+	unchanged, _ := NewSetupScheme(filepath.Join(dir, "tasks.yaml"), &map[string]string{"onboard.org": "org", "onboard.repo": "repo"})
+	assertEqual(t, unchanged.Version, setup.Version, "Version of the same files, actual %s, expected %s")
+
+	ioutil.WriteFile(filepath.Join(dir, "teams/b.yml"), []byte("# Nothing to add\n"), 0600)
+	changed, _ := NewSetupScheme(filepath.Join(dir, "tasks.yaml"), &map[string]string{"onboard.org": "org", "onboard.repo": "repo"})
+	assert(t, (changed != nil) && (changed.Version != setup.Version), "An included file's change should change the version")
+}
+
+func TestIncludeErrors(t *testing.T) {
+	dir := writeSchemeFiles(t, map[string]string{
+		"tasks.yaml":   "include: [teams]\ntasks:\n    - title: one\n",
+		"teams/a.yaml": "tasks:\n    - title: two\n    - title: two\n      assignee: nobody\n",
+		"missing.yaml": "include:\n    - tasks.yaml\n    - nowhere.yaml\n",
+		"cycle.yaml":   "include: [cycle.yaml]\n",
+	})
+	defer os.RemoveAll(dir)
+
+	_, err := NewSetupScheme(filepath.Join(dir, "tasks.yaml"), &map[string]string{})
+	team := filepath.Join(dir, "teams", "a.yaml")
+	expected := team + ":3:tasks[1].title: Task 'two' is declared more than once\n" + team + ":4:tasks[1].assignee: Unknown task owner 'nobody'"
+	assertEqual(t, err.Error(), expected, "Problems of an included file, actual %q, expected %q")
+
+	_, err = NewSetupScheme(filepath.Join(dir, "missing.yaml"), &map[string]string{})
+	assert(t, (err != nil) && strings.HasPrefix(err.Error(), filepath.Join(dir, "missing.yaml")+":3:include[1]: Cannot include 'nowhere.yaml'"), "Missing include, actual %v", err)
+
+	_, err = NewSetupScheme(filepath.Join(dir, "cycle.yaml"), &map[string]string{})
+	assert(t, (err != nil) && strings.Contains(err.Error(), "includes itself"), "Include cycle, actual %v", err)
+}
+
+func TestSchemeWatcherIncludes(t *testing.T) {
+	dir := writeSchemeFiles(t, map[string]string{
+		"tasks.yaml":   "include: [teams]\n",
+		"teams/a.yaml": "tasks:\n    - title: one\n",
+	})
+	defer os.RemoveAll(dir)
+
+	watcher, err := NewSchemeWatcher(filepath.Join(dir, "tasks.yaml"), &map[string]string{})
+	if err != nil {
+		t.Fatalf("NewSchemeWatcher produced an error?! %v", err)
+	}
+
+	ioutil.WriteFile(filepath.Join(dir, "teams/b.yaml"), []byte("tasks:\n    - title: two\n"), 0600)
+	replaced, err := watcher.Reload()
+	assert(t, replaced && (err == nil), "Reloading with a new included file, actual %v %v, expected a replacement", replaced, err)
+	assertEqual(t, len(watcher.Current().Tasks), 2, "Tasks after reloading, actual %d, expected %d")
+}
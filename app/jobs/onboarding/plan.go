@@ -0,0 +1,325 @@
+/*
+This module computes what a GenerateProject run would change in a repository, using only read-only requests.
+*/
+
+package onboarding
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-github/github"
+	"github.com/samsung-cnct/container-technical-on-boarding/app/jobs"
+)
+
+// Actions a Plan may report for each resource.
+const (
+	PlanCreate    = "create"
+	PlanUpdate    = "update"
+	PlanUnchanged = "unchanged"
+	PlanSkip      = "skip"
+)
+
+type (
+	// Plan describes the changes a GenerateProject run would make against a repository.
+	Plan struct {
+		Organization string       `json:"organization"`
+		Repository   string       `json:"repository"`
+		Username     string       `json:"username"`
+		Buddy        string       `json:"buddy,omitempty"`
+		Role         string       `json:"role,omitempty"`
+		Changes      []PlanChange `json:"changes"`
+	}
+
+	// PlanChange is a single resource within a Plan, and what would happen to it.
+	PlanChange struct {
+		Resource string `json:"resource"` // "label", "milestone", "project", "column", "issue", "card" or "buddy"
+		Title    string `json:"title"`
+		Action   string `json:"action"`
+		Reason   string `json:"reason,omitempty"`
+	}
+)
+
+func (change PlanChange) String() string {
+	text := fmt.Sprintf("%s %s - %s", change.Action, change.Resource, change.Title)
+	if len(change.Reason) > 0 {
+		text = fmt.Sprintf("%s (%s)", text, change.Reason)
+	}
+	return text
+}
+
+func (plan *Plan) add(resource string, title string, action string, reason string) {
+	plan.Changes = append(plan.Changes, PlanChange{
+		Resource: resource,
+		Title:    title,
+		Action:   action,
+		Reason:   reason,
+	})
+}
+
+// Count returns the number of changes in the plan with the given action.
+func (plan *Plan) Count(action string) int {
+	count := 0
+	for _, change := range plan.Changes {
+		if change.Action == action {
+			count++
+		}
+	}
+	return count
+}
+
+// Summary renders the change counts of the plan as text.
+func (plan *Plan) Summary() string {
+	return fmt.Sprintf("%d to create, %d to update, %d unchanged, %d skipped",
+		plan.Count(PlanCreate), plan.Count(PlanUpdate), plan.Count(PlanUnchanged), plan.Count(PlanSkip))
+}
+
+// JSON renders the plan as a JSON document.
+func (plan *Plan) JSON() ([]byte, error) {
+	return json.MarshalIndent(plan, "", "  ")
+}
+
+// buildPlan walks the job's tasks through read-only repository requests,
+// mirroring the decisions Run makes for the given username and buddy (including Sync's edits of drifted issues).
+func (job GenerateProject) buildPlan(repo IRepositoryAccess, username string, buddy string) (*Plan, error) {
+	setup := job.Setup
+	plan := Plan{
+		Organization: setup.GithubOrganization,
+		Repository:   setup.GithubRepository,
+		Username:     username,
+		Buddy:        buddy,
+		Role:         job.Role,
+	}
+
+	tasks, err := setup.TasksForRole(job.Role)
+	if err != nil {
+		return nil, err
+	}
+
+	existingLabels, err := repo.FetchMappedLabels()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, label := range setup.LabelsForTasks(tasks) {
+		existing, ok := existingLabels[strings.ToLower(label.Name)]
+		switch {
+		case !ok:
+			plan.add("label", label.Name, PlanCreate, "")
+		case labelDiffers(existing, label):
+			plan.add("label", label.Name, PlanUpdate, "color or description differs")
+		default:
+			plan.add("label", label.Name, PlanUnchanged, "")
+		}
+	}
+
+	schedule, err := setup.NewSchedule(job.StartDate)
+	if err != nil {
+		return nil, err
+	}
+	keepDue := len(job.StartDate) == 0
+
+	title := welcomeTitle(username)
+	description := welcomeDescription(username)
+
+	// Each phase of the onboarding has a milestone, mapped by the phase's name; nil when it is yet to be created.
+	milestones := make(map[string](*github.Milestone))
+	for _, phase := range setup.PhasesForTasks(tasks) {
+		milestoneTitle := phase.MilestoneTitle(username)
+		milestone, err := repo.GetMilestoneByTitle(&milestoneTitle)
+		if err != nil {
+			return nil, err
+		}
+
+		if milestone == nil {
+			plan.add("milestone", milestoneTitle, PlanCreate, fmt.Sprintf("due %s", schedule.PhaseDue(&phase).Format(dateLayout)))
+		} else {
+			plan.add("milestone", milestoneTitle, PlanUnchanged, "")
+		}
+		milestones[phase.Name] = milestone
+	}
+
+	project, err := repo.GetProjectByTitle(&title)
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make(map[string](*github.ProjectColumn))
+
+	switch {
+	case project == nil:
+		plan.add("project", title, PlanCreate, "")
+		for _, name := range setup.ColumnNames() {
+			plan.add("column", name, PlanCreate, "")
+		}
+	case (project.Body != nil) && (project.GetBody() != description): // GitLab boards have no description
+		plan.add("project", title, PlanUpdate, "description differs")
+	default:
+		plan.add("project", title, PlanUnchanged, "")
+	}
+
+	var board *BoardCards
+	if project != nil {
+		columns, err = repo.FetchMappedProjectColumns(project)
+		if err != nil {
+			return nil, err
+		}
+		if board, err = repo.FetchBoardCards(project); err != nil {
+			return nil, err
+		}
+		for _, name := range setup.ColumnNames() {
+			if _, ok := columns[name]; ok {
+				plan.add("column", name, PlanUnchanged, "")
+			} else {
+				plan.add("column", name, PlanCreate, "missing from the project")
+			}
+		}
+	}
+
+	var hireMilestones []int
+	if job.Sync {
+		if hireMilestones, err = findHireMilestones(repo, setup, username); err != nil {
+			return nil, err
+		}
+	}
+
+	issueNumbers := make(map[string]int)
+
+	for _, task := range tasks {
+		var issue *github.Issue
+		var drifted []string
+		milestone := milestones[setup.TaskPhase(&task).Name]
+		assignees := setup.TaskAssignees(&task, username, buddy)
+
+		switch {
+		case job.Sync:
+			// The issue may be in another of the hire's milestones, or the milestone is yet to be created; then only
+			// an issue assigned to the hire is found, and other hires' issues for the task are planned as "create".
+			issue, err = findTaskIssue(repo, username, task.Title, milestone.GetNumber(), hireMilestones)
+			if err != nil {
+				return nil, err
+			}
+			if issue != nil {
+				drifted, _ = issueDrift(issue, assignees, issueBody(&task, issueNumbers, schedule), milestone.GetNumber(), setup.IssueLabels(&task), keepDue)
+				if milestone == nil {
+					drifted = append(drifted, "milestone")
+				}
+			}
+		case milestone != nil:
+			request := newIssueRequest(assignees, &task.Title, &task.Description, milestone.GetNumber(), nil)
+			issues, err := repo.GetIssuesByRequest(&request)
+			if err != nil {
+				return nil, err
+			}
+			if len(issues) > 0 {
+				issue = issues[0]
+				if labelEdit(issue, setup.IssueLabels(&task)) != nil {
+					drifted = append(drifted, "labels")
+				}
+			}
+		}
+
+		switch {
+		case issue == nil:
+			plan.add("issue", task.Title, PlanCreate, "")
+		case len(drifted) > 0:
+			issueNumbers[task.Title] = issue.GetNumber()
+			plan.add("issue", task.Title, PlanUpdate, fmt.Sprintf("%s differs", strings.Join(drifted, ", ")))
+		default:
+			issueNumbers[task.Title] = issue.GetNumber()
+			plan.add("issue", task.Title, PlanUnchanged, "")
+		}
+
+		start := setup.StartColumn(&task)
+		_, current, placement := board.Placement(issue, columns[start])
+		switch placement {
+		case CardCreated:
+			plan.add("card", task.Title, PlanCreate, fmt.Sprintf("in %s", start))
+		case CardMoved:
+			plan.add("card", task.Title, PlanUpdate, fmt.Sprintf("moves from %s to %s", current.GetName(), start))
+		default:
+			plan.add("card", task.Title, PlanUnchanged, "")
+		}
+	}
+
+	return &plan, nil
+}
+
+// Plan reports what Run would change for the job's user, without modifying the repository.
+func (job GenerateProject) Plan() (*Plan, error) {
+	setup := job.Setup
+	auth := job.AuthEnv
+
+	// Waits are only reported while streaming the plan as events.
+	var notify RetryNotifier
+	if job.New != nil {
+		notify = jobWaitNotifier(job.ID, job.New)
+	}
+
+	client, err := auth.newWorkflowClient(job.Context, notify)
+	if err != nil {
+		return nil, err
+	}
+
+	username, err := job.onboardee(client, auth.Username())
+	if err != nil {
+		return nil, err
+	}
+
+	tasks, err := setup.TasksForRole(job.Role)
+	if err != nil {
+		return nil, err
+	}
+	buddy, picked, err := job.buddyFor(username, job.Role, true)
+	if err != nil {
+		return nil, err
+	}
+	if err = job.checkAssignees(client, tasks, username, buddy); err != nil {
+		return nil, err
+	}
+
+	repo, err := client.GetRepository(setup.GithubOrganization, setup.GithubRepository)
+	if err != nil {
+		return nil, err
+	}
+
+	plan, err := job.buildPlan(repo, username, buddy)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case picked:
+		strategy := setup.BuddiesForRole(job.Role).Strategy
+		if len(strategy) == 0 {
+			strategy = BuddyRoundRobin
+		}
+		plan.add("buddy", buddy, PlanCreate, fmt.Sprintf("picked by %s", strategy))
+	case len(buddy) > 0:
+		plan.add("buddy", buddy, PlanUnchanged, "")
+	}
+	return plan, nil
+}
+
+// runPlan emits the job's plan as a stream of "plan" events.
+func (job GenerateProject) runPlan() {
+	defer close(job.New)
+	if job.Sync {
+		job.New <- jobs.NewEvent(job.ID, "start", "Planning project sync (dry run)")
+	} else {
+		job.New <- jobs.NewEvent(job.ID, "start", "Planning project generation (dry run)")
+	}
+
+	plan, err := job.Plan()
+	if err != nil {
+		job.fail("Failed to plan project generation", err)
+		return
+	}
+
+	for _, change := range plan.Changes {
+		job.New <- jobs.NewEvent(job.ID, "plan", change.String())
+	}
+
+	job.New <- jobs.NewEvent(job.ID, "complete", fmt.Sprintf("Dry run complete: %s", plan.Summary()))
+}
@@ -0,0 +1,200 @@
+package onboarding
+
+/*
+This module's tests focus on exercising the `plan.go` module.
+It requires the GitHub Client mock/fixtures implemented in `github_client_test.go`
+*/
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/samsung-cnct/container-technical-on-boarding/app/jobs"
+)
+
+func preparePlanSetup() *SetupScheme {
+	return &SetupScheme{
+		GithubOrganization: "testOrganization",
+		GithubRepository:   "testRepository",
+		Tasks: []TaskEntry{
+			{Title: "test1", Description: "test", Assignee: indirectAssignee{GithubUsername: "test"}},
+			{Title: "test2", Description: "test", Assignee: indirectAssignee{GithubUsername: "test"}},
+		},
+	}
+}
+
+func runJobEvents(job GenerateProject) []jobs.Event {
+	var result []jobs.Event
+	events := make(chan jobs.Event)
+	job.New = events
+
+	go job.Run()
+	for event := range events {
+		result = append(result, event)
+	}
+	return result
+}
+
+func TestPlanEmptyRepository(t *testing.T) {
+	client := prepareGitHubClientTest()
+	setup := preparePlanSetup()
+	repo, _ := client.GetRepository("testowner", "testrepo")
+	job := GenerateProject{Setup: setup}
+
+	plan, err := job.buildPlan(repo, "test", "")
+	if err != nil {
+		t.Fatalf("buildPlan produced an error?! %v", err)
+	}
+
+	// 1 milestone, 1 project, 4 columns, and an issue and card per task.
+	expected := 1 + 1 + len(defaultBoard) + 2*len(setup.Tasks)
+	assertEqual(t, plan.Count(PlanCreate), expected, "Planned creations, actual %d, expected %d")
+	assertEqual(t, len(plan.Changes), expected, "Planned changes, actual %d, expected %d")
+
+	cache := client.Client.(TestGitHubClient).Cache
+	for _, key := range []string{"milestones", "projects", "issues"} {
+		if _, ok := cache[key]; ok {
+			t.Errorf("Planning should not create any %s", key)
+		}
+	}
+}
+
+func TestPlanAfterFullWorkload(t *testing.T) {
+	client := prepareGitHubClientTest()
+	setup := preparePlanSetup()
+	job := GenerateProject{
+		ID:      42,
+		Setup:   setup,
+		AuthEnv: &AuthEnvironment{workflowClient: client},
+	}
+
+	for _, event := range runJobEvents(job) {
+		if event.Type == "error" {
+			t.Fatalf("Full workload failed: %s; %s", event.Text, event.Error)
+		}
+	}
+
+	plan, err := job.Plan()
+	if err != nil {
+		t.Fatalf("Plan produced an error?! %v", err)
+	}
+
+	for _, change := range plan.Changes {
+		if change.Action != PlanUnchanged {
+			t.Errorf("Expected no changes after a full workload, found: %s", change)
+		}
+	}
+
+	data, err := plan.JSON()
+	if err != nil {
+		t.Fatalf("Plan could not be rendered as JSON: %v", err)
+	}
+
+	decoded := Plan{}
+	if err = json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Plan JSON could not be decoded: %v", err)
+	}
+	assertEqual(t, len(decoded.Changes), len(plan.Changes), "Decoded plan changes, actual %d, expected %d")
+	assertEqual(t, decoded.Repository, "testRepository", "Decoded plan repository, actual %v, expected %v")
+}
+
+func TestPlanCardMove(t *testing.T) {
+	client := prepareGitHubClientTest()
+	setup := preparePlanSetup()
+	setup.Columns = []ColumnEntry{{Name: "To Do", Preset: PresetTodo}, {Name: "Doing"}, {Name: "Done", Preset: PresetDone}}
+	job := GenerateProject{
+		ID:      42,
+		Setup:   setup,
+		AuthEnv: &AuthEnvironment{workflowClient: client},
+	}
+	assertNoErrorEvents(t, runJobEvents(job), "Workload failed")
+
+	// The first task now starts further along the board, so its card moves there.
+	setup.Tasks[0].Column = "Doing"
+	plan, err := job.Plan()
+	if err != nil {
+		t.Fatalf("Plan produced an error?! %v", err)
+	}
+	moves := 0
+	for _, change := range plan.Changes {
+		if (change.Resource == "card") && (change.Action == PlanUpdate) {
+			moves++
+			assertEqual(t, change.String(), "update card - test1 (moves from To Do to Doing)", "Planned card move, actual %v, expected %v")
+		}
+	}
+	assertEqual(t, moves, 1, "Planned card moves, actual %d, expected %d")
+
+	assertNoErrorEvents(t, runJobEvents(job), "Repeated workload failed")
+	if plan, err = job.Plan(); err != nil {
+		t.Fatalf("Plan produced an error?! %v", err)
+	}
+	assertEqual(t, plan.Count(PlanUpdate), 0, "Planned updates after the move, actual %d, expected %d")
+}
+
+func TestSyncPlanWithoutMilestone(t *testing.T) {
+	client := prepareGitHubClientTest()
+	setup := preparePlanSetup()
+	setup.Tasks[0].Assignee.GithubUsername = "$username"
+	job := GenerateProject{
+		ID:      42,
+		Setup:   setup,
+		AuthEnv: &AuthEnvironment{workflowClient: client},
+		Hire:    "newhire",
+	}
+	assertNoErrorEvents(t, runJobEvents(job), "Workload failed")
+
+	// The hire's milestone is yet to be created: only the issues assigned to the hire are theirs.
+	setup.Phases = []PhaseEntry{{Name: "week 1", Title: "Week 1 @$username"}}
+	job.Sync = true
+	plan, err := job.Plan()
+	if err != nil {
+		t.Fatalf("Plan produced an error?! %v", err)
+	}
+	actions := make(map[string]string)
+	for _, change := range plan.Changes {
+		if change.Resource == "issue" {
+			actions[change.Title] = change.String()
+		}
+	}
+	assertEqual(t, actions["test1"], "update issue - test1 (milestone differs)", "Planned change of the hire's issue, actual %v, expected %v")
+	assertEqual(t, actions["test2"], "create issue - test2", "Planned change of the owner's issue, actual %v, expected %v")
+
+	assertNoErrorEvents(t, runJobEvents(job), "Sync failed")
+	if plan, err = job.Plan(); err != nil {
+		t.Fatalf("Plan produced an error?! %v", err)
+	}
+	for _, change := range plan.Changes {
+		if change.Action != PlanUnchanged {
+			t.Errorf("Expected no changes after a sync, found: %s", change)
+		}
+	}
+}
+
+func TestDryRunWorkload(t *testing.T) {
+	client := prepareGitHubClientTest()
+	job := GenerateProject{
+		ID:      42,
+		Setup:   preparePlanSetup(),
+		AuthEnv: &AuthEnvironment{workflowClient: client},
+		DryRun:  true,
+	}
+
+	planEvents := 0
+	events := runJobEvents(job)
+	for _, event := range events {
+		switch event.Type {
+		case "error":
+			t.Errorf("Dry run failed: %s; %s", event.Text, event.Error)
+		case "plan":
+			planEvents++
+		}
+	}
+
+	assert(t, planEvents > 0, "Dry run produced no plan events")
+	assertEqual(t, events[len(events)-1].Type, "complete", "Last dry run event type, actual %v, expected %v")
+
+	cache := client.Client.(TestGitHubClient).Cache
+	if _, ok := cache["milestones"]; ok {
+		t.Errorf("Dry run should not create a milestone")
+	}
+}
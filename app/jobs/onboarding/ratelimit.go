@@ -0,0 +1,190 @@
+/*
+This module makes API clients wait out rate limits, and retry transient failures, rather than failing a job halfway.
+*/
+
+package onboarding
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/samsung-cnct/container-technical-on-boarding/app/jobs"
+)
+
+// Defaults of RetryTransport.
+const (
+	defaultMaxRetries       = 4
+	defaultBaseDelay        = 500 * time.Millisecond
+	defaultMaxRateLimitWait = 10 * time.Minute
+)
+
+type (
+	// RetryNotifier is told of each wait before a request is retried, and why; e.g. to keep the user informed.
+	RetryNotifier func(wait time.Duration, reason string)
+
+	// RetryTransport is an http.RoundTripper which waits out rate limits, honoring the Retry-After and
+	// rate limit reset headers of GitHub (X-RateLimit-Reset) and GitLab (RateLimit-Reset), and retries
+	// idempotent requests failing with a network error or a 5xx status, with jittered exponential backoff.
+	RetryTransport struct {
+		Base             http.RoundTripper // http.DefaultTransport when nil
+		MaxRetries       int               // defaultMaxRetries when 0
+		BaseDelay        time.Duration     // defaultBaseDelay when 0; doubled on each retry
+		MaxRateLimitWait time.Duration     // defaultMaxRateLimitWait when 0; longer limits fail the request
+		Notify           RetryNotifier     // optional
+
+		sleep func(time.Duration) // replaced by tests
+		now   func() time.Time    // replaced by tests
+	}
+)
+
+// NewRetryTransport wraps a transport with the default retry settings.
+func NewRetryTransport(base http.RoundTripper, notify RetryNotifier) *RetryTransport {
+	return &RetryTransport{Base: base, Notify: notify}
+}
+
+// jobWaitNotifier reports waits as "progress" events of a job.
+func jobWaitNotifier(id int, events chan<- jobs.Event) RetryNotifier {
+	return func(wait time.Duration, reason string) {
+		events <- jobs.NewEvent(id, "progress", fmt.Sprintf("Waiting %v for %s", wait.Truncate(time.Millisecond), reason))
+	}
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case "GET", "HEAD", "OPTIONS", "PUT", "DELETE":
+		return true
+	}
+	return false
+}
+
+// RoundTrip implements http.RoundTripper.
+func (transport *RetryTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	base := transport.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	maxRetries := transport.MaxRetries
+	if maxRetries == 0 {
+		maxRetries = defaultMaxRetries
+	}
+
+	// Requests with a body can only be retried when it can be replayed.
+	replayable := (request.Body == nil) || (request.GetBody != nil)
+
+	for attempt := 0; ; attempt++ {
+		response, err := base.RoundTrip(request)
+
+		wait, reason, retry := transport.retryAfter(request, response, err, attempt)
+		if !retry || !replayable || (attempt >= maxRetries) {
+			return response, err
+		}
+
+		if response != nil {
+			io.Copy(ioutil.Discard, response.Body)
+			response.Body.Close()
+		}
+
+		if transport.Notify != nil {
+			transport.Notify(wait, reason)
+		}
+		if err = transport.wait(request, wait); err != nil {
+			return nil, err
+		}
+
+		if request.Body != nil {
+			retried := *request
+			if retried.Body, err = request.GetBody(); err != nil {
+				return nil, err
+			}
+			request = &retried
+		}
+	}
+}
+
+// retryAfter decides whether (and how long after) a request should be retried.
+func (transport *RetryTransport) retryAfter(request *http.Request, response *http.Response, err error, attempt int) (time.Duration, string, bool) {
+	if err != nil {
+		return transport.backoff(attempt), fmt.Sprintf("a retry after a network error (%v)", err), isIdempotent(request.Method)
+	}
+
+	// A rate limited request was not processed, so it is safe to retry whatever its method.
+	if wait, limited := transport.rateLimitWait(response); limited {
+		maxWait := transport.MaxRateLimitWait
+		if maxWait == 0 {
+			maxWait = defaultMaxRateLimitWait
+		}
+		return wait, "the rate limit to reset", wait <= maxWait
+	}
+
+	switch response.StatusCode {
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return transport.backoff(attempt), fmt.Sprintf("a retry after HTTP %d", response.StatusCode), isIdempotent(request.Method)
+	}
+	return 0, "", false
+}
+
+// rateLimitWait returns how long a rate limited response asks to wait, if it is rate limited.
+func (transport *RetryTransport) rateLimitWait(response *http.Response) (time.Duration, bool) {
+	header := response.Header
+	exhausted := (header.Get("X-RateLimit-Remaining") == "0") || (header.Get("RateLimit-Remaining") == "0")
+	limited := (response.StatusCode == http.StatusTooManyRequests) ||
+		((response.StatusCode == http.StatusForbidden) && (exhausted || (len(header.Get("Retry-After")) > 0)))
+	if !limited {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(header.Get("Retry-After")); err == nil {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	for _, name := range []string{"X-RateLimit-Reset", "RateLimit-Reset"} {
+		if reset, err := strconv.ParseInt(header.Get(name), 10, 64); err == nil {
+			wait := time.Unix(reset, 0).Sub(transport.clock())
+			if wait < 0 {
+				wait = 0
+			}
+			return wait + time.Second, true // allow for clock skew
+		}
+	}
+
+	return transport.backoff(0), true
+}
+
+// backoff doubles the delay on each attempt, with jitter so that concurrent jobs don't retry in lockstep.
+func (transport *RetryTransport) backoff(attempt int) time.Duration {
+	delay := transport.BaseDelay
+	if delay == 0 {
+		delay = defaultBaseDelay
+	}
+	delay = delay << uint(attempt)
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+func (transport *RetryTransport) clock() time.Time {
+	if transport.now != nil {
+		return transport.now()
+	}
+	return time.Now()
+}
+
+// wait sleeps, unless the request is cancelled first.
+func (transport *RetryTransport) wait(request *http.Request, wait time.Duration) error {
+	if transport.sleep != nil {
+		transport.sleep(wait)
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-request.Context().Done():
+		return request.Context().Err()
+	}
+}
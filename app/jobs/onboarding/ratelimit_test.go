@@ -0,0 +1,156 @@
+package onboarding
+
+/*
+This module's tests focus on exercising the `ratelimit.go` module, against an httptest server.
+*/
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// prepareRetryTest serves the given responses in turn (the last one repeatedly), recording the request bodies.
+func prepareRetryTest(responses ...func(w http.ResponseWriter)) (*httptest.Server, *[]string) {
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, string(data))
+		index := len(bodies) - 1
+		if index >= len(responses) {
+			index = len(responses) - 1
+		}
+		responses[index](w)
+	}))
+	return server, &bodies
+}
+
+func respond(status int, headers ...string) func(w http.ResponseWriter) {
+	return func(w http.ResponseWriter) {
+		for i := 0; i+1 < len(headers); i += 2 {
+			w.Header().Set(headers[i], headers[i+1])
+		}
+		w.WriteHeader(status)
+	}
+}
+
+// prepareRetryTransport records its waits rather than sleeping, on a fixed clock.
+func prepareRetryTransport(now time.Time) (*RetryTransport, *[]time.Duration, *[]string) {
+	var waits []time.Duration
+	var reasons []string
+	transport := &RetryTransport{
+		BaseDelay: 100 * time.Millisecond,
+		Notify: func(wait time.Duration, reason string) {
+			reasons = append(reasons, reason)
+		},
+		sleep: func(wait time.Duration) { waits = append(waits, wait) },
+		now:   func() time.Time { return now },
+	}
+	return transport, &waits, &reasons
+}
+
+func TestRetryGitHubRateLimit(t *testing.T) {
+	now := time.Unix(1500000000, 0)
+	reset := strconv.FormatInt(now.Add(30*time.Second).Unix(), 10)
+	server, bodies := prepareRetryTest(
+		respond(403, "X-RateLimit-Remaining", "0", "X-RateLimit-Reset", reset),
+		respond(200),
+	)
+	defer server.Close()
+
+	transport, waits, reasons := prepareRetryTransport(now)
+	response, err := (&http.Client{Transport: transport}).Get(server.URL)
+	if err != nil {
+		t.Fatalf("Request produced an error?! %v", err)
+	}
+
+	assertEqual(t, response.StatusCode, 200, "Status after waiting, actual %d, expected %d")
+	assertEqual(t, len(*bodies), 2, "Requests, actual %d, expected %d")
+	assertEqual(t, len(*waits), 1, "Waits, actual %d, expected %d")
+	assertEqual(t, (*waits)[0], 31*time.Second, "Wait until the reset, actual %v, expected %v")
+	assertEqual(t, (*reasons)[0], "the rate limit to reset", "Wait reason, actual %v, expected %v")
+}
+
+func TestRetryAfterReplaysBody(t *testing.T) {
+	server, bodies := prepareRetryTest(
+		respond(403, "Retry-After", "5"), // GitHub's abuse detection
+		respond(201),
+	)
+	defer server.Close()
+
+	transport, waits, _ := prepareRetryTransport(time.Now())
+	response, err := (&http.Client{Transport: transport}).Post(server.URL, "application/json", strings.NewReader(`{"title":"test"}`))
+	if err != nil {
+		t.Fatalf("Request produced an error?! %v", err)
+	}
+
+	assertEqual(t, response.StatusCode, 201, "Status after waiting, actual %d, expected %d")
+	assertEqual(t, (*waits)[0], 5*time.Second, "Retry-After wait, actual %v, expected %v")
+	assertEqual(t, len(*bodies), 2, "Requests, actual %d, expected %d")
+	assertEqual(t, (*bodies)[1], `{"title":"test"}`, "Replayed body, actual %v, expected %v")
+}
+
+func TestRetryGitLabRateLimit(t *testing.T) {
+	now := time.Unix(1500000000, 0)
+	reset := strconv.FormatInt(now.Add(9*time.Second).Unix(), 10)
+	server, _ := prepareRetryTest(respond(429, "RateLimit-Reset", reset), respond(200))
+	defer server.Close()
+
+	transport, waits, _ := prepareRetryTransport(now)
+	response, err := (&http.Client{Transport: transport}).Get(server.URL)
+	if err != nil {
+		t.Fatalf("Request produced an error?! %v", err)
+	}
+
+	assertEqual(t, response.StatusCode, 200, "Status after waiting, actual %d, expected %d")
+	assertEqual(t, (*waits)[0], 10*time.Second, "Wait until the reset, actual %v, expected %v")
+}
+
+func TestRetryLongRateLimitFails(t *testing.T) {
+	now := time.Unix(1500000000, 0)
+	reset := strconv.FormatInt(now.Add(time.Hour).Unix(), 10)
+	server, bodies := prepareRetryTest(respond(403, "X-RateLimit-Remaining", "0", "X-RateLimit-Reset", reset))
+	defer server.Close()
+
+	transport, waits, _ := prepareRetryTransport(now)
+	response, err := (&http.Client{Transport: transport}).Get(server.URL)
+	if err != nil {
+		t.Fatalf("Request produced an error?! %v", err)
+	}
+
+	assertEqual(t, response.StatusCode, 403, "Status of a long rate limit, actual %d, expected %d")
+	assertEqual(t, len(*bodies), 1, "Requests, actual %d, expected %d")
+	assertEqual(t, len(*waits), 0, "Waits, actual %d, expected %d")
+}
+
+func TestRetryServerErrorsWithBackoff(t *testing.T) {
+	server, bodies := prepareRetryTest(respond(502))
+	defer server.Close()
+
+	transport, waits, _ := prepareRetryTransport(time.Now())
+	transport.MaxRetries = 3
+	response, err := (&http.Client{Transport: transport}).Get(server.URL)
+	if err != nil {
+		t.Fatalf("Request produced an error?! %v", err)
+	}
+
+	assertEqual(t, response.StatusCode, 502, "Status after retries, actual %d, expected %d")
+	assertEqual(t, len(*bodies), 4, "Requests, actual %d, expected %d")
+	for attempt, wait := range *waits {
+		delay := transport.BaseDelay << uint(attempt)
+		assert(t, (wait >= delay/2) && (wait <= delay), "Backoff of attempt %d out of bounds: %v", attempt, wait)
+	}
+
+	// Creating is not idempotent; a 5xx may have been processed, so it is not retried.
+	*bodies = nil
+	response, err = (&http.Client{Transport: transport}).Post(server.URL, "application/json", strings.NewReader("{}"))
+	if err != nil {
+		t.Fatalf("Request produced an error?! %v", err)
+	}
+	assertEqual(t, response.StatusCode, 502, "Status of a failed POST, actual %d, expected %d")
+	assertEqual(t, len(*bodies), 1, "Requests, actual %d, expected %d")
+}
@@ -0,0 +1,129 @@
+/*
+This module reloads the setup scheme when one of its files changes (or on demand, e.g. on SIGHUP), so that the task
+list can be edited without restarting the server. A new version replaces the current one only once it is valid; jobs
+keep the scheme they started with, as a loaded scheme is never modified.
+*/
+
+package onboarding
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SchemeWatcher holds the current setup scheme of a file, reloading it when the file (or one it includes) changes.
+type SchemeWatcher struct {
+	filename string
+	environ  *map[string]string
+	current  atomic.Value // *SetupScheme
+
+	mutex       sync.Mutex // serializes reloads
+	sources     []string   // the files (and directories) watched
+	fingerprint string     // of the sources, when last (re)loaded
+}
+
+// schemeVersion identifies the contents of a setup scheme's files.
+func schemeVersion(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+// NewSchemeWatcher loads the setup scheme of a file, which must be valid.
+func NewSchemeWatcher(filename string, environ *map[string]string) (*SchemeWatcher, error) {
+	watcher := SchemeWatcher{filename: filename, environ: environ}
+
+	setup := SetupScheme{}
+	if err := setup.load(filename, environ); err != nil {
+		return nil, err
+	}
+	watcher.sources = setup.sources
+	watcher.fingerprint = fingerprint(setup.sources)
+	watcher.current.Store(&setup)
+	return &watcher, nil
+}
+
+// Current returns the latest valid setup scheme. Callers should keep the returned scheme for the whole of a request
+// or job, rather than calling Current again.
+func (watcher *SchemeWatcher) Current() *SetupScheme {
+	return watcher.current.Load().(*SetupScheme)
+}
+
+// fingerprint summarizes the modification times and sizes of a scheme's sources, and of their directories (which
+// change as files are added to them, or renamed).
+func fingerprint(sources []string) string {
+	var stats []string
+	for _, source := range sources {
+		for _, path := range []string{source, filepath.Dir(source)} {
+			if info, err := os.Stat(path); err == nil {
+				stats = append(stats, fmt.Sprintf("%s %d %d", path, info.ModTime().UnixNano(), info.Size()))
+			}
+		}
+	}
+	return strings.Join(stats, "\n")
+}
+
+// Changed indicates whether one of the scheme's files was modified since it was last (re)loaded.
+func (watcher *SchemeWatcher) Changed() bool {
+	watcher.mutex.Lock()
+	defer watcher.mutex.Unlock()
+
+	return fingerprint(watcher.sources) != watcher.fingerprint
+}
+
+// Reload loads the scheme again, replacing the current one when its files' contents changed and are valid.
+// It indicates whether the scheme was replaced; when the new version is invalid, the current one is kept and the
+// problems are returned.
+func (watcher *SchemeWatcher) Reload() (bool, error) {
+	watcher.mutex.Lock()
+	defer watcher.mutex.Unlock()
+
+	// Taken before loading, so that a change made meanwhile is picked up by the next check; the sources read (even
+	// by an invalid version) are only fingerprinted afterwards when they differ, e.g. when an include was added.
+	watcher.fingerprint = fingerprint(watcher.sources)
+
+	setup := SetupScheme{}
+	err := setup.load(watcher.filename, watcher.environ)
+	if strings.Join(setup.sources, "\n") != strings.Join(watcher.sources, "\n") {
+		watcher.sources = setup.sources
+		watcher.fingerprint = fingerprint(setup.sources)
+	}
+	if err != nil {
+		return false, err
+	}
+	if setup.Version == watcher.Current().Version {
+		return false, nil
+	}
+	watcher.current.Store(&setup)
+	return true, nil
+}
+
+// Watch checks the files for changes at every interval (unless 0), and reloads the scheme on those and on every signal
+// received from reload, until stop is closed. Each reload is reported with its outcome.
+func (watcher *SchemeWatcher) Watch(interval time.Duration, reload <-chan os.Signal, stop <-chan struct{}, report func(replaced bool, err error)) {
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-tick:
+			if !watcher.Changed() {
+				continue
+			}
+		case <-reload:
+		}
+		report(watcher.Reload())
+	}
+}
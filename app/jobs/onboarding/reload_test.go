@@ -0,0 +1,122 @@
+package onboarding
+
+/*
+This module's tests focus on exercising the `reload.go` module.
+*/
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSchemeWatcherReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "onboarding")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "tasks.yaml")
+	ioutil.WriteFile(filename, []byte("tasks:\n    - title: one\n"), 0600)
+
+	watcher, err := NewSchemeWatcher(filename, &map[string]string{})
+	if err != nil {
+		t.Fatalf("NewSchemeWatcher produced an error?! %v", err)
+	}
+	started := watcher.Current()
+	assertEqual(t, len(started.Version), 12, "Version length, actual %d, expected %d")
+	assert(t, !watcher.Changed(), "An unmodified file should not be reported as changed")
+
+	replaced, err := watcher.Reload()
+	assert(t, !replaced && (err == nil), "Reloading an unmodified file, actual %v %v, expected neither a replacement nor an error", replaced, err)
+
+	ioutil.WriteFile(filename, []byte("tasks:\n    - title: one\n    - title: one\n"), 0600)
+	replaced, err = watcher.Reload()
+	assert(t, !replaced && (err != nil), "Reloading an invalid file, actual %v %v, expected an error", replaced, err)
+	assert(t, watcher.Current() == started, "An invalid version should not replace the current one")
+
+	ioutil.WriteFile(filename, []byte("tasks:\n    - title: one\n    - title: two\n"), 0600)
+	replaced, err = watcher.Reload()
+	assert(t, replaced && (err == nil), "Reloading a valid file, actual %v %v, expected a replacement", replaced, err)
+
+	current := watcher.Current()
+	assert(t, current.Version != started.Version, "Versions should differ, both are %s", current.Version)
+	assertEqual(t, len(current.Tasks), 2, "Tasks after reloading, actual %d, expected %d")
+	assertEqual(t, len(started.Tasks), 1, "Tasks of the earlier snapshot, actual %d, expected %d")
+
+	_, err = NewSchemeWatcher(filepath.Join(dir, "missing.yaml"), &map[string]string{})
+	assert(t, err != nil, "Expected an error for a missing file")
+}
+
+func TestSchemeWatcherCalendar(t *testing.T) {
+	dir, err := ioutil.TempDir("", "onboarding")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "tasks.yaml")
+	calendar := filepath.Join(dir, "holidays.ics")
+	ioutil.WriteFile(filename, []byte("schedule:\n    calendar: holidays.ics\ntasks:\n    - title: one\n"), 0600)
+	ioutil.WriteFile(calendar, []byte(testICalendarFixture), 0600)
+
+	watcher, err := NewSchemeWatcher(filename, &map[string]string{})
+	if err != nil {
+		t.Fatalf("NewSchemeWatcher produced an error?! %v", err)
+	}
+	started := watcher.Current()
+
+	// A different size, as the modification time may not tell writes this close apart.
+	ioutil.WriteFile(calendar, []byte(strings.Replace(testICalendarFixture, "Independence Day", "Fourth of July", 1)), 0600)
+	assert(t, watcher.Changed(), "A modified holiday calendar should be reported as changed")
+	replaced, err := watcher.Reload()
+	assert(t, replaced && (err == nil), "Reloading a modified holiday calendar, actual %v %v, expected a replacement", replaced, err)
+	assert(t, watcher.Current().Version != started.Version, "Versions should differ, both are %s", started.Version)
+
+	os.Remove(calendar)
+	assert(t, watcher.Changed(), "A removed holiday calendar should be reported as changed")
+	replaced, err = watcher.Reload()
+	assert(t, !replaced && (err != nil), "Reloading without the holiday calendar, actual %v %v, expected an error", replaced, err)
+}
+
+func TestSchemeWatcherWatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "onboarding")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "tasks.yaml")
+	ioutil.WriteFile(filename, []byte("tasks:\n    - title: one\n"), 0600)
+
+	watcher, err := NewSchemeWatcher(filename, &map[string]string{})
+	if err != nil {
+		t.Fatalf("NewSchemeWatcher produced an error?! %v", err)
+	}
+
+	reload := make(chan os.Signal)
+	stop := make(chan struct{})
+	reports := make(chan bool)
+	done := make(chan struct{})
+	go func() {
+		watcher.Watch(0, reload, stop, func(replaced bool, err error) { reports <- replaced })
+		close(done)
+	}()
+
+	ioutil.WriteFile(filename, []byte("tasks:\n    - title: two\n"), 0600)
+	reload <- os.Interrupt
+	select {
+	case replaced := <-reports:
+		assert(t, replaced, "A signal should reload the changed file")
+	case <-time.After(5 * time.Second):
+		t.Fatalf("No reload after a signal")
+	}
+	assertEqual(t, watcher.Current().Tasks[0].Title, "two", "Task after reloading, actual %s, expected %s")
+
+	close(stop)
+	<-done
+}
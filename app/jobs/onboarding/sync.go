@@ -0,0 +1,215 @@
+/*
+This module brings issues generated by an earlier run up to date with the task template, for GenerateProject's Sync mode.
+*/
+
+package onboarding
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+// checkboxPattern matches a Markdown task list item, e.g. "- [x] Slack"; the groups are the prefix, the mark, and the text.
+var checkboxPattern = regexp.MustCompile(`^(\s*[-*+]\s+\[)([ xX])\]\s+(.*?)\s*$`)
+
+// dueNotePattern matches the due date line of an issue body, as written by dueNote.
+var dueNotePattern = regexp.MustCompile(`(?m)^Due by [^\n]*$`)
+
+func dueNote(due time.Time) string {
+	return fmt.Sprintf("Due by %s", due.Format("Monday, January 2, 2006"))
+}
+
+// preserveDueNote keeps the due date of an existing body in a rendered one, e.g. when syncing without knowing
+// the hire's start date. Bodies without a due date are left as they are.
+func preserveDueNote(rendered string, existing string) string {
+	note := dueNotePattern.FindString(normalizeBody(existing))
+	if len(note) == 0 {
+		return rendered
+	}
+	return dueNotePattern.ReplaceAllLiteralString(rendered, note)
+}
+
+// normalizeBody ignores the differences in line endings and surrounding whitespace GitHub introduces when issues are edited.
+func normalizeBody(body string) string {
+	return strings.TrimSpace(strings.Replace(body, "\r\n", "\n", -1))
+}
+
+// preserveCheckboxes ticks the task list items of a rendered body which are already ticked in the existing body,
+// matching items by their text, so that syncing an issue does not undo a hire's progress.
+func preserveCheckboxes(rendered string, existing string) string {
+	ticked := make(map[string]int)
+	for _, line := range strings.Split(normalizeBody(existing), "\n") {
+		if match := checkboxPattern.FindStringSubmatch(line); (match != nil) && (match[2] != " ") {
+			ticked[match[3]]++
+		}
+	}
+
+	lines := strings.Split(rendered, "\n")
+	for index, line := range lines {
+		match := checkboxPattern.FindStringSubmatch(line)
+		if (match != nil) && (match[2] == " ") && (ticked[match[3]] > 0) {
+			ticked[match[3]]--
+			lines[index] = match[1] + "x" + line[len(match[1])+1:]
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func issueAssignees(issue *github.Issue) []string {
+	var logins []string
+	for _, user := range issue.Assignees {
+		logins = append(logins, strings.ToLower(user.GetLogin()))
+	}
+	if (len(logins) == 0) && (issue.Assignee != nil) {
+		logins = append(logins, strings.ToLower(issue.Assignee.GetLogin()))
+	}
+	sort.Strings(logins)
+	return logins
+}
+
+// labelEdit returns the edit adding the labels an issue lacks (matching their names regardless of case, as GitHub
+// does) while keeping its others, as an edit replaces the labels of a GitHub issue; nil when it has them all.
+func labelEdit(issue *github.Issue, labels []string) *github.IssueRequest {
+	present := make(map[string]bool)
+	names := []string{}
+	for _, label := range issue.Labels {
+		present[strings.ToLower(label.GetName())] = true
+		names = append(names, label.GetName())
+	}
+
+	missing := false
+	for _, name := range labels {
+		if !present[strings.ToLower(name)] {
+			present[strings.ToLower(name)] = true
+			names = append(names, name)
+			missing = true
+		}
+	}
+	if !missing {
+		return nil
+	}
+	return &github.IssueRequest{Labels: &names}
+}
+
+// issueDrift compares an existing issue with the rendered task, returning the names of the drifted fields
+// ("body", "assignee", "milestone" and "labels"), and the edit which would bring the issue up to date.
+// With keepDue, the issue's due date is not considered drifted.
+func issueDrift(issue *github.Issue, assignees []string, body string, milestone int, labels []string, keepDue bool) ([]string, *github.IssueRequest) {
+	var drifted []string
+	edit := github.IssueRequest{}
+
+	if keepDue {
+		body = preserveDueNote(body, issue.GetBody())
+	}
+	merged := preserveCheckboxes(body, issue.GetBody())
+	if normalizeBody(merged) != normalizeBody(issue.GetBody()) {
+		drifted = append(drifted, "body")
+		edit.Body = &merged
+	}
+
+	wanted := []string{}
+	for _, assignee := range assignees {
+		wanted = append(wanted, strings.ToLower(assignee))
+	}
+	sort.Strings(wanted)
+	if strings.Join(issueAssignees(issue), ",") != strings.Join(wanted, ",") {
+		drifted = append(drifted, "assignee")
+		edit.Assignees = &assignees
+	}
+
+	if (milestone > 0) && ((issue.Milestone == nil) || (issue.Milestone.GetNumber() != milestone)) {
+		drifted = append(drifted, "milestone")
+		edit.Milestone = &milestone
+	}
+
+	if labeled := labelEdit(issue, labels); labeled != nil {
+		drifted = append(drifted, "labels")
+		edit.Labels = labeled.Labels
+	}
+
+	return drifted, &edit
+}
+
+// findHireMilestones looks up the numbers of a hire's milestones, for every phase of the setup (including those which
+// no task belongs to any more, where issues may have been left), in the phases' order.
+func findHireMilestones(repo IRepositoryAccess, setup *SetupScheme, username string) ([]int, error) {
+	var numbers []int
+	for _, phase := range setup.PhaseList() {
+		title := phase.MilestoneTitle(username)
+		milestone, err := repo.GetMilestoneByTitle(&title)
+		if err != nil {
+			return nil, err
+		}
+		if milestone != nil {
+			numbers = append(numbers, milestone.GetNumber())
+		}
+	}
+	return numbers, nil
+}
+
+// findTaskIssue locates the open issue generated for a task of a hire's onboarding: by title within the task's
+// milestone, or another of the hire's milestones, whatever its assignees; or failing that (e.g. when it was moved out
+// of them) by title among the issues assigned to the hire. Other hires' issues for the same task are never matched,
+// so it is nil when the hire has none.
+func findTaskIssue(repo IRepositoryAccess, hire string, title string, milestone int, milestones []int) (*github.Issue, error) {
+	searched := []int{}
+	if milestone > 0 {
+		searched = append(searched, milestone)
+	}
+	for _, other := range milestones {
+		if other != milestone {
+			searched = append(searched, other)
+		}
+	}
+
+	for _, number := range searched {
+		request := newIssueRequest(nil, &title, nil, number, nil)
+		issues, err := repo.GetIssuesByRequest(&request)
+		if err != nil {
+			return nil, err
+		}
+		if len(issues) > 0 {
+			return issues[0], nil
+		}
+	}
+
+	if len(hire) == 0 {
+		return nil, nil
+	}
+
+	request := newIssueRequest([]string{hire}, &title, nil, 0, nil)
+	issues, err := repo.GetIssuesByRequest(&request)
+	if (err != nil) || (len(issues) == 0) {
+		return nil, err
+	}
+	return issues[0], nil
+}
+
+// syncIssue creates the issue for a task of a hire's onboarding when there is none (see findTaskIssue, which searches
+// the hire's milestones), or else edits the existing issue where it has drifted from the task (see issueDrift). It
+// returns the issue, and the names of the fields which were edited.
+func syncIssue(repo IRepositoryAccess, hire string, milestones []int, assignees []string, title string, body string, milestone int, labels []string, keepDue bool) (*github.Issue, []string, error) {
+	issue, err := findTaskIssue(repo, hire, title, milestone, milestones)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if issue == nil {
+		issue, err = repo.CreateOrUpdateIssue(assignees, &title, &body, milestone, labels)
+		return issue, nil, err
+	}
+
+	drifted, edit := issueDrift(issue, assignees, body, milestone, labels, keepDue)
+	if len(drifted) == 0 {
+		return issue, nil, nil
+	}
+
+	issue, err = repo.EditIssue(issue, edit)
+	return issue, drifted, err
+}
@@ -0,0 +1,154 @@
+package onboarding
+
+/*
+This module's tests focus on exercising the `sync.go` module.
+It requires the GitHub Client mock/fixtures implemented in `github_client_test.go`
+*/
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/github"
+)
+
+func TestPreserveCheckboxes(t *testing.T) {
+	cases := []struct {
+		rendered string
+		existing string
+		expected string
+	}{
+		{"- [ ] Slack\n- [ ] Email", "- [x] Slack\n- [ ] Email", "- [x] Slack\n- [ ] Email"},
+		{"- [ ] Slack\n- [ ] Calendar", "- [X] Slack\r\n- [x] Email\r\n", "- [x] Slack\n- [ ] Calendar"},
+		{"  * [ ] nested", "* [x] nested", "  * [x] nested"},
+		{"- [ ] twice\n- [ ] twice", "- [x] twice\n- [ ] twice", "- [x] twice\n- [ ] twice"},
+		{"plain text", "- [x] plain text", "plain text"},
+	}
+
+	for _, c := range cases {
+		result := preserveCheckboxes(c.rendered, c.existing)
+		assertEqual(t, result, c.expected, "Preserved checkboxes, actual %q, expected %q")
+	}
+}
+
+func TestIssueDrift(t *testing.T) {
+	issue := github.Issue{
+		Body:      github.String("- [x] Slack\r\n"),
+		Assignees: []*github.User{{Login: github.String("Test")}},
+		Milestone: &github.Milestone{Number: github.Int(1)},
+		Labels:    []github.Label{{Name: github.String("onboarding")}},
+	}
+
+	drifted, _ := issueDrift(&issue, []string{"test"}, "- [ ] Slack", 1, []string{"Onboarding"}, false)
+	assertEqual(t, len(drifted), 0, "Drifted fields of an up to date issue, actual %d, expected %d")
+
+	drifted, edit := issueDrift(&issue, []string{"newhire"}, "- [ ] Slack\n- [ ] Email", 2, []string{"onboarding", "security"}, false)
+	assertEqual(t, strings.Join(drifted, ","), "body,assignee,milestone,labels", "Drifted fields, actual %v, expected %v")
+	assertEqual(t, strings.Join(edit.GetLabels(), ","), "onboarding,security", "Edited labels, actual %v, expected %v")
+	assertEqual(t, edit.GetBody(), "- [x] Slack\n- [ ] Email", "Edited body, actual %q, expected %q")
+	assertEqual(t, strings.Join(edit.GetAssignees(), ","), "newhire", "Edited assignees, actual %v, expected %v")
+	assertEqual(t, edit.GetMilestone(), 2, "Edited milestone, actual %v, expected %v")
+}
+
+func TestSyncWorkload(t *testing.T) {
+	client := prepareGitHubClientTest()
+	setup := preparePlanSetup()
+	setup.Tasks[0].Description = "- [ ] Slack\n- [ ] Email\n"
+
+	job := GenerateProject{
+		ID:      42,
+		Setup:   setup,
+		AuthEnv: &AuthEnvironment{workflowClient: client},
+	}
+	assertNoErrorEvents(t, runJobEvents(job), "Workload failed")
+
+	cache := client.Client.(TestGitHubClient).Cache
+	issues, _ := cache["issues"].([]*github.Issue)
+	ticked := "- [x] Slack\r\n- [ ] Email"
+	issues[0].Body = &ticked
+
+	// Without sync, template changes never reach existing issues.
+	setup.Tasks[0].Description = "- [ ] Slack\n- [ ] Email\n- [ ] Calendar\n"
+	assertNoErrorEvents(t, runJobEvents(job), "Workload failed")
+	issues, _ = cache["issues"].([]*github.Issue)
+	assertEqual(t, len(issues), 2, "Issues after a repeated workload, actual %d, expected %d")
+	assertEqual(t, issues[0].GetBody(), ticked, "Body without sync, actual %q, expected %q")
+
+	setup.Tasks[1].Assignee.GithubUsername = "newhire"
+	job.Sync = true
+	plan, err := job.Plan()
+	if err != nil {
+		t.Fatalf("Plan produced an error?! %v", err)
+	}
+	updates := 0
+	for _, change := range plan.Changes {
+		if change.Action == PlanUpdate {
+			updates++
+			assertEqual(t, change.Resource, "issue", "Updated resource, actual %v, expected %v")
+		}
+	}
+	assertEqual(t, updates, 2, "Planned issue updates, actual %d, expected %d")
+
+	events := runJobEvents(job)
+	assertNoErrorEvents(t, events, "Sync failed")
+	issues, _ = cache["issues"].([]*github.Issue)
+	assertEqual(t, len(issues), 2, "Issues after a sync, actual %d, expected %d")
+	assertEqual(t, issues[0].GetBody(), "- [x] Slack\n- [ ] Email\n- [ ] Calendar\n", "Synced body, actual %q, expected %q")
+	assertEqual(t, issues[1].Assignees[0].GetLogin(), "newhire", "Synced assignee, actual %v, expected %v")
+
+	synced := 0
+	for _, event := range events {
+		if strings.HasPrefix(event.Text, "Updated Issue - ") {
+			synced++
+		}
+	}
+	assertEqual(t, synced, 2, "Updated issue events, actual %d, expected %d")
+
+	plan, err = job.Plan()
+	if err != nil {
+		t.Fatalf("Plan produced an error?! %v", err)
+	}
+	for _, change := range plan.Changes {
+		if change.Action != PlanUnchanged {
+			t.Errorf("Expected no changes after a sync, found: %s", change)
+		}
+	}
+}
+
+func TestSyncSeparateHires(t *testing.T) {
+	client := prepareGitHubClientTest()
+	setup := preparePlanSetup()
+	setup.Tasks[0].Assignee.GithubUsername = "$username"
+
+	job := GenerateProject{
+		ID:      42,
+		Setup:   setup,
+		AuthEnv: &AuthEnvironment{workflowClient: client},
+		Hire:    "alice",
+	}
+	assertNoErrorEvents(t, runJobEvents(job), "Workload for alice failed")
+
+	repo, _ := client.GetRepository("testOrganization", "testRepository")
+	alice, _ := repo.GetMilestoneByTitle(github.String("Welcome @alice!"))
+
+	// The second task is assigned to the same owner for both hires; bob's sync leaves alice's issues alone.
+	job.Hire = "bob"
+	job.Sync = true
+	plan, err := job.Plan()
+	if err != nil {
+		t.Fatalf("Plan produced an error?! %v", err)
+	}
+	for _, change := range plan.Changes {
+		if change.Resource == "issue" {
+			assertEqual(t, change.Action, PlanCreate, "Planned action for bob's issue, actual %v, expected %v")
+		}
+	}
+
+	assertNoErrorEvents(t, runJobEvents(job), "Sync for bob failed")
+	issues, _ := client.Client.(TestGitHubClient).Cache["issues"].([]*github.Issue)
+	assertEqual(t, len(issues), 4, "Issues of both hires, actual %d, expected %d")
+	for _, issue := range issues[:2] {
+		assertEqual(t, issue.Milestone.GetNumber(), alice.GetNumber(), "Milestone of alice's issue, actual %v, expected %v")
+	}
+	assertEqual(t, issues[0].Assignees[0].GetLogin(), "alice", "Assignee of alice's issue, actual %v, expected %v")
+}
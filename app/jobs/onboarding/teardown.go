@@ -0,0 +1,180 @@
+/*
+This module implements the reverse of GenerateProject, tearing down a new hire's onboarding project;
+e.g. when they leave, or to start over after a botched run.
+*/
+
+package onboarding
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/go-github/github"
+	"github.com/samsung-cnct/container-technical-on-boarding/app/jobs"
+)
+
+// TeardownProject represents a Job to be executed by the revel job module, reversing GenerateProject.
+// It removes the cards of (and closes) the open issues in the milestones of the onboarding's phases (by default,
+// the "Welcome @user!" milestone), deletes the project, and closes the milestones. Closed milestones keep their
+// title, so when Purge is set the milestones are deleted instead, allowing the onboarding to be generated again.
+// Username selects whose onboarding is torn down, defaulting to the authenticated user.
+// The checkpoint of an unfinished GenerateProject run (if any, in Checkpoints) is deleted too, and once torn down,
+// the hire's buddy assignment (if any, in Buddies) is released.
+// Cancelling Context (when set) stops the teardown between issues and milestones, interrupting any request in
+// progress; the teardown reports a "cancelled" event, and running it again finishes it.
+type TeardownProject struct {
+	ID          int
+	Context     context.Context
+	Setup       *SetupScheme
+	AuthEnv     *AuthEnvironment
+	New         chan<- jobs.Event
+	Username    string
+	Purge       bool
+	Checkpoints CheckpointStore
+	Buddies     BuddyStore
+}
+
+// cancelled indicates whether the job's Context is done, reporting the cancellation when it is.
+func (job TeardownProject) cancelled() bool {
+	if (job.Context == nil) || (job.Context.Err() == nil) {
+		return false
+	}
+	job.New <- jobs.NewEvent(job.ID, "cancelled", "Cancelled the teardown; it can be run again to finish it")
+	return true
+}
+
+// fail reports an error of the job, or its cancellation when the error is due to it (e.g. an interrupted request).
+func (job TeardownProject) fail(text string, err error) {
+	if !job.cancelled() {
+		job.New <- jobs.NewError(job.ID, text, err.Error())
+	}
+}
+
+// Run implements the required cron.Job interface for revel job execution
+func (job TeardownProject) Run() {
+	setup := job.Setup
+	auth := job.AuthEnv
+
+	defer close(job.New)
+
+	username := job.Username
+	if len(username) == 0 {
+		username = auth.Username()
+	}
+	job.New <- jobs.NewEvent(job.ID, "start", fmt.Sprintf("Starting teardown of the onboarding of @%s", username))
+
+	client, err := auth.newWorkflowClient(job.Context, jobWaitNotifier(job.ID, job.New))
+	if err != nil {
+		job.fail("Failed to connect", err)
+		return
+	}
+
+	repo, err := client.GetRepository(setup.GithubOrganization, setup.GithubRepository)
+	if err != nil {
+		job.fail(fmt.Sprintf("Failed to repository - %s", setup.GithubRepository), err)
+		return
+	}
+
+	title := welcomeTitle(username)
+
+	// Resuming would otherwise reuse the resources being torn down.
+	if job.Checkpoints != nil {
+		key := CheckpointKey(setup.GithubOrganization, setup.GithubRepository, username)
+		if err = job.Checkpoints.DeleteCheckpoint(key); err != nil {
+			job.fail(fmt.Sprintf("Failed to delete checkpoint - %s", key), err)
+			return
+		}
+	}
+
+	var milestones []*github.Milestone
+	for _, phase := range setup.PhaseList() {
+		milestoneTitle := phase.MilestoneTitle(username)
+		milestone, err := repo.GetMilestoneByTitle(&milestoneTitle)
+		if err != nil {
+			job.fail(fmt.Sprintf("Failed to fetch milestone - %s", milestoneTitle), err)
+			return
+		}
+		if milestone != nil {
+			milestones = append(milestones, milestone)
+		}
+	}
+
+	project, err := repo.GetProjectByTitle(&title)
+	if err != nil {
+		job.fail(fmt.Sprintf("Failed to fetch project - %s", title), err)
+		return
+	}
+
+	if (len(milestones) == 0) && (project == nil) {
+		job.New <- jobs.NewEvent(job.ID, "complete", fmt.Sprintf("Nothing to tear down; there is no open milestone or project named %s", title))
+		return
+	}
+
+	closedIssues := 0
+
+	for _, milestone := range milestones {
+		request := newIssueRequest(nil, nil, nil, milestone.GetNumber(), nil)
+		issues, err := repo.GetIssuesByRequest(&request)
+		if err != nil {
+			job.fail(fmt.Sprintf("Failed to fetch the issues of milestone - %s", milestone.GetTitle()), err)
+			return
+		}
+
+		for _, issue := range issues {
+			if job.cancelled() {
+				return
+			}
+			if project != nil {
+				removed, err := repo.DeleteCardForIssue(project, issue)
+				if err != nil {
+					job.fail(fmt.Sprintf("Failed to remove card - %s", issue.GetTitle()), err)
+					return
+				}
+				if removed {
+					job.New <- jobs.NewEvent(job.ID, "progress", fmt.Sprintf("Removed Card - %s", issue.GetTitle()))
+				}
+			}
+
+			job.New <- jobs.NewEvent(job.ID, "progress", fmt.Sprintf("Closing Issue - #%d %s", issue.GetNumber(), issue.GetTitle()))
+			if _, err = repo.CloseIssue(issue); err != nil {
+				job.fail(fmt.Sprintf("Failed to close issue - %s", issue.GetTitle()), err)
+				return
+			}
+			closedIssues++
+		}
+	}
+
+	if job.cancelled() {
+		return
+	}
+	if project != nil {
+		job.New <- jobs.NewEvent(job.ID, "progress", fmt.Sprintf("Deleting Project - %s", title))
+		if err = repo.DeleteProject(project); err != nil {
+			job.fail(fmt.Sprintf("Failed to delete project - %s", title), err)
+			return
+		}
+	}
+
+	for _, milestone := range milestones {
+		if job.cancelled() {
+			return
+		}
+		milestoneTitle := milestone.GetTitle()
+		if job.Purge {
+			job.New <- jobs.NewEvent(job.ID, "progress", fmt.Sprintf("Deleting Milestone - %s", milestoneTitle))
+			err = repo.DeleteMilestone(milestone)
+		} else {
+			job.New <- jobs.NewEvent(job.ID, "progress", fmt.Sprintf("Closing Milestone - %s", milestoneTitle))
+			_, err = repo.CloseMilestone(milestone)
+		}
+		if err != nil {
+			job.fail(fmt.Sprintf("Failed to tear down milestone - %s", milestoneTitle), err)
+			return
+		}
+	}
+
+	releaseBuddy(job.Buddies, CheckpointKey(setup.GithubOrganization, setup.GithubRepository, username))
+
+	completed := fmt.Sprintf("Successfully tore down the onboarding of @%s; %d issues closed", username, closedIssues)
+	job.New <- jobs.NewEvent(job.ID, "complete", completed)
+}
@@ -0,0 +1,129 @@
+package onboarding
+
+/*
+This module's tests focus on exercising the `teardown.go` module.
+It requires the GitHub Client mock/fixtures implemented in `github_client_test.go`
+*/
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/github"
+	"github.com/samsung-cnct/container-technical-on-boarding/app/jobs"
+)
+
+func runTeardownEvents(job TeardownProject) []jobs.Event {
+	var result []jobs.Event
+	events := make(chan jobs.Event)
+	job.New = events
+
+	go job.Run()
+	for event := range events {
+		result = append(result, event)
+	}
+	return result
+}
+
+func assertNoErrorEvents(t *testing.T, events []jobs.Event, message string) {
+	for _, event := range events {
+		if event.Type == "error" {
+			t.Fatalf("%s: %s; %s", message, event.Text, event.Error)
+		}
+	}
+}
+
+func TestTeardownWorkload(t *testing.T) {
+	client := prepareGitHubClientTest()
+	setup := preparePlanSetup()
+	auth := &AuthEnvironment{workflowClient: client}
+
+	assertNoErrorEvents(t, runJobEvents(GenerateProject{ID: 42, Setup: setup, AuthEnv: auth}), "Workload failed")
+
+	teardown := TeardownProject{ID: 42, Setup: setup, AuthEnv: auth}
+	events := runTeardownEvents(teardown)
+	assertNoErrorEvents(t, events, "Teardown failed")
+	assertEqual(t, events[len(events)-1].Type, "complete", "Last teardown event type, actual %v, expected %v")
+
+	cache := client.Client.(TestGitHubClient).Cache
+
+	issues, _ := cache["issues"].([]*github.Issue)
+	for _, issue := range issues {
+		assertEqual(t, issue.GetState(), "closed", "Issue state after teardown, actual %v, expected %v")
+	}
+
+	projects, _ := cache["projects"].([]*github.Project)
+	assertEqual(t, len(projects), 0, "Projects after teardown, actual %d, expected %d")
+
+	cards, _ := cache["cards/column/1"].([]*github.ProjectCard)
+	assertEqual(t, len(cards), 0, "Cards after teardown, actual %d, expected %d")
+
+	milestones, _ := cache["milestones"].([]*github.Milestone)
+	assertEqual(t, len(milestones), 1, "Milestones kept after teardown, actual %d, expected %d")
+	assertEqual(t, milestones[0].GetState(), "closed", "Milestone state after teardown, actual %v, expected %v")
+
+	// A second teardown finds nothing left to do.
+	events = runTeardownEvents(teardown)
+	assertNoErrorEvents(t, events, "Repeated teardown failed")
+	assertEqual(t, len(events), 2, "Repeated teardown events, actual %d, expected %d")
+}
+
+func TestTeardownPurge(t *testing.T) {
+	client := prepareGitHubClientTest()
+	setup := preparePlanSetup()
+	auth := &AuthEnvironment{workflowClient: client}
+
+	assertNoErrorEvents(t, runJobEvents(GenerateProject{ID: 42, Setup: setup, AuthEnv: auth}), "Workload failed")
+
+	// Another hire's onboarding is left alone.
+	assertNoErrorEvents(t, runTeardownEvents(TeardownProject{ID: 42, Setup: setup, AuthEnv: auth, Username: "someone", Purge: true}),
+		"Teardown of another user failed")
+
+	cache := client.Client.(TestGitHubClient).Cache
+	milestones, _ := cache["milestones"].([]*github.Milestone)
+	assertEqual(t, len(milestones), 1, "Milestones after another user's teardown, actual %d, expected %d")
+
+	assertNoErrorEvents(t, runTeardownEvents(TeardownProject{ID: 42, Setup: setup, AuthEnv: auth, Purge: true}), "Purge failed")
+
+	milestones, _ = cache["milestones"].([]*github.Milestone)
+	assertEqual(t, len(milestones), 0, "Milestones after purge, actual %d, expected %d")
+
+	// The onboarding can then be generated anew.
+	assertNoErrorEvents(t, runJobEvents(GenerateProject{ID: 42, Setup: setup, AuthEnv: auth}), "Regenerated workload failed")
+	issues, _ := cache["issues"].([]*github.Issue)
+	assertEqual(t, len(issues), 2*len(setup.Tasks), "Issues after regeneration, actual %d, expected %d")
+}
+
+func TestTeardownCancel(t *testing.T) {
+	client := prepareGitHubClientTest()
+	setup := preparePlanSetup()
+	auth := &AuthEnvironment{workflowClient: client}
+
+	assertNoErrorEvents(t, runJobEvents(GenerateProject{ID: 42, Setup: setup, AuthEnv: auth}), "Workload failed")
+
+	// Cancelled while closing the issues, the teardown stops before deleting the project.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := make(chan jobs.Event)
+	go TeardownProject{ID: 42, Context: ctx, Setup: setup, AuthEnv: auth, New: events}.Run()
+	var last jobs.Event
+	for event := range events {
+		if strings.HasPrefix(event.Text, "Closing Issue - ") {
+			cancel()
+		}
+		last = event
+	}
+	assertEqual(t, last.Type, "cancelled", "Last event of a cancelled teardown, actual %v, expected %v")
+
+	cache := client.Client.(TestGitHubClient).Cache
+	projects, _ := cache["projects"].([]*github.Project)
+	assertEqual(t, len(projects), 1, "Projects after a cancelled teardown, actual %d, expected %d")
+	milestones, _ := cache["milestones"].([]*github.Milestone)
+	assert(t, milestones[0].GetState() != "closed", "Expected the milestone to be left open by a cancelled teardown")
+
+	// Running it again finishes it.
+	teardownEvents := runTeardownEvents(TeardownProject{ID: 42, Setup: setup, AuthEnv: auth})
+	assertNoErrorEvents(t, teardownEvents, "Teardown failed")
+	assertEqual(t, teardownEvents[len(teardownEvents)-1].Type, "complete", "Last teardown event type, actual %v, expected %v")
+}
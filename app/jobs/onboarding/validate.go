@@ -0,0 +1,354 @@
+/*
+This module validates a setup scheme, reporting every problem found with the file, line and field it was found in,
+so that the scheme can be checked (e.g. by the lint command, before changes to it are merged) rather than failing
+the server on startup.
+*/
+
+package onboarding
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+var (
+	// usernamePattern matches GitHub and GitLab usernames: alphanumeric, with inner dashes (or dots and underscores).
+	usernamePattern = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9._-]*[A-Za-z0-9])?$`)
+
+	// ownerVariables are the variables an assignee, or a phase's text, may refer to (see expandOwners).
+	ownerVariables = map[string]bool{"username": true, "buddy": true}
+
+	variablePattern     = regexp.MustCompile(`\$(\{[^}]*\}|[A-Za-z0-9_]+)`)
+	environPattern      = regexp.MustCompile(`index\s+\.Environ\s+"([^"]*)"`)
+	errorLinePattern    = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+	templateLinePattern = regexp.MustCompile(`^template: config:(\d+):(?:\d+:)? (.*)$`)
+)
+
+type (
+	// ConfigError is a problem found in a setup scheme, located by its file, line (0 when unknown) and field,
+	// e.g. "roles.sre.tasks[2].title".
+	ConfigError struct {
+		File    string
+		Line    int
+		Field   string
+		Message string
+	}
+
+	// ConfigErrors lists every problem found in a setup scheme.
+	ConfigErrors []ConfigError
+
+	// schemeLines locates the fields of a scheme (as paths, e.g. "tasks[2].title") by their line in its YAML.
+	schemeLines map[string]int
+
+	yamlFrame struct {
+		indent int
+		path   string
+		item   bool // a sequence item, rather than a key
+		items  int  // the number of items of the sequence it holds
+	}
+)
+
+func (err ConfigError) Error() string {
+	var location []string
+	if len(err.File) > 0 {
+		location = append(location, err.File)
+	}
+	if err.Line > 0 {
+		location = append(location, strconv.Itoa(err.Line))
+	}
+	if len(err.Field) > 0 {
+		location = append(location, err.Field)
+	}
+	if len(location) == 0 {
+		return err.Message
+	}
+	return fmt.Sprintf("%s: %s", strings.Join(location, ":"), err.Message)
+}
+
+// Error lists the problems, one per line.
+func (errs ConfigErrors) Error() string {
+	var lines []string
+	for _, err := range errs {
+		lines = append(lines, err.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (errs *ConfigErrors) add(lines schemeLines, field string, format string, args ...interface{}) {
+	*errs = append(*errs, ConfigError{Line: lines.find(field), Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// inFile locates the problems in a file, unless they are already located in another (e.g. an included one).
+func (errs ConfigErrors) inFile(filename string) ConfigErrors {
+	for index := range errs {
+		if len(errs[index].File) == 0 {
+			errs[index].File = filename
+		}
+	}
+	return errs
+}
+
+// sorted orders the problems by file, line, then field.
+func (errs ConfigErrors) sorted() ConfigErrors {
+	sort.SliceStable(errs, func(i int, j int) bool {
+		if errs[i].File != errs[j].File {
+			return errs[i].File < errs[j].File
+		}
+		if errs[i].Line != errs[j].Line {
+			return errs[i].Line < errs[j].Line
+		}
+		return errs[i].Field < errs[j].Field
+	})
+	return errs
+}
+
+// newSchemeLines maps the keys and sequence items of a YAML document in block style to their lines. Values in
+// flow style ({...} or [...]) are located by the line of their key.
+func newSchemeLines(text string) schemeLines {
+	lines := make(schemeLines)
+	stack := []yamlFrame{{indent: -1}}
+	blockIndent := -1 // the lines of a block scalar are indented beyond the key holding it
+
+	for number, line := range strings.Split(text, "\n") {
+		content := strings.TrimLeft(line, " ")
+		indent := len(line) - len(content)
+		if blockIndent >= 0 {
+			if (indent > blockIndent) || (len(strings.TrimSpace(content)) == 0) {
+				continue
+			}
+			blockIndent = -1
+		}
+		if (len(content) == 0) || strings.HasPrefix(content, "#") || strings.HasPrefix(content, "---") {
+			continue
+		}
+
+		for strings.HasPrefix(content, "- ") || (content == "-") {
+			for (len(stack) > 1) && ((stack[len(stack)-1].indent > indent) || ((stack[len(stack)-1].indent == indent) && stack[len(stack)-1].item)) {
+				stack = stack[:len(stack)-1]
+			}
+			parent := &stack[len(stack)-1]
+			path := fmt.Sprintf("%s[%d]", parent.path, parent.items)
+			parent.items++
+			lines[path] = number + 1
+			stack = append(stack, yamlFrame{indent: indent, path: path, item: true})
+
+			trimmed := strings.TrimLeft(content[1:], " ")
+			indent += len(content) - len(trimmed)
+			content = trimmed
+		}
+
+		key, value, ok := splitYAMLKey(content)
+		if !ok {
+			continue
+		}
+		for (len(stack) > 1) && (stack[len(stack)-1].indent >= indent) {
+			stack = stack[:len(stack)-1]
+		}
+		path := key
+		if parent := stack[len(stack)-1]; len(parent.path) > 0 {
+			path = parent.path + "." + key
+		}
+		lines[path] = number + 1
+		stack = append(stack, yamlFrame{indent: indent, path: path})
+
+		if strings.HasPrefix(value, "|") || strings.HasPrefix(value, ">") {
+			blockIndent = indent
+		}
+	}
+
+	return lines
+}
+
+// splitYAMLKey splits a line of a mapping into its key and value.
+func splitYAMLKey(content string) (string, string, bool) {
+	if strings.HasPrefix(content, "{") || strings.HasPrefix(content, "[") {
+		return "", "", false
+	}
+	end := strings.Index(content, ": ")
+	if end < 0 {
+		if !strings.HasSuffix(content, ":") {
+			return "", "", false
+		}
+		end = len(content) - 1
+	}
+	key := strings.Trim(strings.TrimSpace(content[:end]), `"'`)
+	value := strings.TrimSpace(content[end+1:])
+	return key, value, len(key) > 0
+}
+
+// locate names the fields of the problems located by their line only.
+func (lines schemeLines) locate(errs ConfigErrors) ConfigErrors {
+	fields := make(map[int]string)
+	for field, line := range lines {
+		if existing, ok := fields[line]; !ok || (len(field) > len(existing)) {
+			fields[line] = field // the innermost, e.g. "tasks[0].title" rather than "tasks[0]"
+		}
+	}
+	for index := range errs {
+		if len(errs[index].Field) == 0 {
+			errs[index].Field = fields[errs[index].Line]
+		}
+	}
+	return errs
+}
+
+// find returns the line of a field, or else of its closest located parent; 0 when none is located.
+func (lines schemeLines) find(field string) int {
+	for len(field) > 0 {
+		if line, ok := lines[field]; ok {
+			return line
+		}
+		cut := strings.LastIndexAny(field, ".[")
+		if cut < 0 {
+			break
+		}
+		field = field[:cut]
+	}
+	return 0
+}
+
+// checkEnviron reports the environment variables the template refers to, but which are not set.
+func checkEnviron(data []byte, environ map[string]string) ConfigErrors {
+	var errs ConfigErrors
+	for number, line := range strings.Split(string(data), "\n") {
+		for _, match := range environPattern.FindAllStringSubmatch(line, -1) {
+			if _, ok := environ[match[1]]; !ok {
+				errs = append(errs, ConfigError{Line: number + 1, Message: fmt.Sprintf("Unresolved variable '%s'", match[1])})
+			}
+		}
+	}
+	return errs
+}
+
+// parseErrors locates the errors of the template and YAML parsers by their line.
+func parseErrors(err error) ConfigErrors {
+	var messages []string
+	if typeError, ok := err.(*yaml.TypeError); ok {
+		messages = typeError.Errors
+	} else {
+		messages = []string{err.Error()}
+	}
+
+	var errs ConfigErrors
+	for _, message := range messages {
+		configError := ConfigError{Message: message}
+		for _, pattern := range []*regexp.Regexp{errorLinePattern, templateLinePattern} {
+			if match := pattern.FindStringSubmatch(message); match != nil {
+				configError.Line, _ = strconv.Atoi(match[1])
+				configError.Message = match[2]
+			}
+		}
+		errs = append(errs, configError)
+	}
+	return errs
+}
+
+// checkUsername reports an assignee which is neither a username, nor a variable standing for one.
+func (errs *ConfigErrors) checkUsername(lines schemeLines, field string, username string) {
+	if len(username) == 0 {
+		return
+	}
+	if variables := variablePattern.FindAllString(username, -1); len(variables) > 0 {
+		errs.checkVariables(lines, field, username)
+		if (len(variables) > 1) || (variables[0] != username) {
+			errs.add(lines, field, "Invalid username '%s'; a variable must stand alone", username)
+		}
+		return
+	}
+	if !usernamePattern.MatchString(username) {
+		errs.add(lines, field, "Invalid username '%s'", username)
+	}
+}
+
+// checkVariables reports the variables of a text which are neither $username nor $buddy.
+func (errs *ConfigErrors) checkVariables(lines schemeLines, field string, text string) {
+	for _, variable := range variablePattern.FindAllString(text, -1) {
+		if name := strings.Trim(variable[1:], "{}"); !ownerVariables[name] {
+			errs.add(lines, field, "Unknown variable '%s'; expected $username or $buddy", variable)
+		}
+	}
+}
+
+// checkTasks reports tasks without a title, or with the title of another task in the same list.
+func (errs *ConfigErrors) checkTasks(lines schemeLines, path string, tasks []TaskEntry, owners map[string]indirectAssignee) {
+	seen := make(map[string]bool)
+	for index, task := range tasks {
+		field := fmt.Sprintf("%s[%d]", path, index)
+		switch {
+		case len(strings.TrimSpace(task.Title)) == 0:
+			errs.add(lines, field+".title", "Tasks must have a title")
+		case seen[task.Title]:
+			errs.add(lines, field+".title", "Task '%s' is declared more than once", task.Title)
+		}
+		seen[task.Title] = true
+
+		assignees := map[string]indirectAssignee{field + ".assignee": task.Assignee}
+		for owner, assignee := range task.Owners {
+			assignees[fmt.Sprintf("%s.owners[%d]", field, owner)] = assignee
+		}
+		for assigneeField, assignee := range assignees {
+			if len(assignee.owner) > 0 {
+				if _, ok := owners[assignee.owner]; !ok {
+					errs.add(lines, assigneeField, "Unknown task owner '%s'", assignee.owner)
+				}
+				continue
+			}
+			errs.checkUsername(lines, assigneeField+".github_username", assignee.GithubUsername)
+		}
+	}
+}
+
+// lint reports the problems of a file of the scheme which are found before its owners are resolved: empty and
+// duplicate titles, invalid usernames, unknown task owners (among those of the whole scheme), and unknown variables.
+func (setup *SetupScheme) lint(lines schemeLines, owners map[string]indirectAssignee) ConfigErrors {
+	var errs ConfigErrors
+
+	for _, name := range sortedKeys(setup.TaskOwners) {
+		owner := setup.TaskOwners[name]
+		if len(owner.owner) > 0 {
+			// Only tasks refer to task owners by name; a task owner is declared with its username.
+			errs.add(lines, "task_owners."+name, "Task owner '%s' must be declared with its username, e.g. '%s: {github_username: %s}'", name, name, owner.owner)
+			continue
+		}
+		errs.checkUsername(lines, fmt.Sprintf("task_owners.%s.github_username", name), owner.GithubUsername)
+	}
+
+	errs.checkTasks(lines, "tasks", setup.Tasks, owners)
+	for _, name := range setup.RoleNames() {
+		errs.checkTasks(lines, fmt.Sprintf("roles.%s.tasks", name), setup.Roles[name].Tasks, owners)
+	}
+
+	pools := map[string]*BuddiesEntry{"buddies": &setup.Buddies}
+	for _, name := range setup.RoleNames() {
+		entry := setup.Roles[name]
+		pools[fmt.Sprintf("roles.%s.buddies", name)] = &entry.Buddies
+	}
+	for path, pool := range pools {
+		for index, username := range pool.Pool {
+			if (len(username) > 0) && !usernamePattern.MatchString(username) {
+				errs.add(lines, fmt.Sprintf("%s.pool[%d]", path, index), "Invalid username '%s'", username)
+			}
+		}
+	}
+
+	for index, phase := range setup.Phases {
+		errs.checkVariables(lines, fmt.Sprintf("phases[%d].title", index), phase.Title)
+		errs.checkVariables(lines, fmt.Sprintf("phases[%d].description", index), phase.Description)
+	}
+
+	return errs
+}
+
+func sortedKeys(owners map[string]indirectAssignee) []string {
+	var keys []string
+	for key := range owners {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,131 @@
+package onboarding
+
+/*
+This module's tests focus on exercising the `validate.go` module.
+*/
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testLintFixture = `task_owners:
+    new_hire:
+        github_username: $username
+    buddy:
+        github_username: "not a user"
+tasks:
+    - title: one
+      assignee: new_hire
+      description: |
+        title: not a key
+    - title: one
+      assignee: nobody
+    - title: ""
+      owners:
+          - github_username: $manager
+roles:
+    sre:
+        tasks:
+            - title: pager
+              assignee:
+                  github_username: -bad-
+phases:
+    - name: week 1
+      title: Welcome @$hire!
+`
+
+func TestSchemeLines(t *testing.T) {
+	lines := newSchemeLines(testLintFixture)
+
+	cases := []struct {
+		field string
+		line  int
+	}{
+		{"task_owners.buddy.github_username", 5},
+		{"tasks[0].title", 7},
+		{"tasks[0].description", 9},
+		{"tasks[1].title", 11},
+		{"tasks[2].owners[0].github_username", 15},
+		{"roles.sre.tasks[0].assignee.github_username", 21},
+		{"phases[0].title", 24},
+		{"tasks[1].labels[0]", 11}, // the closest located parent
+		{"unknown", 0},
+	}
+	for _, c := range cases {
+		assertEqual(t, lines.find(c.field), c.line, c.field+": line, actual %d, expected %d")
+	}
+}
+
+func TestLintErrors(t *testing.T) {
+	scheme := SetupScheme{}
+	err := scheme.ingest([]byte(testLintFixture), &map[string]string{})
+	errs, ok := err.(ConfigErrors)
+	if !ok {
+		t.Fatalf("Expected ConfigErrors, found %v", err)
+	}
+
+	expected := []string{
+		`5:task_owners.buddy.github_username: Invalid username 'not a user'`,
+		`11:tasks[1].title: Task 'one' is declared more than once`,
+		`12:tasks[1].assignee: Unknown task owner 'nobody'`,
+		`13:tasks[2].title: Tasks must have a title`,
+		`15:tasks[2].owners[0].github_username: Unknown variable '$manager'; expected $username or $buddy`,
+		`21:roles.sre.tasks[0].assignee.github_username: Invalid username '-bad-'`,
+		`24:phases[0].title: Unknown variable '$hire'; expected $username or $buddy`,
+	}
+	var actual []string
+	for _, problem := range errs {
+		actual = append(actual, problem.Error())
+	}
+	assertEqual(t, strings.Join(actual, "\n"), strings.Join(expected, "\n"), "Problems, actual\n%v\nexpected\n%v")
+}
+
+func TestParseErrors(t *testing.T) {
+	cases := []struct {
+		yaml     string
+		environ  map[string]string
+		expected string
+	}{
+		{"tasks:\n    - title: one\n      asignee: new_hire\n", nil, "3:tasks[0].asignee: field asignee not found in type onboarding.TaskEntry"},
+		{"tasks:\n  - title: one\n title: two\n", nil, "2:tasks[0].title: did not find expected key"},
+		{"githubOrganization: \"{{ index .Environ \"onboard.org\" }}\"\n", nil, "1: Unresolved variable 'onboard.org'"},
+		{"githubOrganization: \"{{ index .Environ \"onboard.org\" }\"\n", map[string]string{"onboard.org": "o"}, "1: unexpected \"}\" in operand"},
+		{"labels:\n    - name: \"\"\n", nil, "1:labels: Labels must have a name"},
+		{"task_owners:\n    lead: octocat\n", nil, "2:task_owners.lead: Task owner 'lead' must be declared with its username, e.g. 'lead: {github_username: octocat}'"},
+	}
+
+	for _, c := range cases {
+		scheme := SetupScheme{}
+		environ := c.environ
+		if environ == nil {
+			environ = map[string]string{}
+		}
+		err := scheme.ingest([]byte(c.yaml), &environ)
+		if err == nil {
+			t.Errorf("Expected an error for %q", c.yaml)
+			continue
+		}
+		assertEqual(t, err.Error(), c.expected, "Problem, actual %q, expected %q")
+	}
+}
+
+func TestLoadErrorsName(t *testing.T) {
+	dir, err := ioutil.TempDir("", "onboarding")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "tasks.yaml")
+	ioutil.WriteFile(filename, []byte("tasks:\n    - title: \"\"\n"), 0600)
+
+	_, err = NewSetupScheme(filename, &map[string]string{})
+	assertEqual(t, err.Error(), filename+":2:tasks[0].title: Tasks must have a title", "Problem, actual %q, expected %q")
+
+	_, err = NewSetupScheme(filepath.Join(dir, "missing.yaml"), &map[string]string{})
+	assert(t, err != nil, "Expected an error for a missing file")
+}
@@ -1,5 +1,6 @@
 /*
 This package implements some fairly thin wrappers over the GitHub client API, to allow them to be mocked for testing.
+The GitLab equivalents, behind the same IRepositoryAccess and iClientAccess interfaces, are in `gitlab.go`.
 */
 
 package onboarding
@@ -9,6 +10,7 @@ import (
 	"fmt"
 	"log"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/go-github/github"
@@ -52,9 +54,14 @@ type (
 		// for github.Client.Issues
 		ListMilestones(ctx context.Context, owner string, repo string, opts *github.MilestoneListOptions) ([]*github.Milestone, *github.Response, error)
 		CreateMilestone(ctx context.Context, owner string, repo string, opts *github.Milestone) (*github.Milestone, *github.Response, error)
+		EditMilestone(ctx context.Context, owner string, repo string, number int, milestone *github.Milestone) (*github.Milestone, *github.Response, error)
+		DeleteMilestone(ctx context.Context, owner string, repo string, number int) (*github.Response, error)
 		ListByRepo(ctx context.Context, owner string, repo string, opts *github.IssueListByRepoOptions) ([]*github.Issue, *github.Response, error)
 		Create(ctx context.Context, owner string, repo string, req *github.IssueRequest) (*github.Issue, *github.Response, error)
 		Edit(ctx context.Context, owner string, repo string, issueID int, req *github.IssueRequest) (*github.Issue, *github.Response, error)
+		ListLabels(ctx context.Context, owner string, repo string, opts *github.ListOptions) ([]*github.Label, *github.Response, error)
+		CreateLabel(ctx context.Context, owner string, repo string, label *github.Label) (*github.Label, *github.Response, error)
+		EditLabel(ctx context.Context, owner string, repo string, name string, label *github.Label) (*github.Label, *github.Response, error)
 	}
 
 	iGitHubRepositories interface {
@@ -67,46 +74,102 @@ type (
 	iGitHubProjects interface {
 		// for github.Client.Projects
 		UpdateProject(ctx context.Context, projectID int, opts *github.ProjectOptions) (*github.Project, *github.Response, error)
+		DeleteProject(ctx context.Context, projectID int) (*github.Response, error)
 		CreateProjectColumn(ctx context.Context, projectID int, opts *github.ProjectColumnOptions) (*github.ProjectColumn, *github.Response, error)
 		ListProjectColumns(ctx context.Context, projectID int, opts *github.ListOptions) ([]*github.ProjectColumn, *github.Response, error)
 		CreateProjectCard(ctx context.Context, columnID int, opt *github.ProjectCardOptions) (*github.ProjectCard, *github.Response, error)
 		ListProjectCards(ctx context.Context, columnID int, opt *github.ListOptions) ([]*github.ProjectCard, *github.Response, error)
+		DeleteProjectCard(ctx context.Context, cardID int) (*github.Response, error)
+		MoveProjectCard(ctx context.Context, cardID int, opt *github.ProjectCardMoveOptions) (*github.Response, error)
 	}
 
 	// IRepositoryAccess provides simplified procedures for this project's business case, namily masking non-idempotent requests to reduce duplication.
+	// It is implemented for GitHub by WorkflowRepository, and for GitLab by GitLabRepository; GitHub's models are used by both.
 	IRepositoryAccess interface {
 		// Methods implemented in our proxy
 		GetIssuesByRequest(request *github.IssueRequest) ([]*github.Issue, error)
-		CreateOrUpdateIssue(assignee *string, title *string, body *string, milestone int) (*github.Issue, error)
+		CreateOrUpdateIssue(assignees []string, title *string, body *string, milestone int, labels []string) (*github.Issue, error)
 		CreateOrUpdateMilestone(title *string, description *string, dueDate *time.Time) (*github.Milestone, error)
+		GetMilestoneByTitle(title *string) (*github.Milestone, error)
 		CreateOrUpdateProject(title *string, description *string, columns []string) (*github.Project, error)
+		GetProjectByTitle(title *string) (*github.Project, error)
 		FetchMappedProjectColumns(project *github.Project) (map[string](*github.ProjectColumn), error)
 		ColumnsPresent(project *github.Project, columns []string) (bool, error)
-		CreateCardForIssue(issue *github.Issue, column *github.ProjectColumn) (*github.ProjectCard, error)
-
-		// Internal methods (to be overridden by test models)
-		createIssue(service iGitHubIssues, req *github.IssueRequest) (*github.Issue, error)
-		updateIssue(service iGitHubIssues, issue *github.Issue, req *github.IssueRequest) (*github.Issue, error)
-		fetchIssues(service iGitHubIssues, listOpts *github.IssueListByRepoOptions) ([](*github.Issue), error)
-		fetchProjects(service iGitHubRepositories, listOpts *github.ProjectListOptions) ([]*github.Project, error)
-		createProject(service iGitHubRepositories, createOpts *github.ProjectOptions) (*github.Project, error)
-		updateProject(service iGitHubProjects, project *github.Project, updateOpts *github.ProjectOptions) (*github.Project, error)
-		fetchProjectColumns(service iGitHubProjects, project *github.Project) ([]*github.ProjectColumn, error)
-		createProjectColumns(service iGitHubProjects, project *github.Project, columns []string) ([]*github.ProjectColumn, error)
-		createMilestone(service iGitHubIssues, ms *github.Milestone) (*github.Milestone, error)
-		fetchMilestones(service iGitHubIssues, listOpts *github.MilestoneListOptions) ([]*github.Milestone, error)
+		FetchBoardCards(project *github.Project) (*BoardCards, error)
+		CreateCardForIssue(board *BoardCards, issue *github.Issue, column *github.ProjectColumn) (*github.ProjectCard, string, error)
+		GetCardForIssue(project *github.Project, issue *github.Issue) (*github.ProjectCard, *github.ProjectColumn, error)
+		EnsureLabels(labels []LabelEntry) ([]*github.Label, error)
+		FetchMappedLabels() (map[string](*github.Label), error)
+		EditIssue(issue *github.Issue, request *github.IssueRequest) (*github.Issue, error)
+		CloseIssue(issue *github.Issue) (*github.Issue, error)
+		DeleteCardForIssue(project *github.Project, issue *github.Issue) (bool, error)
+		DeleteProject(project *github.Project) error
+		CloseMilestone(milestone *github.Milestone) (*github.Milestone, error)
+		DeleteMilestone(milestone *github.Milestone) error
 	}
 
+	// iClientAccess is implemented for GitHub by WorkflowClient, and for GitLab by GitLabClient.
 	iClientAccess interface {
 		// Methods implemented in our proxy
 		GetRepository(owner string, name string) (IRepositoryAccess, error)
+		ProjectsURL(owner string, name string) string
 
-		// Internal methods (to be overridden by test models)
-		fetchRepository(service iGitHubRepositories, owner *string, name *string) (IRepositoryAccess, error)
-		resolveUser(username *string) *github.User
+		// Internal methods
+		currentUsername() (string, error)
+		resolveUser(username *string) (*github.User, error)
 	}
 )
 
+// Outcomes of CreateCardForIssue.
+const (
+	CardCreated = "created"
+	CardMoved   = "moved"
+	CardPresent = "present"
+)
+
+// Labels declared without a color are created in GitHub's default grey.
+const defaultLabelColor = "ededed"
+
+// welcomeTitle names both the milestone and the project generated for a new hire.
+func welcomeTitle(username string) string {
+	return fmt.Sprintf("Welcome @%s!", username)
+}
+
+func welcomeDescription(username string) string {
+	return fmt.Sprintf("Let's setup up @%s for success. Here's what we need to cover...", username)
+}
+
+// issueBody renders a task's description, with "Blocked by" cross-references to the issues of its prerequisites,
+// and the task's due date on the schedule (if it has one).
+func issueBody(task *TaskEntry, issueNumbers map[string]int, schedule *Schedule) string {
+	var references []string
+	for _, title := range task.DependsOn {
+		if number, ok := issueNumbers[title]; ok {
+			references = append(references, fmt.Sprintf("#%d", number))
+		}
+	}
+
+	var notes []string
+	if len(references) > 0 {
+		notes = append(notes, fmt.Sprintf("Blocked by %s", strings.Join(references, ", ")))
+	}
+	if schedule != nil {
+		if due, ok := schedule.TaskDue(task); ok {
+			notes = append(notes, dueNote(due))
+		}
+	}
+
+	if len(notes) == 0 {
+		return task.Description
+	}
+
+	footer := strings.Join(notes, "\n\n")
+	if len(task.Description) == 0 {
+		return footer
+	}
+	return fmt.Sprintf("%s\n\n%s", strings.TrimRight(task.Description, "\n"), footer)
+}
+
 // NOTE: this reflects a business process assumption.
 // Target 3 weeks (rounding up) for onboarding completion.
 // New hires starting on Mondays will effectively get 4 weeks.
@@ -121,72 +184,330 @@ func getMilestoneDueTime(fromTime *time.Time) time.Time {
 
 // GenerateProject represents a Job to be executed by the revel job module.
 // See -> https://revel.github.io/modules/jobs.html#implementing-jobs
+// Role selects the track of tasks (see SetupScheme.TasksForRole) to be generated.
+// When DryRun is set, Run only reports the Plan of what would change, without modifying the repository.
+// When Sync is set, issues generated by an earlier run are edited where their body, assignee or milestone
+// has drifted from the task template, keeping the checklist items the hire has already ticked.
+// Progress is recorded in Checkpoints (when set); with Resume, a failed run continues from its checkpoint,
+// including its role and start date, rather than starting over.
+// StartDate (YYYY-MM-DD, today when empty) is the hire's first day, from which the Setup's Schedule computes
+// the milestones' and the tasks' due dates; syncing without it keeps the due dates of existing issues.
+// Issues are assigned to the milestone of their task's phase (see SetupScheme.Phases).
+// Hire names the new hire being onboarded, e.g. by their manager, under the authenticated user's token; the hire
+// is the authenticated user when it is empty. Tasks assigned to $username are assigned to the hire.
+// The hire is paired with a buddy from the Setup's pool (see SetupScheme.Buddies), recorded in Buddies (when set);
+// tasks assigned to $buddy are assigned to the buddy.
+// Cancelling Context (when set) stops the run between its steps, interrupting any request in progress; the run
+// reports a "cancelled" event, and keeps its checkpoint so that it can be resumed.
 type GenerateProject struct {
-	ID      int
-	Setup   *SetupScheme
-	AuthEnv *AuthEnvironment
-	New     chan<- jobs.Event
+	ID          int
+	Context     context.Context
+	Setup       *SetupScheme
+	AuthEnv     *AuthEnvironment
+	New         chan<- jobs.Event
+	Role        string
+	DryRun      bool
+	Sync        bool
+	Resume      bool
+	StartDate   string
+	Hire        string
+	Checkpoints CheckpointStore
+	Buddies     BuddyStore
+}
+
+// onboardee returns the username of the job's hire: Hire, validated with the provider, or else the authenticated user.
+func (job GenerateProject) onboardee(client iClientAccess, authenticated string) (string, error) {
+	if len(job.Hire) == 0 {
+		return authenticated, nil
+	}
+	user, err := client.resolveUser(&job.Hire)
+	if err != nil {
+		return "", err
+	}
+	return user.GetLogin(), nil
+}
+
+// checkAssignees verifies with the provider that the buddy (if any) and every assignee of the tasks exist, before
+// any issue is created.
+func (job GenerateProject) checkAssignees(client iClientAccess, tasks []TaskEntry, username string, buddy string) error {
+	checked := make(map[string]bool)
+	if len(buddy) > 0 {
+		checked[strings.ToLower(buddy)] = true
+		if _, err := client.resolveUser(&buddy); err != nil {
+			return fmt.Errorf("The buddy is an unknown user: %v", err)
+		}
+	}
+	for _, task := range tasks {
+		for _, assignee := range job.Setup.TaskAssignees(&task, username, buddy) {
+			if checked[strings.ToLower(assignee)] {
+				continue
+			}
+			checked[strings.ToLower(assignee)] = true
+			if _, err := client.resolveUser(&assignee); err != nil {
+				return fmt.Errorf("Task '%s' is assigned to an unknown user: %v", task.Title, err)
+			}
+		}
+	}
+	return nil
+}
+
+// cancelled indicates whether the job's Context is done, reporting the cancellation when it is.
+func (job GenerateProject) cancelled() bool {
+	if (job.Context == nil) || (job.Context.Err() == nil) {
+		return false
+	}
+	job.New <- jobs.NewEvent(job.ID, "cancelled", "Cancelled the project generation; it can be resumed from the workload page")
+	return true
+}
+
+// fail reports an error of the job, or its cancellation when the error is due to it (e.g. an interrupted request).
+func (job GenerateProject) fail(text string, err error) {
+	if !job.cancelled() {
+		job.New <- jobs.NewError(job.ID, text, err.Error())
+	}
 }
 
 // Run implements the required cron.Job interface for revel job execution
 func (job GenerateProject) Run() {
+	if job.DryRun {
+		job.runPlan()
+		return
+	}
+
 	setup := job.Setup
 	auth := job.AuthEnv
-	username := auth.GithubUsername()
-	client, _ := auth.newWorkflowClient()
+	authenticated := auth.Username()
 
 	defer close(job.New)
-	job.New <- jobs.NewEvent(job.ID, "start", fmt.Sprintf("Starting project generation as %v", username))
+	if len(job.Hire) > 0 {
+		job.New <- jobs.NewEvent(job.ID, "start", fmt.Sprintf("Starting project generation for @%s as %v", job.Hire, authenticated))
+	} else {
+		job.New <- jobs.NewEvent(job.ID, "start", fmt.Sprintf("Starting project generation as %v", authenticated))
+	}
 
-	repo, err := client.GetRepository(setup.GithubOrganization, setup.GithubRepository)
+	client, err := auth.newWorkflowClient(job.Context, jobWaitNotifier(job.ID, job.New))
 	if err != nil {
-		job.New <- jobs.NewError(job.ID, fmt.Sprintf("Failed to repository - %s", setup.GithubRepository), err.Error())
+		job.fail("Failed to connect", err)
 		return
 	}
 
-	title := fmt.Sprintf("Welcome @%s!", username)
-	description := fmt.Sprintf("Let's setup up @%s for success. Here's what we need to cover...", username)
-	dueOn := getMilestoneDueTime(nil)
+	username, err := job.onboardee(client, authenticated)
+	if err != nil {
+		job.fail(fmt.Sprintf("Failed to find the new hire - %s", job.Hire), err)
+		return
+	}
 
-	job.New <- jobs.NewEvent(job.ID, "progress", fmt.Sprintf("Creating Milestone - %s", title))
-	milestone, err := repo.CreateOrUpdateMilestone(&title, &description, &dueOn)
+	checkpoint := job.loadCheckpoint(username)
+	if checkpoint.Started() {
+		job.New <- jobs.NewEvent(job.ID, "progress", fmt.Sprintf("Resuming from the checkpoint of %s", checkpoint.UpdatedAt.Format(time.RFC1123)))
+	}
+
+	tasks, err := setup.TasksForRole(checkpoint.Role)
 	if err != nil {
-		job.New <- jobs.NewError(job.ID, fmt.Sprintf("Failed to create milestone - %s", title), err.Error())
+		job.fail(fmt.Sprintf("Failed to select tasks for role - %s", checkpoint.Role), err)
 		return
 	}
 
-	job.New <- jobs.NewEvent(job.ID, "progress", fmt.Sprintf("Creating Project - %s", title))
-	project, err := repo.CreateOrUpdateProject(&title, &description, []string{"Backlog", "In Progress", "Review", "Done"})
+	buddy, picked, err := job.buddyFor(username, checkpoint.Role, false)
 	if err != nil {
-		job.New <- jobs.NewError(job.ID, fmt.Sprintf("Failed to create project - %s", title), err.Error())
+		job.fail("Failed to pick a buddy", err)
 		return
 	}
+	if picked {
+		job.New <- jobs.NewEvent(job.ID, "progress", fmt.Sprintf("Paired @%s with buddy @%s", username, buddy))
+	}
+
+	// A buddy just picked is released when the run fails, or is cancelled, before anything introducing them is
+	// generated (and recorded in the checkpoint); a later run picks again.
+	keepBuddy := !picked
+	defer func() {
+		if !keepBuddy {
+			releaseBuddy(job.Buddies, checkpoint.Key)
+		}
+	}()
 
-	columns, err := repo.FetchMappedProjectColumns(project)
+	if err = job.checkAssignees(client, tasks, username, buddy); err != nil {
+		job.fail("Failed to validate the assignees", err)
+		return
+	}
+
+	repo, err := client.GetRepository(setup.GithubOrganization, setup.GithubRepository)
 	if err != nil {
-		job.New <- jobs.NewError(job.ID, "Failed to fetch project columns", err.Error())
+		job.fail(fmt.Sprintf("Failed to repository - %s", setup.GithubRepository), err)
 		return
 	}
 
-	for _, task := range setup.Tasks {
-		job.New <- jobs.NewEvent(job.ID, "progress", fmt.Sprintf("Preparing Issue - %s", task.Title))
-		issue, err := repo.CreateOrUpdateIssue(&task.Assignee.GithubUsername, &task.Title, &task.Description, milestone.GetNumber())
+	schedule, err := setup.NewSchedule(checkpoint.StartDate)
+	if err != nil {
+		job.fail("Failed to compute the schedule", err)
+		return
+	}
+	checkpoint.StartDate = schedule.StartDate()
+	keepDue := len(job.StartDate) == 0
+
+	title := welcomeTitle(username)
+	description := welcomeDescription(username)
+
+	// The run may be cancelled between each of the following steps.
+	if job.cancelled() {
+		return
+	}
+
+	// Labels must exist before the issues which carry them are created.
+	labels := setup.LabelsForTasks(tasks)
+	if (len(labels) > 0) && !checkpoint.LabelsReady {
+		job.New <- jobs.NewEvent(job.ID, "progress", fmt.Sprintf("Preparing %d Labels", len(labels)))
+		if _, err = repo.EnsureLabels(labels); err != nil {
+			job.fail("Failed to prepare labels", err)
+			return
+		}
+	}
+	checkpoint.LabelsReady = true
+	job.saveCheckpoint(checkpoint)
+
+	// Each phase of the onboarding has a milestone, mapped by the phase's name.
+	milestones := make(map[string](*github.Milestone))
+	for _, phase := range setup.PhasesForTasks(tasks) {
+		if job.cancelled() {
+			return
+		}
+		milestoneTitle := phase.MilestoneTitle(username)
+		milestone, recorded := checkpoint.milestone(milestoneTitle)
+		if !recorded {
+			milestoneDescription := phase.MilestoneDescription(username, buddy)
+			dueOn := schedule.PhaseDue(&phase)
+			job.New <- jobs.NewEvent(job.ID, "progress", fmt.Sprintf("Creating Milestone - %s", milestoneTitle))
+			milestone, err = repo.CreateOrUpdateMilestone(&milestoneTitle, &milestoneDescription, &dueOn)
+			if err != nil {
+				job.fail(fmt.Sprintf("Failed to create milestone - %s", milestoneTitle), err)
+				return
+			}
+			checkpoint.Milestones[milestoneTitle] = milestone.GetNumber()
+			job.saveCheckpoint(checkpoint)
+			keepBuddy = true // the milestone introduces them
+		}
+		milestones[phase.Name] = milestone
+	}
+
+	if job.cancelled() {
+		return
+	}
+	project := &github.Project{ID: github.Int(checkpoint.ProjectID)}
+	columns := checkpoint.columns()
+	if (checkpoint.ProjectID == 0) || (len(missingColumns(columns, setup.ColumnNames())) > 0) {
+		job.New <- jobs.NewEvent(job.ID, "progress", fmt.Sprintf("Creating Project - %s", title))
+		project, err = repo.CreateOrUpdateProject(&title, &description, setup.ColumnNames())
 		if err != nil {
-			job.New <- jobs.NewError(job.ID, fmt.Sprintf("Failed to create issue - %s", task.Title), err.Error())
+			job.fail(fmt.Sprintf("Failed to create project - %s", title), err)
 			return
 		}
 
-		// NOTE: this fails with HTTP 422 when the the issue already has a card in the project.
-		_, err = repo.CreateCardForIssue(issue, columns["Backlog"])
+		columns, err = repo.FetchMappedProjectColumns(project)
 		if err != nil {
+			job.fail("Failed to fetch project columns", err)
+			return
+		}
+
+		checkpoint.ProjectID = project.GetID()
+		for name, column := range columns {
+			checkpoint.ColumnIDs[name] = column.GetID()
+		}
+		job.saveCheckpoint(checkpoint)
+	}
+
+	// Tasks are ordered so that prerequisites are created first, and can be referenced by number.
+	issueNumbers := make(map[string]int)
+	for taskTitle, recorded := range checkpoint.Issues {
+		issueNumbers[taskTitle] = recorded.Number
+	}
+
+	// On sync, the issues are looked for in any of the hire's milestones.
+	var hireMilestones []int
+	if job.Sync {
+		if hireMilestones, err = findHireMilestones(repo, setup, username); err != nil {
+			job.fail("Failed to fetch milestones", err)
+			return
+		}
+	}
+
+	// The checkpoint is kept while any card is missing, so that resuming retries them.
+	cardsPlaced := true
+	var board *BoardCards // read when the first card is placed
+
+	for _, task := range tasks {
+		if job.cancelled() {
+			return
+		}
+		issue, resumed := checkpoint.issue(task.Title)
+		if resumed && checkpoint.Issues[task.Title].Card {
+			continue // completed by an earlier run
+		}
+
+		if !resumed {
+			job.New <- jobs.NewEvent(job.ID, "progress", fmt.Sprintf("Preparing Issue - %s", task.Title))
+			milestone := milestones[setup.TaskPhase(&task).Name]
+			assignees := setup.TaskAssignees(&task, username, buddy)
+			body := issueBody(&task, issueNumbers, schedule)
+			if job.Sync {
+				var drifted []string
+				issue, drifted, err = syncIssue(repo, username, hireMilestones, assignees, task.Title, body, milestone.GetNumber(), setup.IssueLabels(&task), keepDue)
+				if (err == nil) && (len(drifted) > 0) {
+					job.New <- jobs.NewEvent(job.ID, "progress", fmt.Sprintf("Updated Issue - #%d %s (%s)", issue.GetNumber(), task.Title, strings.Join(drifted, ", ")))
+				}
+			} else {
+				issue, err = repo.CreateOrUpdateIssue(assignees, &task.Title, &body, milestone.GetNumber(), setup.IssueLabels(&task))
+			}
+			if err != nil {
+				job.fail(fmt.Sprintf("Failed to create issue - %s", task.Title), err)
+				return
+			}
+			issueNumbers[task.Title] = issue.GetNumber()
+			checkpoint.Issues[task.Title] = newCheckpointIssue(issue, false)
+			job.saveCheckpoint(checkpoint)
+		}
+
+		if board == nil {
+			if board, err = repo.FetchBoardCards(project); err != nil {
+				if job.cancelled() {
+					return
+				}
+				job.New <- jobs.NewError(job.ID, fmt.Sprintf("Error fetching the project's cards - %v", err), err.Error())
+				cardsPlaced = false
+				continue // retried with the next task
+			}
+		}
+		column := columns[setup.StartColumn(&task)]
+		if column == nil {
+			job.New <- jobs.NewError(job.ID, fmt.Sprintf("Error creating card - no column %s in the project", setup.StartColumn(&task)), "")
+			cardsPlaced = false
+			continue
+		}
+		_, placed, err := repo.CreateCardForIssue(board, issue, column)
+		if err != nil {
+			if job.cancelled() {
+				return
+			}
 			job.New <- jobs.NewError(job.ID, fmt.Sprintf("Error creating card - %v", err), err.Error())
-			// DO NOT return here.
+			cardsPlaced = false
+			continue // DO NOT return here.
 		}
+		switch placed {
+		case CardPresent:
+			job.New <- jobs.NewEvent(job.ID, "progress", fmt.Sprintf("Already on board - %s", task.Title))
+		case CardMoved:
+			job.New <- jobs.NewEvent(job.ID, "progress", fmt.Sprintf("Moved Card - %s (to %s)", task.Title, column.GetName()))
+		}
+		checkpoint.Issues[task.Title] = newCheckpointIssue(issue, true)
+		job.saveCheckpoint(checkpoint)
 
 	}
 
-	//https://github.com/alika/test-toby/projects
-	projectsURL := fmt.Sprintf("https://github.com/%s/%s/projects/", job.Setup.GithubOrganization, job.Setup.GithubRepository)
+	if cardsPlaced {
+		job.clearCheckpoint(checkpoint)
+	}
+
+	keepBuddy = true
+	projectsURL := client.ProjectsURL(setup.GithubOrganization, setup.GithubRepository)
 	completed := fmt.Sprintf("Successfully created project @ %s", projectsURL)
 	job.New <- jobs.NewEvent(job.ID, "complete", completed)
 }
@@ -222,6 +543,11 @@ func (client *WorkflowClient) fetchRepository(service iGitHubRepositories, owner
 	return &WorkflowRepository{client.Client, client.Context, repo}, nil
 }
 
+// ProjectsURL locates the projects of a GitHub repository, e.g. https://github.com/alika/test-toby/projects
+func (client *WorkflowClient) ProjectsURL(owner string, name string) string {
+	return fmt.Sprintf("https://github.com/%s/%s/projects/", owner, name)
+}
+
 // GetRepository returns a WorkflowRepository instance, wrapping the standard GitHub repository model.
 func (client *WorkflowClient) GetRepository(owner string, name string) (IRepositoryAccess, error) {
 	service := client.Client.getRepositoriesService()
@@ -257,7 +583,8 @@ func (repo *WorkflowRepository) fetchIssues(service iGitHubIssues, listOpts *git
 	return resultIssues, err
 }
 
-// GetIssuesByRequest fetches an issue, if present, by title, milestone, and assignee username
+// GetIssuesByRequest fetches an issue, if present, by title, milestone, and assignee usernames; issues must be
+// assigned to every one of the requested assignees.
 func (repo *WorkflowRepository) GetIssuesByRequest(request *github.IssueRequest) ([]*github.Issue, error) {
 
 	var resultIssues [](*github.Issue)
@@ -271,7 +598,8 @@ func (repo *WorkflowRepository) GetIssuesByRequest(request *github.IssueRequest)
 		milestone = strconv.Itoa(*request.Milestone)
 	}
 
-	if (len(requestedAssignees) == 1) && (requestedAssignees[0] != "none") {
+	// GitHub filters by a single assignee; the others are matched below.
+	if (len(requestedAssignees) > 0) && (len(requestedAssignees[0]) > 0) {
 		assignee = requestedAssignees[0]
 	}
 
@@ -288,7 +616,10 @@ func (repo *WorkflowRepository) GetIssuesByRequest(request *github.IssueRequest)
 	}
 
 	for _, thisIssue := range issues {
-		if (request.Title == nil) || ((*request.Title) == thisIssue.GetTitle()) {
+		if (request.Title != nil) && ((*request.Title) != thisIssue.GetTitle()) {
+			continue
+		}
+		if hasAssignees(thisIssue, requestedAssignees) {
 			resultIssues = append(resultIssues, thisIssue)
 		}
 	}
@@ -317,14 +648,32 @@ func (repo *WorkflowRepository) updateIssue(service iGitHubIssues, issue *github
 	return issue, nil // success
 }
 
-// CreateOrUpdateIssue searches existing issues in the repository, and returns one matching or creates a new issue.
-func (repo *WorkflowRepository) CreateOrUpdateIssue(assignee *string, title *string, body *string, milestone int) (*github.Issue, error) {
+// hasAssignees indicates whether an issue is assigned to all of the usernames; "none" stands for no assignee.
+func hasAssignees(issue *github.Issue, usernames []string) bool {
+	assigned := make(map[string]bool)
+	for _, login := range issueAssignees(issue) {
+		assigned[login] = true
+	}
+	for _, username := range usernames {
+		switch {
+		case len(username) == 0:
+		case username == "none":
+			if len(assigned) > 0 {
+				return false
+			}
+		case !assigned[strings.ToLower(username)]:
+			return false
+		}
+	}
+	return true
+}
 
+// newIssueRequest prepares the request used both to search for and to create an issue.
+func newIssueRequest(assignees []string, title *string, body *string, milestone int, labels []string) github.IssueRequest {
 	request := github.IssueRequest{}
 
-	if assignee != nil {
-		var assigneeList = []string{*assignee}
-		request.Assignees = &assigneeList
+	if assignees != nil {
+		request.Assignees = &assignees
 	}
 
 	if title != nil {
@@ -339,6 +688,19 @@ func (repo *WorkflowRepository) CreateOrUpdateIssue(assignee *string, title *str
 		request.Milestone = &milestone
 	}
 
+	if len(labels) > 0 {
+		request.Labels = &labels
+	}
+
+	return request
+}
+
+// CreateOrUpdateIssue searches existing issues in the repository, and returns one matching or creates a new issue.
+// A matching issue is given the labels it lacks.
+func (repo *WorkflowRepository) CreateOrUpdateIssue(assignees []string, title *string, body *string, milestone int, labels []string) (*github.Issue, error) {
+
+	request := newIssueRequest(assignees, title, body, milestone, labels)
+
 	// log.Printf("Searching issues; assignee: %v; milestone: %v", *request.Assignees, *request.Milestone)
 
 	issuesFound, err := repo.GetIssuesByRequest(&request)
@@ -350,9 +712,13 @@ func (repo *WorkflowRepository) CreateOrUpdateIssue(assignee *string, title *str
 	}
 
 	for _, issue := range issuesFound {
-		if issue != nil {
-			return issue, nil // found a matching issue
+		if issue == nil {
+			continue
+		}
+		if edit := labelEdit(issue, labels); edit != nil {
+			return repo.updateIssue(repo.Client.getIssuesService(), issue, edit)
 		}
+		return issue, nil // found a matching issue
 	}
 
 	issue, err := repo.createIssue(repo.Client.getIssuesService(), &request)
@@ -368,22 +734,33 @@ func (repo *WorkflowRepository) CreateOrUpdateIssue(assignee *string, title *str
 	return issue, err // successfully created it (or maybe failed Edit() above)
 }
 
-// This method is an abstraction intended to be overridden by test models.
-func (client *WorkflowClient) resolveUser(username *string) *github.User {
-	// Alternate mode of declaration; this was initially used in debugging an interface handling issue.
-	// var service IGitHubUsers = client.Client.getUsersService()
-
-	service := client.Client.getUsersService()
+// currentUsername fetches the login of the authenticated GitHub user.
+func (client *WorkflowClient) currentUsername() (string, error) {
+	user, _, err := client.Client.getUsersService().Get(client.Context, "")
+	if err != nil {
+		return "", err
+	}
+	return user.GetLogin(), nil
+}
 
-	// log.Printf("Users service is: %#v", service)
+// EditIssue applies an edit (e.g. from a sync) to an existing issue.
+func (repo *WorkflowRepository) EditIssue(issue *github.Issue, request *github.IssueRequest) (*github.Issue, error) {
+	return repo.updateIssue(repo.Client.getIssuesService(), issue, request)
+}
 
-	user, _, err := service.Get(client.Context, *username) // errors here
+// CloseIssue marks an issue as closed; GitHub does not allow issues to be deleted.
+func (repo *WorkflowRepository) CloseIssue(issue *github.Issue) (*github.Issue, error) {
+	request := github.IssueRequest{State: github.String("closed")}
+	return repo.updateIssue(repo.Client.getIssuesService(), issue, &request)
+}
 
-	if err != nil { // This is deemed to be fatal in our world, as assigning issues is key functionality.
-		log.Fatalf("Failed to resolve user: %v", err)
-		panic(err)
+// resolveUser fetches a GitHub user by login, failing when there is no such user.
+func (client *WorkflowClient) resolveUser(username *string) (*github.User, error) {
+	user, _, err := client.Client.getUsersService().Get(client.Context, *username)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to resolve user '%s': %v", *username, err)
 	}
-	return user
+	return user, nil
 }
 
 // This method is an abstraction intended to be overridden by test models.
@@ -430,6 +807,42 @@ func (repo *WorkflowRepository) CreateOrUpdateMilestone(title *string, descripti
 		DueOn:       dueDate,
 	}
 
+	milestoneFound, err := repo.GetMilestoneByTitle(title)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if milestoneFound != nil {
+		return milestoneFound, nil // found one existing that matches.
+	}
+
+	milestoneCreated, err := repo.createMilestone(repo.Client.getIssuesService(), &newMilestone)
+	if err != nil {
+		return nil, err
+	}
+
+	return milestoneCreated, nil
+
+}
+
+// CloseMilestone marks a milestone as closed, keeping it (and its title) in the repository.
+func (repo *WorkflowRepository) CloseMilestone(milestone *github.Milestone) (*github.Milestone, error) {
+	owner := repo.Owner.GetLogin()
+	update := github.Milestone{State: github.String("closed")}
+	closed, _, err := repo.Client.getIssuesService().EditMilestone(repo.Context, owner, repo.GetName(), milestone.GetNumber(), &update)
+	return closed, err
+}
+
+// DeleteMilestone removes a milestone from the repository; its issues are kept.
+func (repo *WorkflowRepository) DeleteMilestone(milestone *github.Milestone) error {
+	owner := repo.Owner.GetLogin()
+	_, err := repo.Client.getIssuesService().DeleteMilestone(repo.Context, owner, repo.GetName(), milestone.GetNumber())
+	return err
+}
+
+// GetMilestoneByTitle retrieves an existing milestone by name, returning nil when none matches.
+func (repo *WorkflowRepository) GetMilestoneByTitle(title *string) (*github.Milestone, error) {
 	searchOptions := github.MilestoneListOptions{
 		Sort:      "due_date",
 		Direction: "desc",
@@ -443,17 +856,112 @@ func (repo *WorkflowRepository) CreateOrUpdateMilestone(title *string, descripti
 
 	for _, ms := range availableMilestones {
 		if ms.GetTitle() == *title {
-			return ms, nil // found one existing that matches.
+			return ms, nil
 		}
 	}
 
-	milestoneCreated, err := repo.createMilestone(repo.Client.getIssuesService(), &newMilestone)
+	return nil, nil
+}
+
+// This method is an abstraction intended to be overridden by test models.
+func (repo *WorkflowRepository) fetchLabels(service iGitHubIssues) ([]*github.Label, error) {
+	var resultLabels []*github.Label
+
+	owner := repo.Owner.GetLogin()
+	listOpts := github.ListOptions{Page: 0}
+
+	for {
+		result, response, err := service.ListLabels(repo.Context, owner, repo.GetName(), &listOpts)
+		if err != nil {
+			return nil, err
+		}
+		resultLabels = append(resultLabels, result...)
+		if response.NextPage == 0 {
+			break
+		}
+		listOpts.Page = response.NextPage
+	}
+
+	return resultLabels, nil
+}
+
+// This method is an abstraction intended to be overridden by test models.
+func (repo *WorkflowRepository) createLabel(service iGitHubIssues, label *github.Label) (*github.Label, error) {
+	owner := repo.Owner.GetLogin()
+	created, _, err := service.CreateLabel(repo.Context, owner, repo.GetName(), label)
+	return created, err
+}
+
+// This method is an abstraction intended to be overridden by test models.
+func (repo *WorkflowRepository) editLabel(service iGitHubIssues, name string, label *github.Label) (*github.Label, error) {
+	owner := repo.Owner.GetLogin()
+	edited, _, err := service.EditLabel(repo.Context, owner, repo.GetName(), name, label)
+	return edited, err
+}
+
+// FetchMappedLabels produces a map of the repository's labels, keyed by lower-cased name, as GitHub label names ignore case.
+func (repo *WorkflowRepository) FetchMappedLabels() (map[string](*github.Label), error) {
+	labelsFound, err := repo.fetchLabels(repo.Client.getIssuesService())
 	if err != nil {
 		return nil, err
 	}
 
-	return milestoneCreated, nil
+	labelsFoundMap := make(map[string](*github.Label))
+	for _, label := range labelsFound {
+		labelsFoundMap[strings.ToLower(label.GetName())] = label
+	}
+
+	return labelsFoundMap, nil
+}
+
+// labelDiffers indicates whether an existing label lacks the color or description declared for it.
+// Attributes which are not declared are left as they are.
+func labelDiffers(existing *github.Label, label LabelEntry) bool {
+	if (len(label.Color) > 0) && !strings.EqualFold(existing.GetColor(), label.Color) {
+		return true
+	}
+	return (len(label.Description) > 0) && (existing.GetDescription() != label.Description)
+}
+
+// EnsureLabels creates any missing labels in the repository, and updates those whose color or description differ.
+// It is idempotent, so it is safe to run before every generation.
+func (repo *WorkflowRepository) EnsureLabels(labels []LabelEntry) ([]*github.Label, error) {
+	var resultLabels []*github.Label
+
+	service := repo.Client.getIssuesService()
+	existing, err := repo.FetchMappedLabels()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range labels {
+		label := github.Label{Name: github.String(entry.Name)}
+		if len(entry.Color) > 0 {
+			label.Color = github.String(strings.ToLower(entry.Color))
+		}
+		if len(entry.Description) > 0 {
+			label.Description = github.String(entry.Description)
+		}
+
+		found, ok := existing[strings.ToLower(entry.Name)]
+		switch {
+		case !ok:
+			if label.Color == nil {
+				label.Color = github.String(defaultLabelColor)
+			}
+			found, err = repo.createLabel(service, &label)
+		case labelDiffers(found, entry):
+			label.Name = found.Name // keep the name's existing case
+			found, err = repo.editLabel(service, found.GetName(), &label)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to prepare label '%s': %v", entry.Name, err)
+		}
+		resultLabels = append(resultLabels, found)
+	}
 
+	return resultLabels, nil
 }
 
 // This method is an abstraction intended to be overridden by test models.
@@ -515,27 +1023,20 @@ func (repo *WorkflowRepository) createProjectColumns(service iGitHubProjects, pr
 }
 
 // CreateOrUpdateProject retrieves an existing GitHub Project by name, or creates a new one if needed.
-// The provided column names are only used when creating a project anew.
+// Columns missing from an existing project are added to the right of its columns.
 func (repo *WorkflowRepository) CreateOrUpdateProject(title *string, description *string, columns []string) (*github.Project, error) {
 
-	var projectFound *github.Project
-	var err error
 	var updateNeeded = false
 
-	listOpts := github.ProjectListOptions{}
-
 	createProjectOptions := github.ProjectOptions{
 		Name: *title,
 		Body: *description,
 	}
 
-	availableProjects, err := repo.fetchProjects(repo.Client.getRepositoriesService(), &listOpts)
+	projectFound, err := repo.GetProjectByTitle(title)
 
-	for _, proj := range availableProjects {
-		if proj.GetName() == *title {
-			projectFound = proj
-			break
-		}
+	if err != nil {
+		return nil, err
 	}
 
 	if (projectFound != nil) && (projectFound.GetNumber() > 0) {
@@ -550,6 +1051,21 @@ func (repo *WorkflowRepository) CreateOrUpdateProject(title *string, description
 				return nil, err
 			}
 		}
+
+		present, err := repo.ColumnsPresent(projectFound, columns)
+		if err != nil {
+			return nil, err
+		}
+
+		if !present {
+			existing, err := repo.FetchMappedProjectColumns(projectFound)
+			if err != nil {
+				return nil, err
+			}
+			if _, err = repo.createProjectColumns(repo.Client.getProjectsService(), projectFound, missingColumns(existing, columns)); err != nil {
+				return nil, err
+			}
+		}
 	}
 
 	if (projectFound == nil) || (projectFound.GetNumber() < 1) {
@@ -570,6 +1086,31 @@ func (repo *WorkflowRepository) CreateOrUpdateProject(title *string, description
 	return projectFound, nil
 }
 
+// DeleteProject removes a GitHub Project, along with its columns and cards.
+func (repo *WorkflowRepository) DeleteProject(project *github.Project) error {
+	_, err := repo.Client.getProjectsService().DeleteProject(repo.Context, project.GetID())
+	return err
+}
+
+// GetProjectByTitle retrieves an existing GitHub Project by name, returning nil when none matches.
+func (repo *WorkflowRepository) GetProjectByTitle(title *string) (*github.Project, error) {
+	listOpts := github.ProjectListOptions{}
+
+	availableProjects, err := repo.fetchProjects(repo.Client.getRepositoriesService(), &listOpts)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, proj := range availableProjects {
+		if proj.GetName() == *title {
+			return proj, nil
+		}
+	}
+
+	return nil, nil
+}
+
 // This method is an abstraction intended to be overridden by test models.
 func (repo *WorkflowRepository) fetchProjectColumns(service iGitHubProjects, project *github.Project) ([]*github.ProjectColumn, error) {
 	var resultColumns []*github.ProjectColumn
@@ -634,11 +1175,8 @@ func (repo *WorkflowRepository) GetFirstColumn(project *github.Project) (*github
 	return column, nil
 }
 
-// CreateCardForIssue creates a Card in a GitHub project for a given GitHub Issue
-func (repo *WorkflowRepository) CreateCardForIssue(issue *github.Issue, column *github.ProjectColumn) (*github.ProjectCard, error) {
-
-	service := repo.Client.getProjectsService()
-
+// This method is an abstraction intended to be overridden by test models.
+func (repo *WorkflowRepository) createCard(service iGitHubProjects, issue *github.Issue, column *github.ProjectColumn) (*github.ProjectCard, error) {
 	cardOpts := github.ProjectCardOptions{
 		ContentID:   issue.GetID(),
 		ContentType: "Issue",
@@ -656,6 +1194,94 @@ func (repo *WorkflowRepository) CreateCardForIssue(issue *github.Issue, column *
 	return card, nil
 }
 
+// FetchBoardCards reads the cards of every column of a GitHub Project, for CreateCardForIssue.
+func (repo *WorkflowRepository) FetchBoardCards(project *github.Project) (*BoardCards, error) {
+	columnsList, err := repo.fetchProjectColumns(repo.Client.getProjectsService(), project)
+	if err != nil {
+		return nil, err
+	}
+
+	board := newBoardCards(columnsList)
+	for index, col := range columnsList {
+		cards, err := repo.fetchProjectCards(col)
+		if err != nil {
+			return nil, err
+		}
+		for _, card := range cards {
+			board.add(card.GetContentURL(), card, index)
+		}
+	}
+	return board, nil
+}
+
+// CreateCardForIssue idempotently puts an issue's card in a column of a GitHub Project, whose cards were read into
+// board (which is kept up to date): the card is created when the issue has none, moved when it is in an earlier
+// column than the given one, and otherwise left where it is (e.g. where the hire moved it). The outcome is
+// CardCreated, CardMoved or CardPresent (see BoardCards.Placement).
+func (repo *WorkflowRepository) CreateCardForIssue(board *BoardCards, issue *github.Issue, column *github.ProjectColumn) (*github.ProjectCard, string, error) {
+	service := repo.Client.getProjectsService()
+	card, _, placement := board.Placement(issue, column)
+	switch placement {
+	case CardPresent:
+		return card, CardPresent, nil
+	case CardMoved:
+		moveOpts := github.ProjectCardMoveOptions{Position: "top", ColumnID: column.GetID()}
+		if _, err := service.MoveProjectCard(repo.Context, card.GetID(), &moveOpts); err != nil {
+			return nil, "", err
+		}
+		board.place(issue, card, column)
+		return card, CardMoved, nil
+	}
+
+	card, err := repo.createCard(service, issue, column)
+	if err != nil {
+		return nil, "", err
+	}
+	board.place(issue, card, column)
+	return card, CardCreated, nil
+}
+
+// GetCardForIssue scans every column of a GitHub Project for the card holding a given GitHub Issue.
+// The card and its column are nil when the issue is not on the project board.
+func (repo *WorkflowRepository) GetCardForIssue(project *github.Project, issue *github.Issue) (*github.ProjectCard, *github.ProjectColumn, error) {
+	if len(issue.GetURL()) == 0 {
+		return nil, nil, nil // nothing to match cards against
+	}
+
+	columnsList, err := repo.fetchProjectColumns(repo.Client.getProjectsService(), project)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, col := range columnsList {
+		cards, err := repo.fetchProjectCards(col)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, card := range cards {
+			if card.GetContentURL() == issue.GetURL() {
+				return card, col, nil
+			}
+		}
+	}
+
+	return nil, nil, nil
+}
+
+// DeleteCardForIssue removes the card holding a given GitHub Issue from a GitHub Project, if there is one.
+// It reports whether a card was removed.
+func (repo *WorkflowRepository) DeleteCardForIssue(project *github.Project, issue *github.Issue) (bool, error) {
+	card, _, err := repo.GetCardForIssue(project, issue)
+	if (err != nil) || (card == nil) {
+		return false, err
+	}
+
+	if _, err = repo.Client.getProjectsService().DeleteProjectCard(repo.Context, card.GetID()); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
 // FetchMappedProjectColumns produces a string-map of the named columns in a project.
 func (repo *WorkflowRepository) FetchMappedProjectColumns(project *github.Project) (map[string](*github.ProjectColumn), error) {
 	var columnsFoundMap map[string](*github.ProjectColumn)
@@ -673,6 +1299,17 @@ func (repo *WorkflowRepository) FetchMappedProjectColumns(project *github.Projec
 	return columnsFoundMap, nil
 }
 
+// missingColumns lists the named columns which are not among the existing ones, in order.
+func missingColumns(existing map[string](*github.ProjectColumn), columns []string) []string {
+	var missing []string
+	for _, name := range columns {
+		if _, ok := existing[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
+
 // ColumnsPresent indicates whether all the named columns are present in a project.
 func (repo *WorkflowRepository) ColumnsPresent(project *github.Project, columns []string) (bool, error) {
 	foundColumns, err := repo.fetchProjectColumns(repo.Client.getProjectsService(), project)
@@ -6,8 +6,10 @@ It requires the GitHub Client mock/fixtures implemented in `github_client_test.g
 */
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"strings"
 	"testing"
 	"time"
 
@@ -68,7 +70,8 @@ func nvl(values ...string) *string {
 func TestCreateClientAndResolveUser(t *testing.T) {
 	client := prepareGitHubClientTest()
 	testUsername := "testingisawesome"
-	user := client.resolveUser(&testUsername)
+	user, err := client.resolveUser(&testUsername)
+	assertIsNil(t, err, "Resolving a user produced an error?! %v")
 
 	verify := []struct {
 		expect string
@@ -151,7 +154,7 @@ func TestCreateIssuesMilestones(t *testing.T) {
 		title := i.title
 		assignee := i.assignee
 		description := i.description
-		thisIssue, _ := repo.CreateOrUpdateIssue(&assignee, &title, &description, milestone.GetNumber())
+		thisIssue, _ := repo.CreateOrUpdateIssue([]string{assignee}, &title, &description, milestone.GetNumber(), nil)
 		resultIssues = append(resultIssues, thisIssue)
 	}
 
@@ -224,12 +227,13 @@ func TestCreateIssuesCards(t *testing.T) {
 		title := i.title
 		assignee := i.assignee
 		description := i.description
-		thisIssue, _ := repo.CreateOrUpdateIssue(&assignee, &title, &description, 0)
+		thisIssue, _ := repo.CreateOrUpdateIssue([]string{assignee}, &title, &description, 0, nil)
 
 		// cache them...
 		resultIssues = append(resultIssues, thisIssue)
 
-		card, _ := repo.CreateCardForIssue(thisIssue, columns["backlog"])
+		board, _ := repo.FetchBoardCards(project)
+		card, _, _ := repo.CreateCardForIssue(board, thisIssue, columns["backlog"])
 		resultCards = append(resultCards, card)
 	}
 
@@ -281,3 +285,463 @@ func TestFullWorkload(t *testing.T) {
 		}
 	}
 }
+
+func TestRoleWorkload(t *testing.T) {
+	client := prepareGitHubClientTest()
+	setup := SetupScheme{
+		GithubOrganization: "testOrganization",
+		GithubRepository:   "testRepository",
+		Tasks: []TaskEntry{
+			{Title: "shared", Description: "test", Assignee: indirectAssignee{GithubUsername: "test"}},
+			{Title: "not for sre", Description: "test", Assignee: indirectAssignee{GithubUsername: "test"}},
+		},
+		Roles: map[string]RoleEntry{
+			"sre": {
+				Name:   "Site Reliability Engineer",
+				Tasks:  []TaskEntry{{Title: "sre only", Description: "test", Assignee: indirectAssignee{GithubUsername: "test"}}},
+				Remove: []string{"not for sre"},
+			},
+		},
+	}
+
+	job := GenerateProject{
+		ID:      42,
+		Setup:   &setup,
+		AuthEnv: &AuthEnvironment{workflowClient: client},
+		Role:    "sre",
+	}
+
+	for _, event := range runJobEvents(job) {
+		if event.Type == "error" {
+			t.Fatalf("Role workload failed: %s; %s", event.Text, event.Error)
+		}
+	}
+
+	issues, _ := client.Client.(TestGitHubClient).Cache["issues"].([]*github.Issue)
+	assertEqual(t, len(issues), 2, "Issues created for role, actual %d, expected %d")
+	for _, issue := range issues {
+		if issue.GetTitle() == "not for sre" {
+			t.Errorf("Issue removed by the role was created")
+		}
+	}
+
+	job.Role = "unknown"
+	events := runJobEvents(job)
+	assertEqual(t, events[len(events)-1].Type, "error", "Last event type for an unknown role, actual %v, expected %v")
+}
+
+func TestDependentWorkload(t *testing.T) {
+	client := prepareGitHubClientTest()
+	setup := SetupScheme{
+		GithubOrganization: "testOrganization",
+		GithubRepository:   "testRepository",
+		Tasks: []TaskEntry{
+			{Title: "spin up cluster", Description: "Use the CLI.", Assignee: indirectAssignee{GithubUsername: "test"},
+				DependsOn: []string{"log into tooling", "read README"}},
+			{Title: "log into tooling", Description: "test", Assignee: indirectAssignee{GithubUsername: "test"}},
+			{Title: "read README", Assignee: indirectAssignee{GithubUsername: "test"}},
+		},
+	}
+
+	job := GenerateProject{
+		ID:      42,
+		Setup:   &setup,
+		AuthEnv: &AuthEnvironment{workflowClient: client},
+	}
+
+	for _, event := range runJobEvents(job) {
+		if event.Type == "error" {
+			t.Fatalf("Dependent workload failed: %s; %s", event.Text, event.Error)
+		}
+	}
+
+	issues, _ := client.Client.(TestGitHubClient).Cache["issues"].([]*github.Issue)
+	assertEqual(t, len(issues), 3, "Issues created, actual %d, expected %d")
+	assertEqual(t, issues[2].GetTitle(), "spin up cluster", "Last issue created, actual %v, expected %v")
+	assertEqual(t, issues[2].GetBody(), "Use the CLI.\n\nBlocked by #1, #2", "Dependent issue body, actual %q, expected %q")
+	assertEqual(t, issues[0].GetBody(), "test", "Independent issue body, actual %q, expected %q")
+}
+
+func TestEnsureLabels(t *testing.T) {
+	client := prepareGitHubClientTest()
+	repo, _ := client.GetRepository("testowner", "testrepo")
+	cache := client.Client.(TestGitHubClient).Cache
+
+	labels := []LabelEntry{
+		{Name: "onboarding", Color: "0E8A16", Description: "Onboarding tasks"},
+		{Name: "docs"},
+	}
+
+	for attempt := 0; attempt < 2; attempt++ {
+		if _, err := repo.EnsureLabels(labels); err != nil {
+			t.Fatalf("EnsureLabels attempt %d produced an error?! %v", attempt, err)
+		}
+	}
+
+	created, _ := cache["labels"].([]*github.Label)
+	assertEqual(t, len(created), 2, "Labels created, actual %d, expected %d")
+	assertEqual(t, created[0].GetColor(), "0e8a16", "Declared label color, actual %v, expected %v")
+	assertEqual(t, created[1].GetColor(), defaultLabelColor, "Default label color, actual %v, expected %v")
+
+	// Existing labels are matched regardless of case, and only declared attributes are updated.
+	labels = []LabelEntry{{Name: "Onboarding", Color: "fbca04"}, {Name: "DOCS"}}
+	if _, err := repo.EnsureLabels(labels); err != nil {
+		t.Fatalf("EnsureLabels produced an error?! %v", err)
+	}
+
+	created, _ = cache["labels"].([]*github.Label)
+	assertEqual(t, len(created), 2, "Labels after update, actual %d, expected %d")
+	assertEqual(t, created[0].GetName(), "onboarding", "Updated label name, actual %v, expected %v")
+	assertEqual(t, created[0].GetColor(), "fbca04", "Updated label color, actual %v, expected %v")
+	assertEqual(t, created[0].GetDescription(), "Onboarding tasks", "Kept label description, actual %v, expected %v")
+	assertEqual(t, created[1].GetColor(), defaultLabelColor, "Undeclared color is kept, actual %v, expected %v")
+}
+
+func TestLabeledWorkload(t *testing.T) {
+	client := prepareGitHubClientTest()
+	setup := SetupScheme{
+		GithubOrganization: "testOrganization",
+		GithubRepository:   "testRepository",
+		Labels:             []LabelEntry{{Name: "onboarding", Color: "0e8a16"}},
+		Tasks: []TaskEntry{
+			{Title: "read docs", Description: "test", Assignee: indirectAssignee{GithubUsername: "test"},
+				Labels: []LabelEntry{{Name: "docs"}}},
+			{Title: "plain", Description: "test", Assignee: indirectAssignee{GithubUsername: "test"}},
+		},
+	}
+
+	job := GenerateProject{
+		ID:      42,
+		Setup:   &setup,
+		AuthEnv: &AuthEnvironment{workflowClient: client},
+	}
+
+	for _, event := range runJobEvents(job) {
+		if event.Type == "error" {
+			t.Fatalf("Labeled workload failed: %s; %s", event.Text, event.Error)
+		}
+	}
+
+	cache := client.Client.(TestGitHubClient).Cache
+	labels, _ := cache["labels"].([]*github.Label)
+	assertEqual(t, len(labels), 2, "Labels created, actual %d, expected %d")
+
+	issues, _ := cache["issues"].([]*github.Issue)
+	assertEqual(t, len(issues[0].Labels), 2, "Labels of the first issue, actual %d, expected %d")
+	assertEqual(t, issues[0].Labels[1].GetName(), "docs", "Task label, actual %v, expected %v")
+	assertEqual(t, len(issues[1].Labels), 1, "Labels of the second issue, actual %d, expected %d")
+
+	plan, err := job.Plan()
+	if err != nil {
+		t.Fatalf("Plan produced an error?! %v", err)
+	}
+	for _, change := range plan.Changes {
+		if change.Action != PlanUnchanged {
+			t.Errorf("Expected no changes after a labeled workload, found: %s", change)
+		}
+	}
+}
+
+func TestCreateCardIdempotent(t *testing.T) {
+	client := prepareGitHubClientTest()
+	repo, _ := client.GetRepository("testowner", "testrepo")
+
+	projectName := "testproject"
+	projectDescription := "this is a test project. super awesome."
+	project, _ := repo.CreateOrUpdateProject(&projectName, &projectDescription, []string{"Backlog", "In Progress", "Review", "Done"})
+	columns, _ := repo.FetchMappedProjectColumns(project)
+
+	assignee, title, description := "testuser1", "Issue #1", "First Issue"
+	issue, _ := repo.CreateOrUpdateIssue([]string{assignee}, &title, &description, 0, nil)
+
+	board, err := repo.FetchBoardCards(project)
+	assertIsNil(t, err, "Fetching the board's cards produced an error?! %v")
+	card, placed, err := repo.CreateCardForIssue(board, issue, columns["Backlog"])
+	assertIsNil(t, err, "Creating a card produced an error?! %v")
+	assertEqual(t, placed, CardCreated, "First placement, actual %v, expected %v")
+
+	again, placed, err := repo.CreateCardForIssue(board, issue, columns["Backlog"])
+	assertIsNil(t, err, "Placing a card again produced an error?! %v")
+	assertEqual(t, placed, CardPresent, "Second placement, actual %v, expected %v")
+	assertEqual(t, again.GetID(), card.GetID(), "Card placed again, actual %v, expected %v")
+
+	// Cards only move forward, e.g. from Backlog to In Progress, but never back.
+	_, placed, _ = repo.CreateCardForIssue(board, issue, columns["In Progress"])
+	assertEqual(t, placed, CardMoved, "Placement in a later column, actual %v, expected %v")
+	found, column, _ := repo.GetCardForIssue(project, issue)
+	assertEqual(t, found.GetID(), card.GetID(), "Moved card, actual %v, expected %v")
+	assertEqual(t, column.GetName(), "In Progress", "Column of the moved card, actual %v, expected %v")
+
+	board, _ = repo.FetchBoardCards(project)
+	_, placed, _ = repo.CreateCardForIssue(board, issue, columns["Backlog"])
+	assertEqual(t, placed, CardPresent, "Placement in an earlier column, actual %v, expected %v")
+	_, column, _ = repo.GetCardForIssue(project, issue)
+	assertEqual(t, column.GetName(), "In Progress", "Column of the card left in place, actual %v, expected %v")
+}
+
+func TestRepeatedWorkloadKeepsCards(t *testing.T) {
+	client := prepareGitHubClientTest()
+	job := GenerateProject{
+		ID:      42,
+		Setup:   preparePlanSetup(),
+		AuthEnv: &AuthEnvironment{workflowClient: client},
+	}
+
+	assertNoErrorEvents(t, runJobEvents(job), "Workload failed")
+	events := runJobEvents(job)
+	assertNoErrorEvents(t, events, "Repeated workload failed")
+	assertEqual(t, countEvents(events, "Already on board - "), len(job.Setup.Tasks), "Cards already on board, actual %d, expected %d")
+
+	cards := 0
+	for key, value := range client.Client.(TestGitHubClient).Cache {
+		if list, ok := value.([]*github.ProjectCard); ok && strings.HasPrefix(key, "cards/column/") {
+			cards += len(list)
+		}
+	}
+	assertEqual(t, cards, len(job.Setup.Tasks), "Cards after a repeated workload, actual %d, expected %d")
+}
+
+func TestConfiguredBoardWorkload(t *testing.T) {
+	client := prepareGitHubClientTest()
+	setup := preparePlanSetup()
+	setup.Columns = []ColumnEntry{{Name: "To Do", Preset: PresetTodo}, {Name: "Doing"}, {Name: "Done", Preset: PresetDone}}
+	setup.Tasks[1].Column = "Doing"
+
+	job := GenerateProject{
+		ID:      42,
+		Setup:   setup,
+		AuthEnv: &AuthEnvironment{workflowClient: client},
+	}
+	assertNoErrorEvents(t, runJobEvents(job), "Workload failed")
+
+	repo, _ := client.GetRepository("testOrganization", "testRepository")
+	title := welcomeTitle(job.AuthEnv.Username())
+	project, _ := repo.GetProjectByTitle(&title)
+	columns, _ := repo.FetchMappedProjectColumns(project)
+	assertEqual(t, len(columns), 3, "Project columns, actual %d, expected %d")
+
+	issues, _ := client.Client.(TestGitHubClient).Cache["issues"].([]*github.Issue)
+	for index, expected := range []string{"To Do", "Doing"} {
+		_, column, err := repo.GetCardForIssue(project, issues[index])
+		assertIsNil(t, err, "Finding a card produced an error?! %v")
+		assertEqual(t, column.GetName(), expected, "Column of the card, actual %v, expected %v")
+	}
+
+	// Columns added to the layout later are added to the existing project.
+	setup.Columns = append(setup.Columns[:2], ColumnEntry{Name: "Review"}, setup.Columns[2])
+	plan, err := job.Plan()
+	if err != nil {
+		t.Fatalf("Plan produced an error?! %v", err)
+	}
+	assertEqual(t, plan.Count(PlanCreate), 1, "Planned creations, actual %d, expected %d")
+
+	assertNoErrorEvents(t, runJobEvents(job), "Repeated workload failed")
+	present, _ := repo.ColumnsPresent(project, setup.ColumnNames())
+	assert(t, present, "Expected the Review column to be added to the project")
+	columns, _ = repo.FetchMappedProjectColumns(project)
+	assertEqual(t, len(columns), 4, "Project columns after adding one, actual %d, expected %d")
+}
+
+func TestUnknownColumnWorkload(t *testing.T) {
+	client := prepareGitHubClientTest()
+	store := testCheckpointStore{}
+	setup := preparePlanSetup()
+	setup.Tasks[1].Column = "Nowhere"
+
+	job := GenerateProject{
+		ID:          42,
+		Setup:       setup,
+		AuthEnv:     &AuthEnvironment{workflowClient: client},
+		Checkpoints: store,
+	}
+	events := runJobEvents(job)
+	assertEqual(t, countEvents(events, "Error creating card - no column Nowhere"), 1, "Errors of the task without a column, actual %d, expected %d")
+	assertEqual(t, events[len(events)-1].Type, "complete", "Last event of the workload, actual %v, expected %v")
+
+	issues, _ := client.Client.(TestGitHubClient).Cache["issues"].([]*github.Issue)
+	assertEqual(t, len(issues), 2, "Issues created, actual %d, expected %d")
+	assertEqual(t, len(store), 1, "Checkpoints of a workload with a card missing, actual %d, expected %d")
+}
+
+func TestPhasedWorkload(t *testing.T) {
+	client := prepareGitHubClientTest()
+	setup := preparePlanSetup()
+	setup.Schedule = ScheduleEntry{Timezone: "UTC"}
+	setup.Phases = []PhaseEntry{
+		{Name: "week 1", Title: "Week 1 @$username", Due: 5},
+		{Name: "month 1", Title: "Month 1 @$username", Due: 20},
+	}
+	setup.Tasks[1].Phase = "month 1"
+
+	job := GenerateProject{
+		ID:        42,
+		Setup:     setup,
+		AuthEnv:   &AuthEnvironment{workflowClient: client},
+		StartDate: "2017-07-03",
+	}
+	assertNoErrorEvents(t, runJobEvents(job), "Workload failed")
+
+	repo, _ := client.GetRepository("testOrganization", "testRepository")
+	username := job.AuthEnv.Username()
+	issues, _ := client.Client.(TestGitHubClient).Cache["issues"].([]*github.Issue)
+	for index, phase := range setup.Phases {
+		title := phase.MilestoneTitle(username)
+		milestone, _ := repo.GetMilestoneByTitle(&title)
+		if milestone == nil {
+			t.Fatalf("Expected a milestone for phase %s", phase.Name)
+		}
+		assertEqual(t, issues[index].Milestone.GetNumber(), milestone.GetNumber(), "Milestone of the phase's issue, actual %v, expected %v")
+	}
+	week, _ := repo.GetMilestoneByTitle(github.String(setup.Phases[0].MilestoneTitle(username)))
+	assertEqual(t, week.GetDueOn().Format(dateLayout), "2017-07-07", "Due date of the first phase, actual %v, expected %v")
+
+	// Moving a task to another phase moves its issue to that phase's milestone, on sync.
+	setup.Tasks[1].Phase = "week 1"
+	job.Sync = true
+	plan, err := job.Plan()
+	assertIsNil(t, err, "Plan produced an error?! %v")
+	assertEqual(t, plan.Count(PlanUpdate), 1, "Planned updates, actual %d, expected %d")
+	assertEqual(t, plan.Count(PlanCreate), 0, "Planned creations, actual %d, expected %d")
+
+	assertNoErrorEvents(t, runJobEvents(job), "Sync failed")
+	issues, _ = client.Client.(TestGitHubClient).Cache["issues"].([]*github.Issue)
+	assertEqual(t, len(issues), 2, "Issues after a sync, actual %d, expected %d")
+	assertEqual(t, issues[1].Milestone.GetNumber(), week.GetNumber(), "Milestone of the moved issue, actual %v, expected %v")
+
+	// Teardown covers the milestones of every phase.
+	setup.Tasks[1].Phase = "month 1"
+	events := runTeardownEvents(TeardownProject{ID: 42, Setup: setup, AuthEnv: job.AuthEnv, Purge: true})
+	assertNoErrorEvents(t, events, "Teardown failed")
+	assertEqual(t, countEvents(events, "Deleting Milestone - "), 2, "Milestones deleted, actual %d, expected %d")
+}
+
+func TestManagerWorkload(t *testing.T) {
+	client := prepareGitHubClientTest()
+	cache := client.Client.(TestGitHubClient).Cache
+	store := testCheckpointStore{}
+	setup := preparePlanSetup()
+	setup.Tasks[0].Assignee.GithubUsername = "$username"
+
+	job := GenerateProject{
+		ID:          42,
+		Setup:       setup,
+		AuthEnv:     &AuthEnvironment{workflowClient: client},
+		Hire:        "newhire",
+		Checkpoints: store,
+	}
+
+	login, err := job.AuthEnv.ResolveUsername("newhire")
+	assertIsNil(t, err, "Resolving the hire produced an error?! %v")
+	assertEqual(t, login, "newhire", "Login of the hire, actual %v, expected %v")
+
+	plan, err := job.Plan()
+	assertIsNil(t, err, "Plan produced an error?! %v")
+	assertEqual(t, plan.Changes[0].Title, "Welcome @newhire!", "Planned milestone, actual %v, expected %v")
+
+	events := runJobEvents(job)
+	assertNoErrorEvents(t, events, "Workload failed")
+	assert(t, strings.Contains(events[0].Text, "for @newhire"), "Expected the start event to name the hire, found %q", events[0].Text)
+
+	repo, _ := client.GetRepository("testOrganization", "testRepository")
+	milestone, _ := repo.GetMilestoneByTitle(github.String("Welcome @newhire!"))
+	assert(t, milestone != nil, "Expected a milestone named after the hire")
+	project, _ := repo.GetProjectByTitle(github.String("Welcome @newhire!"))
+	assert(t, project != nil, "Expected a project named after the hire")
+
+	issues, _ := cache["issues"].([]*github.Issue)
+	assertEqual(t, issues[0].Assignees[0].GetLogin(), "newhire", "Assignee of the hire's task, actual %v, expected %v")
+	assertEqual(t, issues[1].Assignees[0].GetLogin(), "test", "Assignee of another owner's task, actual %v, expected %v")
+
+	// Unknown hires are reported before anything is generated.
+	cache["unknownUser"] = "nobody"
+	job.Hire = "nobody"
+	_, err = job.AuthEnv.ResolveUsername("nobody")
+	assert(t, err != nil, "Expected an error resolving an unknown user")
+	events = runJobEvents(job)
+	assertEqual(t, events[len(events)-1].Type, "error", "Last event for an unknown hire, actual %v, expected %v")
+	assertEqual(t, countEvents(events, "Creating Milestone"), 0, "Milestones created for an unknown hire, actual %d, expected %d")
+}
+
+func TestMultipleOwnersWorkload(t *testing.T) {
+	client := prepareGitHubClientTest()
+	cache := client.Client.(TestGitHubClient).Cache
+	setup := preparePlanSetup()
+	setup.Tasks[0].Owners = []indirectAssignee{{GithubUsername: "buddy"}, {GithubUsername: "manager"}}
+
+	job := GenerateProject{ID: 42, Setup: setup, AuthEnv: &AuthEnvironment{workflowClient: client}}
+	assertNoErrorEvents(t, runJobEvents(job), "Workload failed")
+
+	issues, _ := cache["issues"].([]*github.Issue)
+	assertEqual(t, strings.Join(issueAssignees(issues[0]), ","), "buddy,manager,test", "Assignees of the issue, actual %v, expected %v")
+
+	// Issues are only found when assigned to all the owners.
+	repo, _ := client.GetRepository("testOrganization", "testRepository")
+	request := newIssueRequest([]string{"test", "buddy"}, github.String("test1"), nil, 0, nil)
+	found, _ := repo.GetIssuesByRequest(&request)
+	assertEqual(t, len(found), 1, "Issues assigned to the owners, actual %d, expected %d")
+	request = newIssueRequest([]string{"test", "someone"}, github.String("test1"), nil, 0, nil)
+	found, _ = repo.GetIssuesByRequest(&request)
+	assertEqual(t, len(found), 0, "Issues assigned to other owners, actual %d, expected %d")
+
+	// Syncing after an owner leaves the task unassigns them.
+	setup.Tasks[0].Owners = setup.Tasks[0].Owners[:1]
+	job.Sync = true
+	plan, err := job.Plan()
+	assertIsNil(t, err, "Plan produced an error?! %v")
+	assertEqual(t, plan.Count(PlanUpdate), 1, "Planned updates, actual %d, expected %d")
+	assertNoErrorEvents(t, runJobEvents(job), "Sync failed")
+	issues, _ = cache["issues"].([]*github.Issue)
+	assertEqual(t, strings.Join(issueAssignees(issues[0]), ","), "buddy,test", "Assignees after syncing, actual %v, expected %v")
+
+	// Unknown owners fail the job before any issue is created.
+	cache["unknownUser"] = "manager"
+	setup.Tasks[1].Owners = []indirectAssignee{{GithubUsername: "manager"}}
+	_, err = job.Plan()
+	assert(t, err != nil, "Expected a plan error for an unknown owner")
+	events := runJobEvents(job)
+	assertEqual(t, events[len(events)-1].Type, "error", "Last event for an unknown owner, actual %v, expected %v")
+	assertEqual(t, countEvents(events, "Preparing Issue"), 0, "Issues prepared for an unknown owner, actual %d, expected %d")
+}
+
+func TestCancelWorkload(t *testing.T) {
+	client := prepareGitHubClientTest()
+	store := testCheckpointStore{}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	job := GenerateProject{
+		ID:          42,
+		Context:     ctx,
+		Setup:       preparePlanSetup(),
+		AuthEnv:     &AuthEnvironment{workflowClient: client},
+		Checkpoints: store,
+	}
+
+	// The job waits for each event to be received, so it is cancelled while creating the milestone.
+	var events []jobs.Event
+	channel := make(chan jobs.Event)
+	job.New = channel
+	go job.Run()
+	for event := range channel {
+		if strings.HasPrefix(event.Text, "Creating Milestone") {
+			cancel()
+		}
+		events = append(events, event)
+	}
+
+	assertNoErrorEvents(t, events, "Cancelled workload failed")
+	assertEqual(t, events[len(events)-1].Type, "cancelled", "Last event of a cancelled run, actual %v, expected %v")
+	assertEqual(t, countEvents(events, "Creating Project"), 0, "Projects created once cancelled, actual %d, expected %d")
+
+	issues, _ := client.Client.(TestGitHubClient).Cache["issues"].([]*github.Issue)
+	assertEqual(t, len(issues), 0, "Issues created once cancelled, actual %d, expected %d")
+	assertEqual(t, len(store), 1, "Checkpoints of a cancelled run, actual %d, expected %d")
+
+	job.Context = context.Background()
+	job.Resume = true
+	events = runJobEvents(job)
+	assertNoErrorEvents(t, events, "Resuming a cancelled workload failed")
+	assertEqual(t, countEvents(events, "Creating Milestone"), 0, "Milestones created on resume, actual %d, expected %d")
+	assertEqual(t, countEvents(events, "Preparing Issue"), 2, "Issues prepared on resume, actual %d, expected %d")
+	assertEqual(t, len(store), 0, "Checkpoints of a completed run, actual %d, expected %d")
+}
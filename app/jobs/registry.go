@@ -0,0 +1,195 @@
+package jobs
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/revel/cron"
+)
+
+// Run is the execution of a job, decoupled from the clients following it: its events are logged with their sequence
+// numbers, so that a client reconnecting (e.g. after a page reload) can replay the events it missed.
+type Run struct {
+	mutex   sync.Mutex
+	events  []Event
+	done    bool
+	changed chan struct{} // closed, and replaced, whenever an event is logged or the job ends
+	cancel  context.CancelFunc
+	kind    string
+	owner   string
+	ended   time.Time
+}
+
+// Registry keeps the runs of jobs by key (e.g. per hire), so that at most one job runs under a key at a time.
+// Finished runs are dropped once they have ended for longer than its TTL.
+type Registry struct {
+	mutex sync.Mutex
+	runs  map[string]*Run
+	start func(job cron.Job)
+	ttl   time.Duration
+}
+
+// FinishedRunTTL is how long a registry keeps the runs of jobs which have ended, for clients to reattach to them.
+const FinishedRunTTL = time.Hour
+
+// NewRegistry creates an empty registry, starting its jobs with StartJob.
+func NewRegistry() *Registry {
+	return &Registry{runs: make(map[string]*Run), start: StartJob, ttl: FinishedRunTTL}
+}
+
+// Start returns the run of the job under key: the one in progress if any, or else (when reattach is set, for a client
+// which was following it) the last one of that kind; otherwise it starts a new job, made by newJob with the context
+// cancelling it and the channel of its events. It indicates whether a new job was started. As jobs of several kinds
+// may share a key (e.g. the workload and the teardown of a hire), the run in progress may be of another kind than
+// asked for, which callers check with Kind. The run records its owner (e.g. the user starting it; see Owner). When
+// reattaching to a job which is no longer known (e.g. after a restart, or once its run was dropped; see
+// FinishedRunTTL), no job is started, and the run is nil.
+func (registry *Registry) Start(key string, kind string, owner string, reattach bool, newJob func(ctx context.Context, events chan<- Event) cron.Job) (*Run, bool) {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	registry.prune()
+
+	run, ok := registry.runs[key]
+	if ok && (!run.Done() || (reattach && (run.kind == kind))) {
+		return run, false
+	}
+	if reattach {
+		return nil, false
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events := make(chan Event)
+	run = &Run{changed: make(chan struct{}), cancel: cancel, kind: kind, owner: owner}
+	registry.runs[key] = run
+
+	registry.start(newJob(ctx, events))
+	go func() {
+		defer cancel()
+		for event := range events {
+			run.log(event)
+		}
+		run.finish()
+	}()
+	return run, true
+}
+
+// Get returns the last run under key, or nil when there is none (or it was dropped; see FinishedRunTTL).
+func (registry *Registry) Get(key string) *Run {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	registry.prune()
+	return registry.runs[key]
+}
+
+// prune drops the runs which have ended for longer than the registry's TTL. The registry must be locked.
+func (registry *Registry) prune() {
+	for key, run := range registry.runs {
+		if run.endedBefore(time.Now().Add(-registry.ttl)) {
+			delete(registry.runs, key)
+		}
+	}
+}
+
+func (run *Run) log(event Event) {
+	run.mutex.Lock()
+	defer run.mutex.Unlock()
+
+	event.Sequence = len(run.events) + 1
+	run.events = append(run.events, event)
+	close(run.changed)
+	run.changed = make(chan struct{})
+}
+
+func (run *Run) finish() {
+	run.mutex.Lock()
+	defer run.mutex.Unlock()
+
+	run.done = true
+	run.ended = time.Now()
+	close(run.changed)
+	run.changed = make(chan struct{})
+}
+
+// Since returns the events logged after the given sequence number, whether the job has ended, and a channel closed
+// once there is more to follow.
+func (run *Run) Since(sequence int) ([]Event, bool, <-chan struct{}) {
+	run.mutex.Lock()
+	defer run.mutex.Unlock()
+
+	if sequence < 0 {
+		sequence = 0
+	}
+	var events []Event
+	if sequence < len(run.events) {
+		events = append(events, run.events[sequence:]...)
+	}
+	return events, run.done, run.changed
+}
+
+// Wait returns the events logged after the given sequence number, waiting up to timeout for one when there are none
+// yet, and whether the job has ended.
+func (run *Run) Wait(sequence int, timeout time.Duration) ([]Event, bool) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		events, done, changed := run.Since(sequence)
+		if (len(events) > 0) || done {
+			return events, done
+		}
+		select {
+		case <-changed:
+		case <-timer.C:
+			return nil, false
+		}
+	}
+}
+
+// Status summarizes the run: "running", or once the job has ended, "completed", "cancelled" or "failed", after its
+// last event.
+func (run *Run) Status() string {
+	run.mutex.Lock()
+	defer run.mutex.Unlock()
+
+	if !run.done {
+		return "running"
+	}
+	if len(run.events) > 0 {
+		switch run.events[len(run.events)-1].Type {
+		case "complete":
+			return "completed"
+		case "cancelled":
+			return "cancelled"
+		}
+	}
+	return "failed"
+}
+
+// Done indicates whether the job has ended.
+func (run *Run) Done() bool {
+	run.mutex.Lock()
+	defer run.mutex.Unlock()
+	return run.done
+}
+
+func (run *Run) endedBefore(deadline time.Time) bool {
+	run.mutex.Lock()
+	defer run.mutex.Unlock()
+	return run.done && run.ended.Before(deadline)
+}
+
+// Kind returns the kind of the job, as given to Start.
+func (run *Run) Kind() string {
+	return run.kind
+}
+
+// Owner returns who started the job.
+func (run *Run) Owner() string {
+	return run.owner
+}
+
+// Cancel cancels the job's context, asking it to stop.
+func (run *Run) Cancel() {
+	run.cancel()
+}
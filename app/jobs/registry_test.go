@@ -0,0 +1,238 @@
+package jobs
+
+/*
+This module's tests focus on exercising the `registry.go` module.
+*/
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/revel/cron"
+)
+
+// stepsJob emits a progress event per step, waiting to be released (or cancelled) before each one.
+type stepsJob struct {
+	ctx     context.Context
+	events  chan<- Event
+	steps   int
+	release chan struct{}
+}
+
+func (job stepsJob) Run() {
+	defer close(job.events)
+	for step := 0; step < job.steps; step++ {
+		select {
+		case <-job.ctx.Done():
+			job.events <- NewEvent(0, "cancelled", "Cancelled")
+			return
+		case <-job.release:
+		}
+		job.events <- NewEvent(0, "progress", "Step")
+	}
+	job.events <- NewEvent(0, "complete", "Done")
+}
+
+func newTestRegistry() *Registry {
+	registry := NewRegistry()
+	registry.start = func(job cron.Job) { go job.Run() }
+	return registry
+}
+
+// waitEvents waits for the run to have logged at least count events, and returns them.
+func waitEvents(t *testing.T, run *Run, count int) []Event {
+	for {
+		events, _, changed := run.Since(0)
+		if len(events) >= count {
+			return events
+		}
+		select {
+		case <-changed:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Only %d events of %d were logged", len(events), count)
+		}
+	}
+}
+
+// waitDone waits for the run to end.
+func waitDone(t *testing.T, run *Run) {
+	for {
+		_, done, changed := run.Since(0)
+		if done {
+			return
+		}
+		select {
+		case <-changed:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("The job did not end")
+		}
+	}
+}
+
+func TestRegistryReplay(t *testing.T) {
+	registry := newTestRegistry()
+	release := make(chan struct{})
+	newJob := func(ctx context.Context, events chan<- Event) cron.Job {
+		return stepsJob{ctx: ctx, events: events, steps: 2, release: release}
+	}
+
+	run, started := registry.Start("workload/octocat", "workload", "octocat", false, newJob)
+	if !started {
+		t.Fatalf("Expected a new job to be started")
+	}
+	if again, started := registry.Start("workload/octocat", "workload", "octocat", false, newJob); started || (again != run) {
+		t.Errorf("Expected the job in progress, rather than a second one")
+	}
+
+	if kind := run.Kind(); kind != "workload" {
+		t.Errorf("Kind of the job, actual %s, expected workload", kind)
+	}
+	if owner := run.Owner(); owner != "octocat" {
+		t.Errorf("Owner of the job, actual %s, expected octocat", owner)
+	}
+	if status := run.Status(); status != "running" {
+		t.Errorf("Status of the job in progress, actual %s, expected running", status)
+	}
+
+	release <- struct{}{}
+	events := waitEvents(t, run, 1)
+	if events[0].Sequence != 1 {
+		t.Errorf("Sequence of the first event, actual %d, expected 1", events[0].Sequence)
+	}
+
+	release <- struct{}{}
+	waitDone(t, run)
+
+	events, done, _ := run.Since(1)
+	if !done || (len(events) != 2) {
+		t.Fatalf("Events since the first one, actual %d (done %v), expected 2", len(events), done)
+	}
+	if (events[0].Sequence != 2) || (events[1].Sequence != 3) || (events[1].Type != "complete") {
+		t.Errorf("Replayed events, actual %+v", events)
+	}
+	if status := run.Status(); status != "completed" {
+		t.Errorf("Status of the completed job, actual %s, expected completed", status)
+	}
+	if events, _, _ := run.Since(3); len(events) != 0 {
+		t.Errorf("Events since the last one, actual %d, expected none", len(events))
+	}
+
+	if again, started := registry.Start("workload/octocat", "workload", "octocat", true, newJob); started || (again != run) {
+		t.Errorf("Expected to reattach to the finished job")
+	}
+	if next, started := registry.Start("workload/octocat", "workload", "octocat", false, newJob); !started || (next == run) {
+		t.Errorf("Expected a new job once the last one has ended")
+	}
+	if missing, started := registry.Start("teardown/octocat", "teardown", "octocat", true, newJob); started || (missing != nil) {
+		t.Errorf("Expected no job when reattaching to an unknown one")
+	}
+}
+
+func TestRegistryKinds(t *testing.T) {
+	registry := newTestRegistry()
+	release := make(chan struct{})
+	newJob := func(ctx context.Context, events chan<- Event) cron.Job {
+		return stepsJob{ctx: ctx, events: events, steps: 1, release: release}
+	}
+
+	workload, _ := registry.Start("onboarding/octocat", "workload", "octocat", false, newJob)
+	if run, started := registry.Start("onboarding/octocat", "teardown", "octocat", false, newJob); started || (run != workload) {
+		t.Errorf("Expected the workload in progress, rather than a teardown")
+	}
+
+	release <- struct{}{}
+	waitDone(t, workload)
+
+	if run, started := registry.Start("onboarding/octocat", "teardown", "octocat", true, newJob); started || (run != nil) {
+		t.Errorf("Expected no job when reattaching to a teardown after a workload")
+	}
+	teardown, started := registry.Start("onboarding/octocat", "teardown", "octocat", false, newJob)
+	if !started || (teardown.Kind() != "teardown") {
+		t.Errorf("Expected a teardown once the workload has ended")
+	}
+	release <- struct{}{}
+	waitDone(t, teardown)
+}
+
+func TestRegistryDropsFinishedRuns(t *testing.T) {
+	registry := newTestRegistry()
+	registry.ttl = 100 * time.Millisecond
+	release := make(chan struct{})
+	newJob := func(ctx context.Context, events chan<- Event) cron.Job {
+		return stepsJob{ctx: ctx, events: events, steps: 1, release: release}
+	}
+
+	run, _ := registry.Start("workload/octocat", "workload", "octocat", false, newJob)
+	time.Sleep(200 * time.Millisecond)
+	if registry.Get("workload/octocat") != run {
+		t.Errorf("Expected the job in progress to be kept")
+	}
+
+	release <- struct{}{}
+	waitDone(t, run)
+	if registry.Get("workload/octocat") != run {
+		t.Errorf("Expected the finished job to be kept for a while")
+	}
+	time.Sleep(200 * time.Millisecond)
+	if registry.Get("workload/octocat") != nil {
+		t.Errorf("Expected the finished job to be dropped")
+	}
+	if again, started := registry.Start("workload/octocat", "workload", "octocat", true, newJob); started || (again != nil) {
+		t.Errorf("Expected no job when reattaching to a dropped one")
+	}
+}
+
+func TestRunWait(t *testing.T) {
+	registry := newTestRegistry()
+	release := make(chan struct{})
+	run, _ := registry.Start("workload/octocat", "workload", "octocat", false, func(ctx context.Context, events chan<- Event) cron.Job {
+		return stepsJob{ctx: ctx, events: events, steps: 1, release: release}
+	})
+
+	if events, done := run.Wait(0, 10*time.Millisecond); (len(events) != 0) || done {
+		t.Errorf("Waiting without events, actual %d (done %v), expected none", len(events), done)
+	}
+
+	go func() { release <- struct{}{} }()
+	events, done := run.Wait(0, 5*time.Second)
+	if (len(events) == 0) || (events[0].Type != "progress") {
+		t.Fatalf("Waiting for the first event, actual %+v", events)
+	}
+
+	// The last event may be logged before the job is known to have ended.
+	last := events[len(events)-1]
+	for !done {
+		events, done = run.Wait(last.Sequence, 5*time.Second)
+		if (len(events) == 0) && !done {
+			t.Fatalf("The job did not end")
+		}
+		if len(events) > 0 {
+			last = events[len(events)-1]
+		}
+	}
+	if last.Type != "complete" {
+		t.Errorf("Last event waited for, actual %+v", last)
+	}
+}
+
+func TestRegistryCancel(t *testing.T) {
+	registry := newTestRegistry()
+	run, _ := registry.Start("workload/octocat", "workload", "octocat", false, func(ctx context.Context, events chan<- Event) cron.Job {
+		return stepsJob{ctx: ctx, events: events, steps: 1, release: make(chan struct{})}
+	})
+
+	run.Cancel()
+	waitDone(t, run)
+
+	events, _, _ := run.Since(0)
+	if (len(events) != 1) || (events[0].Type != "cancelled") {
+		t.Errorf("Events of a cancelled job, actual %+v", events)
+	}
+	if status := run.Status(); status != "cancelled" {
+		t.Errorf("Status of the cancelled job, actual %s, expected cancelled", status)
+	}
+	if registry.Get("workload/octocat") != run {
+		t.Errorf("Expected the cancelled job to remain the last one")
+	}
+}
@@ -0,0 +1,320 @@
+package models
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/samsung-cnct/container-technical-on-boarding/app/jobs/onboarding"
+	bolt "go.etcd.io/bbolt"
+	"golang.org/x/oauth2"
+)
+
+var (
+	usersBucket       = []byte("users")
+	checkpointsBucket = []byte("checkpoints")
+	buddiesBucket     = []byte("buddies")
+)
+
+type (
+	// BoltStore persists users in an embedded BoltDB file, with OAuth tokens encrypted at rest.
+	// It also persists the checkpoints of onboarding jobs, as an onboarding.CheckpointStore,
+	// and the hires' buddies, as an onboarding.BuddyStore.
+	BoltStore struct {
+		db    *bolt.DB
+		key   []byte
+		creds *onboarding.Credentials
+	}
+
+	// userRecord is the stored form of a User.
+	userRecord struct {
+		ID       int    `json:"id"`
+		Username string `json:"username,omitempty"`
+		State    string `json:"state,omitempty"`
+		Token    []byte `json:"token,omitempty"` // sealed JSON of an oauth2.Token
+	}
+)
+
+// NewBoltStore opens (or creates) a BoltDB file of users.
+// Tokens are encrypted with a key derived from secret; creds are used to restore each user's AuthEnvironment.
+func NewBoltStore(filename string, secret string, creds *onboarding.Credentials) (*BoltStore, error) {
+	if len(secret) == 0 {
+		return nil, errors.New("a secret is required to encrypt stored tokens")
+	}
+
+	db, err := bolt.Open(filename, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{usersBucket, checkpointsBucket, buddiesBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	key := sha256.Sum256([]byte(secret))
+	return &BoltStore{db: db, key: key[:], creds: creds}, nil
+}
+
+func userKey(id int) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(id))
+	return key
+}
+
+// NewUser creates a new user, with an ID from the store's sequence.
+func (store *BoltStore) NewUser() (*User, error) {
+	var user *User
+
+	err := store.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(usersBucket)
+		id, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+		user = &User{ID: int(id)}
+		return store.put(bucket, user)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// GetUser returns a User by id, or nil when there is no such user.
+func (store *BoltStore) GetUser(id int) (*User, error) {
+	var record *userRecord
+
+	err := store.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(usersBucket).Get(userKey(id))
+		if data == nil {
+			return nil
+		}
+		record = &userRecord{}
+		return json.Unmarshal(data, record)
+	})
+
+	if (err != nil) || (record == nil) {
+		return nil, err
+	}
+	return store.restore(record)
+}
+
+// SaveUser stores the user, including the state and token of its AuthEnvironment.
+func (store *BoltStore) SaveUser(user *User) error {
+	return store.db.Update(func(tx *bolt.Tx) error {
+		return store.put(tx.Bucket(usersBucket), user)
+	})
+}
+
+// GetCheckpoint returns a checkpoint by key, or nil when there is none.
+func (store *BoltStore) GetCheckpoint(key string) (*onboarding.Checkpoint, error) {
+	var checkpoint *onboarding.Checkpoint
+
+	err := store.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(checkpointsBucket).Get([]byte(key))
+		if data == nil {
+			return nil
+		}
+		checkpoint = &onboarding.Checkpoint{}
+		return json.Unmarshal(data, checkpoint)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+	return checkpoint, nil
+}
+
+// SaveCheckpoint stores a checkpoint by its key.
+func (store *BoltStore) SaveCheckpoint(checkpoint *onboarding.Checkpoint) error {
+	data, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+	return store.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(checkpointsBucket).Put([]byte(checkpoint.Key), data)
+	})
+}
+
+// DeleteCheckpoint removes a checkpoint, if present.
+func (store *BoltStore) DeleteCheckpoint(key string) error {
+	return store.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(checkpointsBucket).Delete([]byte(key))
+	})
+}
+
+// GetBuddyAssignment returns a buddy assignment by key, or nil when there is none.
+func (store *BoltStore) GetBuddyAssignment(key string) (*onboarding.BuddyAssignment, error) {
+	var assignment *onboarding.BuddyAssignment
+
+	err := store.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(buddiesBucket).Get([]byte(key))
+		if data == nil {
+			return nil
+		}
+		assignment = &onboarding.BuddyAssignment{}
+		return json.Unmarshal(data, assignment)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+	return assignment, nil
+}
+
+// ListBuddyAssignments returns all the buddy assignments, by key.
+func (store *BoltStore) ListBuddyAssignments() ([]onboarding.BuddyAssignment, error) {
+	var assignments []onboarding.BuddyAssignment
+
+	err := store.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(buddiesBucket).ForEach(func(key []byte, data []byte) error {
+			assignment := onboarding.BuddyAssignment{}
+			if err := json.Unmarshal(data, &assignment); err != nil {
+				return err
+			}
+			assignments = append(assignments, assignment)
+			return nil
+		})
+	})
+
+	if err != nil {
+		return nil, err
+	}
+	return assignments, nil
+}
+
+// SaveBuddyAssignment stores a buddy assignment by its key.
+func (store *BoltStore) SaveBuddyAssignment(assignment *onboarding.BuddyAssignment) error {
+	data, err := json.Marshal(assignment)
+	if err != nil {
+		return err
+	}
+	return store.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(buddiesBucket).Put([]byte(assignment.Key), data)
+	})
+}
+
+// DeleteBuddyAssignment removes a buddy assignment, if present.
+func (store *BoltStore) DeleteBuddyAssignment(key string) error {
+	return store.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(buddiesBucket).Delete([]byte(key))
+	})
+}
+
+// Close the underlying BoltDB file.
+func (store *BoltStore) Close() error {
+	return store.db.Close()
+}
+
+func (store *BoltStore) put(bucket *bolt.Bucket, user *User) error {
+	record := userRecord{
+		ID:       user.ID,
+		Username: user.Username,
+	}
+
+	if user.AuthEnv != nil {
+		record.State = user.AuthEnv.StateString
+		if user.AuthEnv.AccessToken != nil {
+			sealed, err := store.sealToken(user.AuthEnv.AccessToken)
+			if err != nil {
+				return err
+			}
+			record.Token = sealed
+		}
+	}
+
+	data, err := json.Marshal(&record)
+	if err != nil {
+		return err
+	}
+	return bucket.Put(userKey(user.ID), data)
+}
+
+func (store *BoltStore) restore(record *userRecord) (*User, error) {
+	user := User{
+		ID:       record.ID,
+		Username: record.Username,
+	}
+
+	if (len(record.State) == 0) && (record.Token == nil) {
+		return &user, nil // never started an OAuth login
+	}
+
+	var token *oauth2.Token
+	if record.Token != nil {
+		var err error
+		if token, err = store.openToken(record.Token); err != nil {
+			return nil, fmt.Errorf("Cannot decrypt the token of user %d: %v", record.ID, err)
+		}
+	}
+
+	user.AuthEnv = store.creds.RestoreAuthEnvironment(record.State, token)
+	return &user, nil
+}
+
+func (store *BoltStore) sealToken(token *oauth2.Token) ([]byte, error) {
+	plaintext, err := json.Marshal(token)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := store.cipher()
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func (store *BoltStore) openToken(sealed []byte) (*oauth2.Token, error) {
+	gcm, err := store.cipher()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("sealed token is too short")
+	}
+
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	token := oauth2.Token{}
+	if err = json.Unmarshal(plaintext, &token); err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (store *BoltStore) cipher() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(store.key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,229 @@
+package models
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/samsung-cnct/container-technical-on-boarding/app/jobs/onboarding"
+	bolt "go.etcd.io/bbolt"
+	"golang.org/x/oauth2"
+)
+
+var testCredentials = &onboarding.Credentials{
+	ClientID:     "TEST_CLIENT_ID",
+	ClientSecret: "TEST_CLIENT_SECRET",
+	Scopes:       []string{"user", "repo"},
+}
+
+func prepareBoltStore(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "onboarding-store")
+	if err != nil {
+		t.Fatalf("Cannot create temporary directory: %v", err)
+	}
+	return filepath.Join(dir, "users.db"), func() { os.RemoveAll(dir) }
+}
+
+func TestBoltStorePersistsUsers(t *testing.T) {
+	filename, cleanup := prepareBoltStore(t)
+	defer cleanup()
+
+	store, err := NewBoltStore(filename, "test secret", testCredentials)
+	if err != nil {
+		t.Fatalf("NewBoltStore produced an error?! %v", err)
+	}
+
+	user, err := store.NewUser()
+	if err != nil {
+		t.Fatalf("NewUser produced an error?! %v", err)
+	}
+
+	user.Username = "testuser"
+	user.AuthEnv = testCredentials.RestoreAuthEnvironment("test-state", &oauth2.Token{AccessToken: "very-secret-token"})
+	if err = store.SaveUser(user); err != nil {
+		t.Fatalf("SaveUser produced an error?! %v", err)
+	}
+	store.Close()
+
+	// Reopen, as if the server restarted.
+	store, err = NewBoltStore(filename, "test secret", testCredentials)
+	if err != nil {
+		t.Fatalf("NewBoltStore could not reopen the store: %v", err)
+	}
+	defer store.Close()
+
+	found, err := store.GetUser(user.ID)
+	if (err != nil) || (found == nil) {
+		t.Fatalf("GetUser did not find user %d: %v", user.ID, err)
+	}
+
+	if !found.Authenticated() {
+		t.Fatalf("Restored user should be authenticated")
+	}
+	assertEqual(t, found.Username, "testuser", "Restored username, actual %v, expected %v")
+	assertEqual(t, found.AuthEnv.StateString, "test-state", "Restored state, actual %v, expected %v")
+	assertEqual(t, found.AuthEnv.AccessToken.AccessToken, "very-secret-token", "Restored token, actual %v, expected %v")
+
+	missing, err := store.GetUser(user.ID + 1000)
+	if (err != nil) || (missing != nil) {
+		t.Errorf("Expected no user and no error, found %v, %v", missing, err)
+	}
+
+	// The token must not be stored in the clear.
+	store.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(usersBucket).Get(userKey(user.ID))
+		if bytes.Contains(data, []byte("very-secret-token")) {
+			t.Errorf("Token is stored unencrypted: %s", data)
+		}
+		return nil
+	})
+}
+
+func TestBoltStoreRejectsWrongSecret(t *testing.T) {
+	filename, cleanup := prepareBoltStore(t)
+	defer cleanup()
+
+	store, _ := NewBoltStore(filename, "test secret", testCredentials)
+	user, _ := store.NewUser()
+	user.AuthEnv = testCredentials.RestoreAuthEnvironment("", &oauth2.Token{AccessToken: "token"})
+	store.SaveUser(user)
+	store.Close()
+
+	store, _ = NewBoltStore(filename, "another secret", testCredentials)
+	defer store.Close()
+
+	if _, err := store.GetUser(user.ID); err == nil {
+		t.Errorf("Expected an error decrypting a token with the wrong secret")
+	}
+}
+
+func TestBoltStoreUniqueIDs(t *testing.T) {
+	filename, cleanup := prepareBoltStore(t)
+	defer cleanup()
+
+	store, _ := NewBoltStore(filename, "test secret", testCredentials)
+	defer store.Close()
+
+	var mutex sync.Mutex
+	var wait sync.WaitGroup
+	ids := make(map[int]bool)
+
+	for i := 0; i < 50; i++ {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			user, err := store.NewUser()
+			if err != nil {
+				t.Errorf("NewUser produced an error?! %v", err)
+				return
+			}
+			mutex.Lock()
+			defer mutex.Unlock()
+			if ids[user.ID] {
+				t.Errorf("Duplicate user ID %d", user.ID)
+			}
+			ids[user.ID] = true
+		}()
+	}
+	wait.Wait()
+
+	assertEqual(t, len(ids), 50, "Unique user IDs, actual %d, expected %d")
+}
+
+func assertEqual(t *testing.T, value1 interface{}, value2 interface{}, message string) {
+	if value1 != value2 {
+		t.Errorf(message, value1, value2)
+	}
+}
+
+func TestCheckpointStores(t *testing.T) {
+	filename, cleanup := prepareBoltStore(t)
+	defer cleanup()
+
+	boltStore, _ := NewBoltStore(filename, "test secret", testCredentials)
+	defer boltStore.Close()
+
+	stores := map[string]onboarding.CheckpointStore{"bolt": boltStore, "memory": NewMemoryStore()}
+	for name, store := range stores {
+		key := onboarding.CheckpointKey("org", "repo", "newhire")
+
+		missing, err := store.GetCheckpoint(key)
+		if (err != nil) || (missing != nil) {
+			t.Errorf("%s: expected no checkpoint and no error, found %v, %v", name, missing, err)
+		}
+
+		checkpoint := onboarding.NewCheckpoint(key, "sre")
+		checkpoint.Milestones["Welcome @newhire!"] = 3
+		checkpoint.ColumnIDs["Backlog"] = 11
+		checkpoint.Issues["test1"] = onboarding.CheckpointIssue{ID: 101, Number: 1, Card: true}
+		if err = store.SaveCheckpoint(checkpoint); err != nil {
+			t.Fatalf("%s: SaveCheckpoint produced an error?! %v", name, err)
+		}
+		checkpoint.Issues["test2"] = onboarding.CheckpointIssue{ID: 102, Number: 2} // not saved
+
+		found, err := store.GetCheckpoint(key)
+		if (err != nil) || (found == nil) {
+			t.Fatalf("%s: GetCheckpoint did not find %s: %v", name, key, err)
+		}
+		assertEqual(t, found.Role, "sre", name+": checkpoint role, actual %v, expected %v")
+		assertEqual(t, found.Milestones["Welcome @newhire!"], 3, name+": checkpoint milestone, actual %v, expected %v")
+		assertEqual(t, found.ColumnIDs["Backlog"], 11, name+": checkpoint column, actual %v, expected %v")
+		assertEqual(t, len(found.Issues), 1, name+": checkpoint issues, actual %v, expected %v")
+		assertEqual(t, found.Issues["test1"], onboarding.CheckpointIssue{ID: 101, Number: 1, Card: true}, name+": checkpoint issue, actual %v, expected %v")
+
+		if err = store.DeleteCheckpoint(key); err != nil {
+			t.Errorf("%s: DeleteCheckpoint produced an error?! %v", name, err)
+		}
+		if found, _ = store.GetCheckpoint(key); found != nil {
+			t.Errorf("%s: expected the checkpoint to be deleted, found %v", name, found)
+		}
+	}
+}
+
+func TestBuddyStores(t *testing.T) {
+	filename, cleanup := prepareBoltStore(t)
+	defer cleanup()
+
+	boltStore, _ := NewBoltStore(filename, "test secret", testCredentials)
+	defer boltStore.Close()
+
+	stores := map[string]onboarding.BuddyStore{"bolt": boltStore, "memory": NewMemoryStore()}
+	for name, store := range stores {
+		key := onboarding.CheckpointKey("org", "repo", "newhire")
+
+		missing, err := store.GetBuddyAssignment(key)
+		if (err != nil) || (missing != nil) {
+			t.Errorf("%s: expected no assignment and no error, found %v, %v", name, missing, err)
+		}
+
+		assignment := onboarding.BuddyAssignment{Key: key, Hire: "newhire", Buddy: "alice", Role: "sre"}
+		if err = store.SaveBuddyAssignment(&assignment); err != nil {
+			t.Fatalf("%s: SaveBuddyAssignment produced an error?! %v", name, err)
+		}
+		other := onboarding.BuddyAssignment{Key: onboarding.CheckpointKey("org", "repo", "other"), Hire: "other", Buddy: "bob"}
+		store.SaveBuddyAssignment(&other)
+
+		found, err := store.GetBuddyAssignment(key)
+		if (err != nil) || (found == nil) {
+			t.Fatalf("%s: GetBuddyAssignment did not find %s: %v", name, key, err)
+		}
+		assertEqual(t, found.Buddy, "alice", name+": buddy, actual %v, expected %v")
+		assertEqual(t, found.Role, "sre", name+": role, actual %v, expected %v")
+
+		all, err := store.ListBuddyAssignments()
+		if err != nil {
+			t.Errorf("%s: ListBuddyAssignments produced an error?! %v", name, err)
+		}
+		assertEqual(t, len(all), 2, name+": assignments, actual %d, expected %d")
+
+		if err = store.DeleteBuddyAssignment(key); err != nil {
+			t.Errorf("%s: DeleteBuddyAssignment produced an error?! %v", name, err)
+		}
+		if found, _ = store.GetBuddyAssignment(key); found != nil {
+			t.Errorf("%s: expected the assignment to be deleted, found %v", name, found)
+		}
+	}
+}
@@ -1,28 +1,174 @@
 package models
 
 import (
-	"math/rand"
+	"sync"
 
 	"github.com/samsung-cnct/container-technical-on-boarding/app/jobs/onboarding"
 )
 
-// User model object to manage user authentication
-type User struct {
-	ID       int
-	Username string
-	AuthEnv  *onboarding.AuthEnvironment
+type (
+	// User model object to manage user authentication
+	User struct {
+		ID       int
+		Username string
+		AuthEnv  *onboarding.AuthEnvironment
+	}
+
+	// UserStore persists users across requests. Implementations must be safe for concurrent use.
+	UserStore interface {
+		// NewUser creates and stores a user with a unique ID.
+		NewUser() (*User, error)
+		// GetUser returns a User by id, or nil when there is no such user.
+		GetUser(id int) (*User, error)
+		// SaveUser stores the changes made to a user.
+		SaveUser(user *User) error
+		// Close releases the resources held by the store.
+		Close() error
+	}
+
+	// MemoryStore keeps users (and checkpoints, and buddy assignments) in memory only; they are lost on restart.
+	MemoryStore struct {
+		mutex       sync.RWMutex
+		lastID      int
+		users       map[int]*User
+		checkpoints map[string]onboarding.Checkpoint
+		buddies     map[string]onboarding.BuddyAssignment
+	}
+)
+
+// Authenticated indicates whether the user has completed the OAuth login.
+func (user *User) Authenticated() bool {
+	return (user.AuthEnv != nil) && (user.AuthEnv.AccessToken != nil)
+}
+
+// NewMemoryStore creates an empty in-memory UserStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		users:       make(map[int]*User),
+		checkpoints: make(map[string]onboarding.Checkpoint),
+		buddies:     make(map[string]onboarding.BuddyAssignment),
+	}
 }
 
-var db = make(map[int]*User)
+// NewUser creates a new user
+func (store *MemoryStore) NewUser() (*User, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.lastID++
+	user := &User{ID: store.lastID}
+	store.users[user.ID] = user
+	return user, nil
+}
 
 // GetUser returns a User by id
-func GetUser(id int) *User {
-	return db[id]
+func (store *MemoryStore) GetUser(id int) (*User, error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	return store.users[id], nil
 }
 
-// NewUser creates a new user
-func NewUser() *User {
-	user := &User{ID: rand.Intn(10000)}
-	db[user.ID] = user
-	return user
+// SaveUser stores a user by id
+func (store *MemoryStore) SaveUser(user *User) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.users[user.ID] = user
+	return nil
+}
+
+// GetCheckpoint returns a copy of a checkpoint by key, or nil when there is none.
+func (store *MemoryStore) GetCheckpoint(key string) (*onboarding.Checkpoint, error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	checkpoint, ok := store.checkpoints[key]
+	if !ok {
+		return nil, nil
+	}
+	return copyCheckpoint(&checkpoint), nil
+}
+
+// SaveCheckpoint stores a copy of a checkpoint by its key.
+func (store *MemoryStore) SaveCheckpoint(checkpoint *onboarding.Checkpoint) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.checkpoints[checkpoint.Key] = *copyCheckpoint(checkpoint)
+	return nil
+}
+
+// DeleteCheckpoint removes a checkpoint, if present.
+func (store *MemoryStore) DeleteCheckpoint(key string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	delete(store.checkpoints, key)
+	return nil
+}
+
+// copyCheckpoint copies the maps of a checkpoint too, as a running job keeps updating them.
+func copyCheckpoint(checkpoint *onboarding.Checkpoint) *onboarding.Checkpoint {
+	result := *checkpoint
+	result.Milestones = make(map[string]int)
+	for title, number := range checkpoint.Milestones {
+		result.Milestones[title] = number
+	}
+	result.ColumnIDs = make(map[string]int)
+	for name, id := range checkpoint.ColumnIDs {
+		result.ColumnIDs[name] = id
+	}
+	result.Issues = make(map[string]onboarding.CheckpointIssue)
+	for title, issue := range checkpoint.Issues {
+		result.Issues[title] = issue
+	}
+	return &result
+}
+
+// GetBuddyAssignment returns a copy of a buddy assignment by key, or nil when there is none.
+func (store *MemoryStore) GetBuddyAssignment(key string) (*onboarding.BuddyAssignment, error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	assignment, ok := store.buddies[key]
+	if !ok {
+		return nil, nil
+	}
+	return &assignment, nil
+}
+
+// ListBuddyAssignments returns copies of all the buddy assignments.
+func (store *MemoryStore) ListBuddyAssignments() ([]onboarding.BuddyAssignment, error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	var assignments []onboarding.BuddyAssignment
+	for _, assignment := range store.buddies {
+		assignments = append(assignments, assignment)
+	}
+	return assignments, nil
+}
+
+// SaveBuddyAssignment stores a copy of a buddy assignment by its key.
+func (store *MemoryStore) SaveBuddyAssignment(assignment *onboarding.BuddyAssignment) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.buddies[assignment.Key] = *assignment
+	return nil
+}
+
+// DeleteBuddyAssignment removes a buddy assignment, if present.
+func (store *MemoryStore) DeleteBuddyAssignment(key string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	delete(store.buddies, key)
+	return nil
+}
+
+// Close is a no-op for the in-memory store.
+func (store *MemoryStore) Close() error {
+	return nil
 }
@@ -0,0 +1,174 @@
+/*
+The onboarding command runs tasks of the onboarding service from the command line, e.g. in pre-merge checks.
+
+Usage:
+
+	onboarding <command> [arguments]
+
+The commands are:
+
+	lint    validate a setup scheme (e.g. onboarding-issues.yaml), reporting every problem found
+	run     generate the onboarding of users (e.g. in bulk), printing its events as text or JSON lines
+
+The setup scheme's template variables (e.g. "onboard.org") are read from the environment, as ONBOARD_ORG, and from
+the files given with -env (in the format of template.env); -set sets one directly. run acts with the GitHub (or
+GitLab, per ONBOARD_PROVIDER) token in ONBOARD_TOKEN.
+*/
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/samsung-cnct/container-technical-on-boarding/app/jobs/onboarding"
+)
+
+// command runs with its arguments, returning the process' exit status.
+type command func(args []string, stdout io.Writer, stderr io.Writer) int
+
+var commands = map[string]command{
+	"lint": lint,
+	"run":  run,
+}
+
+func main() {
+	if (len(os.Args) < 2) || (commands[os.Args[1]] == nil) {
+		usage(os.Stderr)
+		os.Exit(2)
+	}
+	os.Exit(commands[os.Args[1]](os.Args[2:], os.Stdout, os.Stderr))
+}
+
+func usage(out io.Writer) {
+	var names []string
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Fprintf(out, "Usage: onboarding <command> [arguments]\n\nThe commands are: %s\n", strings.Join(names, ", "))
+}
+
+// environFlags collects the setup scheme's template variables.
+type environFlags struct {
+	files []string
+	sets  []string
+}
+
+func (flags *environFlags) register(set *flag.FlagSet) {
+	set.Var((*stringList)(&flags.files), "env", "a file of environment variables, e.g. template.env (repeatable)")
+	set.Var((*stringList)(&flags.sets), "set", "a template variable, as name=value, e.g. onboard.org=samsung-cnct (repeatable)")
+}
+
+// environ returns the template variables: those of the environment, then of the files, then those set directly.
+func (flags *environFlags) environ(environment []string) (map[string]string, error) {
+	environ := make(map[string]string)
+	addVariables(environ, environment)
+
+	for _, filename := range flags.files {
+		lines, err := readEnvFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		addVariables(environ, lines)
+	}
+
+	for _, set := range flags.sets {
+		pair := strings.SplitN(set, "=", 2)
+		if len(pair) != 2 {
+			return nil, fmt.Errorf("Invalid -set '%s'; expected name=value", set)
+		}
+		environ[pair[0]] = pair[1]
+	}
+	return environ, nil
+}
+
+// addVariables adds the ONBOARD_* variables of NAME=value pairs, named as in conf/app.conf (e.g. onboard.org).
+func addVariables(environ map[string]string, pairs []string) {
+	for _, pair := range pairs {
+		name := strings.SplitN(pair, "=", 2)
+		if (len(name) != 2) || !strings.HasPrefix(name[0], "ONBOARD_") {
+			continue
+		}
+		environ[strings.Replace(strings.ToLower(name[0]), "_", ".", -1)] = name[1]
+	}
+}
+
+// readEnvFile reads the NAME=value lines of a file, skipping blank lines and comments.
+func readEnvFile(filename string) ([]string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if (len(line) > 0) && !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+	return lines, scanner.Err()
+}
+
+// lint validates setup schemes, printing each problem as "file:line: field: message".
+func lint(args []string, stdout io.Writer, stderr io.Writer) int {
+	set := flag.NewFlagSet("lint", flag.ContinueOnError)
+	set.SetOutput(stderr)
+	var flags environFlags
+	flags.register(set)
+	set.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: onboarding lint [-env file] [-set name=value] <setup scheme>...\n")
+		set.PrintDefaults()
+	}
+	if err := set.Parse(args); err != nil {
+		return 2
+	}
+	if set.NArg() == 0 {
+		set.Usage()
+		return 2
+	}
+
+	environ, err := flags.environ(os.Environ())
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+
+	status := 0
+	for _, filename := range set.Args() {
+		_, err := onboarding.NewSetupScheme(filename, &environ)
+		switch errs := err.(type) {
+		case nil:
+			fmt.Fprintf(stdout, "%s: OK\n", filename)
+		case onboarding.ConfigErrors:
+			for _, problem := range errs {
+				fmt.Fprintln(stdout, problem)
+			}
+			status = 1
+		default:
+			fmt.Fprintf(stdout, "%s: %v\n", filename, err)
+			status = 1
+		}
+	}
+	return status
+}
+
+// stringList is a repeatable flag.
+type stringList []string
+
+func (list *stringList) String() string {
+	return strings.Join(*list, ",")
+}
+
+// Set implements flag.Value.
+func (list *stringList) Set(value string) error {
+	*list = append(*list, value)
+	return nil
+}
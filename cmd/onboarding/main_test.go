@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEnviron(t *testing.T) {
+	dir, err := ioutil.TempDir("", "onboarding")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	envFile := filepath.Join(dir, "test.env")
+	ioutil.WriteFile(envFile, []byte("# comment\nONBOARD_ORG=from-file\n\nONBOARD_CLIENT_ID=id\n"), 0600)
+
+	flags := environFlags{files: []string{envFile}, sets: []string{"onboard.repo=from-set"}}
+	environ, err := flags.environ([]string{"ONBOARD_ORG=from-env", "ONBOARD_REPO=from-env", "HOME=/root"})
+	if err != nil {
+		t.Fatalf("environ produced an error?! %v", err)
+	}
+
+	expected := map[string]string{"onboard.org": "from-file", "onboard.repo": "from-set", "onboard.client.id": "id"}
+	if len(environ) != len(expected) {
+		t.Errorf("Variables, actual %v, expected %v", environ, expected)
+	}
+	for name, value := range expected {
+		if environ[name] != value {
+			t.Errorf("Variable %s, actual %q, expected %q", name, environ[name], value)
+		}
+	}
+
+	flags = environFlags{sets: []string{"onboard.repo"}}
+	if _, err = flags.environ(nil); err == nil {
+		t.Errorf("Expected an error for an invalid -set")
+	}
+}
+
+func TestLint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "onboarding")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	valid := filepath.Join(dir, "valid.yaml")
+	ioutil.WriteFile(valid, []byte("githubOrganization: \"{{ index .Environ \"onboard.org\" }}\"\ntasks:\n    - title: one\n"), 0600)
+	invalid := filepath.Join(dir, "invalid.yaml")
+	ioutil.WriteFile(invalid, []byte("tasks:\n    - title: one\n    - title: one\n"), 0600)
+
+	var stdout, stderr bytes.Buffer
+	status := lint([]string{"-set", "onboard.org=org", valid}, &stdout, &stderr)
+	if (status != 0) || (stdout.String() != valid+": OK\n") {
+		t.Errorf("Linting a valid scheme, actual %d %q, expected 0", status, stdout.String())
+	}
+
+	stdout.Reset()
+	status = lint([]string{valid, invalid}, &stdout, &stderr)
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	if (status != 1) || (len(lines) != 2) {
+		t.Fatalf("Linting invalid schemes, actual %d %q, expected 1 and 2 problems", status, stdout.String())
+	}
+	if lines[0] != valid+":1: Unresolved variable 'onboard.org'" {
+		t.Errorf("First problem, actual %q", lines[0])
+	}
+	if lines[1] != invalid+":3:tasks[1].title: Task 'one' is declared more than once" {
+		t.Errorf("Second problem, actual %q", lines[1])
+	}
+
+	if status = lint(nil, &stdout, &stderr); status != 2 {
+		t.Errorf("Linting without a scheme, actual %d, expected 2", status)
+	}
+}
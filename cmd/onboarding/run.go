@@ -0,0 +1,198 @@
+/*
+This module implements the run command, which generates the onboarding of users from the command line (e.g. in bulk,
+from HR tooling's exports) as the web app's workload page does, and prints their events. Their progress and buddies
+are kept in the same kind of store as the server's, so that a failed run can be resumed.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+
+	"github.com/samsung-cnct/container-technical-on-boarding/app/jobs"
+	"github.com/samsung-cnct/container-technical-on-boarding/app/jobs/onboarding"
+	"github.com/samsung-cnct/container-technical-on-boarding/app/models"
+	"golang.org/x/oauth2"
+)
+
+// runFlags are the options of the run command.
+type runFlags struct {
+	environFlags
+	tokenEnv     string
+	organization string
+	repository   string
+	role         string
+	start        string
+	store        string
+	dryRun       bool
+	sync         bool
+	resume       bool
+	json         bool
+}
+
+// storeSecret encrypts the tokens of the users of a store, which the run command neither saves nor reads; see
+// models.NewBoltStore.
+const storeSecret = "onboarding run"
+
+// hireEvent is an event of a hire's onboarding, as printed by run -json.
+type hireEvent struct {
+	Hire string `json:"hire"`
+	jobs.Event
+}
+
+// run generates the onboarding of each of the given users in turn, as the web app's workload page does, with the
+// token of the environment (see -token-env). Their events are printed as text, or as JSON lines; an interrupt
+// cancels the onboarding in progress after its current step, and skips the others. Their progress and buddies are
+// recorded in the BoltDB file of -store (e.g. the server's onboard.store.file, while the server is stopped), or else
+// in memory, so that the hires still take turns with the buddies.
+func run(args []string, stdout io.Writer, stderr io.Writer) int {
+	set := flag.NewFlagSet("run", flag.ContinueOnError)
+	set.SetOutput(stderr)
+	var flags runFlags
+	flags.register(set)
+	set.StringVar(&flags.tokenEnv, "token-env", "ONBOARD_TOKEN", "the environment variable holding the GitHub (or GitLab) token to act with")
+	set.StringVar(&flags.organization, "org", "", "the organization (or GitLab group) of the repository, overriding the setup scheme's")
+	set.StringVar(&flags.repository, "repo", "", "the repository, overriding the setup scheme's")
+	set.StringVar(&flags.role, "role", "", "the role of the hires, when the setup scheme declares roles")
+	set.StringVar(&flags.start, "start", "", "the hires' first day, as YYYY-MM-DD (today by default)")
+	set.BoolVar(&flags.dryRun, "dry-run", false, "print what would change in the repository, without changing it")
+	set.StringVar(&flags.store, "store", "", "the BoltDB file recording the onboardings' progress and buddies, as the server's onboard.store.file")
+	set.BoolVar(&flags.sync, "sync", false, "edit existing issues where they have drifted from the setup scheme")
+	set.BoolVar(&flags.resume, "resume", false, "resume the failed onboardings from their checkpoints in -store")
+	set.BoolVar(&flags.json, "json", false, "print the events as JSON lines")
+	set.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: onboarding run [flags] <setup scheme> <username>...\n")
+		set.PrintDefaults()
+	}
+	if err := set.Parse(args); err != nil {
+		return 2
+	}
+	if set.NArg() < 2 {
+		set.Usage()
+		return 2
+	}
+	if flags.resume && (len(flags.store) == 0) {
+		fmt.Fprintln(stderr, "-resume requires the -store of the onboardings' checkpoints")
+		return 2
+	}
+
+	token := os.Getenv(flags.tokenEnv)
+	if len(token) == 0 {
+		fmt.Fprintf(stderr, "The token is required, in %s\n", flags.tokenEnv)
+		return 2
+	}
+	environ, err := flags.environ(os.Environ())
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	setup, err := onboarding.NewSetupScheme(set.Arg(0), &environ)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	if len(flags.organization) > 0 {
+		setup.GithubOrganization = flags.organization
+	}
+	if len(flags.repository) > 0 {
+		setup.GithubRepository = flags.repository
+	}
+
+	credentials := onboarding.Credentials{Provider: environ["onboard.provider"], BaseURL: environ["onboard.gitlab.url"]}
+	if err = onboarding.ValidateProvider(credentials.Provider, credentials.BaseURL); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	auth := credentials.RestoreAuthEnvironment("", &oauth2.Token{AccessToken: token})
+
+	var checkpoints onboarding.CheckpointStore
+	var buddies onboarding.BuddyStore
+	if len(flags.store) > 0 {
+		store, err := models.NewBoltStore(flags.store, storeSecret, &credentials)
+		if err != nil {
+			fmt.Fprintf(stderr, "Cannot open the store '%s': %v\n", flags.store, err)
+			return 1
+		}
+		defer store.Close()
+		checkpoints, buddies = store, store
+	} else {
+		store := models.NewMemoryStore()
+		checkpoints, buddies = store, store
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+	go func() {
+		if _, ok := <-interrupt; ok {
+			cancel()
+		}
+	}()
+
+	status := 0
+	for _, hire := range set.Args()[1:] {
+		if ctx.Err() != nil {
+			fmt.Fprintf(stderr, "Skipped the onboarding of %s\n", hire)
+			status = 1
+			continue
+		}
+
+		events := make(chan jobs.Event)
+		job := onboarding.GenerateProject{
+			Context:     ctx,
+			Setup:       setup,
+			AuthEnv:     auth,
+			New:         events,
+			Role:        flags.role,
+			DryRun:      flags.dryRun,
+			Sync:        flags.sync,
+			Resume:      flags.resume,
+			StartDate:   flags.start,
+			Hire:        hire,
+			Checkpoints: checkpoints,
+			Buddies:     buddies,
+		}
+		if !followJob(job, hire, events, stdout, flags.json) {
+			status = 1
+		}
+	}
+	return status
+}
+
+// followJob runs a job, printing its events until it ends, and indicates whether it completed.
+func followJob(job onboarding.GenerateProject, hire string, events <-chan jobs.Event, out io.Writer, asJSON bool) bool {
+	go job.Run()
+
+	completed := true
+	sequence := 0
+	for event := range events {
+		sequence++
+		event.Sequence = sequence
+		printEvent(out, hire, event, asJSON)
+		if (event.Type == "error") || (event.Type == "cancelled") {
+			completed = false
+		}
+	}
+	return completed
+}
+
+// printEvent prints an event of a hire's onboarding, as a line of text or of JSON.
+func printEvent(out io.Writer, hire string, event jobs.Event, asJSON bool) {
+	if asJSON {
+		json.NewEncoder(out).Encode(hireEvent{Hire: hire, Event: event})
+		return
+	}
+	if len(event.Error) > 0 {
+		fmt.Fprintf(out, "%s %s: %s: %s\n", hire, event.Type, event.Text, event.Error)
+		return
+	}
+	fmt.Fprintf(out, "%s %s: %s\n", hire, event.Type, event.Text)
+}
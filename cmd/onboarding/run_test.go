@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/samsung-cnct/container-technical-on-boarding/app/jobs"
+)
+
+func TestRunArguments(t *testing.T) {
+	dir, err := ioutil.TempDir("", "onboarding")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	scheme := filepath.Join(dir, "tasks.yaml")
+	ioutil.WriteFile(scheme, []byte("tasks:\n    - title: one\n    - title: one\n"), 0600)
+
+	var stdout, stderr bytes.Buffer
+	if status := run([]string{scheme}, &stdout, &stderr); status != 2 {
+		t.Errorf("Running without usernames, actual %d, expected 2", status)
+	}
+
+	stderr.Reset()
+	status := run([]string{"-token-env", "ONBOARD_TEST_MISSING_TOKEN", scheme, "octocat"}, &stdout, &stderr)
+	if (status != 2) || !strings.Contains(stderr.String(), "ONBOARD_TEST_MISSING_TOKEN") {
+		t.Errorf("Running without a token, actual %d %q, expected 2", status, stderr.String())
+	}
+
+	os.Setenv("ONBOARD_TEST_TOKEN", "token")
+	defer os.Unsetenv("ONBOARD_TEST_TOKEN")
+	stderr.Reset()
+	status = run([]string{"-token-env", "ONBOARD_TEST_TOKEN", scheme, "octocat"}, &stdout, &stderr)
+	if (status != 1) || !strings.Contains(stderr.String(), "declared more than once") {
+		t.Errorf("Running an invalid scheme, actual %d %q, expected 1", status, stderr.String())
+	}
+
+	stderr.Reset()
+	status = run([]string{"-token-env", "ONBOARD_TEST_TOKEN", "-resume", scheme, "octocat"}, &stdout, &stderr)
+	if (status != 2) || !strings.Contains(stderr.String(), "-store") {
+		t.Errorf("Resuming without a store, actual %d %q, expected 2", status, stderr.String())
+	}
+
+	ioutil.WriteFile(scheme, []byte("tasks:\n    - title: one\n"), 0600)
+	stderr.Reset()
+	store := filepath.Join(dir, "missing", "onboarding.db")
+	status = run([]string{"-token-env", "ONBOARD_TEST_TOKEN", "-store", store, scheme, "octocat"}, &stdout, &stderr)
+	if (status != 1) || !strings.Contains(stderr.String(), "Cannot open the store") {
+		t.Errorf("Running with a store which cannot be opened, actual %d %q, expected 1", status, stderr.String())
+	}
+}
+
+func TestPrintEvent(t *testing.T) {
+	var out bytes.Buffer
+	printEvent(&out, "octocat", jobs.Event{Type: "progress", Text: "Created milestone"}, false)
+	printEvent(&out, "octocat", jobs.Event{Type: "error", Text: "Failed to connect", Error: "timeout"}, false)
+	expected := "octocat progress: Created milestone\noctocat error: Failed to connect: timeout\n"
+	if out.String() != expected {
+		t.Errorf("Events as text, actual %q, expected %q", out.String(), expected)
+	}
+
+	out.Reset()
+	printEvent(&out, "octocat", jobs.Event{Type: "complete", Text: "Done", Sequence: 3}, true)
+	var printed map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &printed); err != nil {
+		t.Fatalf("Event as JSON, actual %q: %v", out.String(), err)
+	}
+	if (printed["hire"] != "octocat") || (printed["Type"] != "complete") || (printed["Sequence"] != 3.0) {
+		t.Errorf("Event as JSON, actual %q", out.String())
+	}
+}
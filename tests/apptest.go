@@ -24,6 +24,12 @@ func (t *AppTest) TestAppVersion() {
 	t.AssertContentType("application/json; charset=utf-8")
 }
 
+func (t *AppTest) TestAPIRequiresAuthentication() {
+	t.Get("/api/v1/onboardings/octocat")
+	t.AssertStatus(401)
+	t.AssertContentType("application/json; charset=utf-8")
+}
+
 func (t *AppTest) After() {
 	println("Tear down")
 }